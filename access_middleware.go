@@ -0,0 +1,226 @@
+package provision
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+)
+
+// AccountSource extracts account ids from a request
+type AccountSource func(c *gin.Context) ([]string, error)
+
+// AccountFromParam reads an account id from a path parameter
+func AccountFromParam(name string) AccountSource {
+	return func(c *gin.Context) ([]string, error) {
+		return splitAccounts(c.Param(name)), nil
+	}
+}
+
+// AccountFromQuery reads account ids from a query parameter,
+// repeated or comma separated
+func AccountFromQuery(name string) AccountSource {
+	return func(c *gin.Context) ([]string, error) {
+		accounts := make([]string, 0)
+		for _, v := range c.QueryArray(name) {
+			accounts = append(accounts, splitAccounts(v)...)
+		}
+		return accounts, nil
+	}
+}
+
+// AccountFromHeader reads comma separated account ids from a header
+func AccountFromHeader(name string) AccountSource {
+	return func(c *gin.Context) ([]string, error) {
+		return splitAccounts(c.GetHeader(name)), nil
+	}
+}
+
+// AccountFromBody reads account ids from a field of a JSON
+// body, a string or list of strings. Nested fields are
+// separated by a period (e.g. asset.account). The body is
+// restored for the next handler.
+func AccountFromBody(field string) AccountSource {
+	return func(c *gin.Context) ([]string, error) {
+		if c.Request.Body == nil {
+			return []string{}, nil
+		}
+
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		if len(bytes.TrimSpace(body)) == 0 {
+			return []string{}, nil
+		}
+
+		var v interface{}
+		err = json.Unmarshal(body, &v)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range strings.Split(field, ".") {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return []string{}, nil
+			}
+			v = obj[key]
+		}
+
+		switch val := v.(type) {
+		case nil:
+			return []string{}, nil
+		case string:
+			return splitAccounts(val), nil
+		case []interface{}:
+			accounts := make([]string, 0, len(val))
+			for _, item := range val {
+				s, ok := item.(string)
+				if !ok {
+					return nil, errors.New("body field " + field + " must be a string or list of strings")
+				}
+				accounts = append(accounts, s)
+			}
+			return accounts, nil
+		}
+
+		return nil, errors.New("body field " + field + " must be a string or list of strings")
+	}
+}
+
+// AccessGuard builds middleware requiring access for the token
+// user set by a UserTokenHandler. The accounts of all Sources
+// are checked together.
+type AccessGuard struct {
+	Sources []AccountSource
+
+	// all (default) or any
+	Mode string
+
+	// when set checks use Api.PolicyInput for account
+	// attributes and inherited admin
+	Api *Api
+}
+
+// DefaultAccessGuard checks the :account path parameter
+var DefaultAccessGuard = &AccessGuard{
+	Sources: []AccountSource{AccountFromParam("account")},
+}
+
+// RequireAccess requires the sections in the :account path
+// parameter with DefaultAccessGuard
+func RequireAccess(sections ...string) gin.HandlerFunc {
+	return DefaultAccessGuard.RequireAccess(sections...)
+}
+
+// RequireAdmin requires admin of the :account path parameter
+// with DefaultAccessGuard
+func RequireAdmin() gin.HandlerFunc {
+	return DefaultAccessGuard.RequireAdmin()
+}
+
+// RequireAccess requires the sections in the guard's accounts.
+// A guard without sources checks sections only.
+func (g *AccessGuard) RequireAccess(sections ...string) gin.HandlerFunc {
+	return g.handler(PolicyCheckAccess, sections)
+}
+
+// RequireAdmin requires admin of the guard's accounts
+func (g *AccessGuard) RequireAdmin() gin.HandlerFunc {
+	return g.handler(PolicyCheckAdmin, nil)
+}
+
+// handler
+func (g *AccessGuard) handler(check string, sections []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userI, ok := c.Get("User")
+		if !ok {
+			ak := ack.Gin(c)
+			ak.SetPayload("Unable to get user from token.")
+			ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+			return
+		}
+
+		user := userI.(*User)
+
+		ac := &AccessCheck{
+			Sections: sections,
+			Accounts: make([]string, 0),
+			Mode:     g.Mode,
+		}
+
+		for _, source := range g.Sources {
+			accounts, err := source(c)
+			if err != nil {
+				ak := ack.Gin(c)
+				ak.SetPayloadType("ValidationError")
+				ak.SetPayload("Unable to read accounts from request.")
+				ak.GinErrorAbort(400, "ValidationError", err.Error())
+				return
+			}
+
+			for _, acc := range accounts {
+				ac.Accounts = appendUnique(ac.Accounts, acc)
+			}
+		}
+
+		if len(g.Sources) > 0 && len(ac.Accounts) < 1 {
+			ak := ack.Gin(c)
+			ak.SetPayload("No account specified.")
+			ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+			return
+		}
+
+		pe := defaultPolicyEvaluator
+		in := PolicyInput{User: user, Request: ac}
+		if g.Api != nil {
+			pe = g.Api.PolicyEvaluator()
+			var err error
+			in, err = g.Api.PolicyInput(user, ac)
+			if err != nil {
+				ak := ack.Gin(c)
+				ak.SetPayloadType("AccountLookupError")
+				ak.SetPayload("Unable to lookup accounts.")
+				ak.GinErrorAbort(500, "AccountLookupError", err.Error())
+				return
+			}
+		}
+
+		decision, err := pe.Evaluate(check, in)
+		if err == nil && decision.Allowed {
+			c.Set("AccessCheck", ac)
+			c.Next()
+			return
+		}
+
+		acr := AccessCheckResult{
+			AccessChecked: ac,
+			Message:       "User does not have required access.",
+		}
+
+		ak := ack.Gin(c)
+		ak.SetPayloadType("AccessCheckResult")
+		ak.SetPayload(acr)
+		ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+	}
+}
+
+// splitAccounts splits comma separated account ids
+func splitAccounts(v string) []string {
+	accounts := make([]string, 0)
+	for _, acc := range strings.Split(v, ",") {
+		acc = strings.TrimSpace(acc)
+		if acc != "" {
+			accounts = append(accounts, acc)
+		}
+	}
+
+	return accounts
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/txn2/ack"
@@ -32,6 +33,7 @@ type Account struct {
 	Modules     []string    `json:"modules" yaml:"modules"`
 	OrgId       int         `json:"org_id" yaml:"orgId"`
 	AccessKeys  []AccessKey `json:"access_keys" yaml:"accessKeys"`
+	Auth        *AuthCfg    `json:"auth,omitempty" yaml:"auth"`
 }
 
 // AccountResult returned from Elastic
@@ -69,39 +71,73 @@ type AccountSummaryResults struct {
 		MaxScore float64                `json:"max_score"`
 		Hits     []AccountSummaryResult `json:"hits"`
 	} `json:"hits"`
+
+	// position after the last hit, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // GetAdmChildAccounts
-// get a list of account with a parent account id
+// get all accounts with a parent account id
 func (a *Api) GetAdmChildAccounts(accountId string) (int, AccountSummaryResults, *es.ErrorResponse, error) {
-	query := es.Obj{
-		"_source": []string{"id", "display_name", "description", "active", "modules"},
-		"query": es.Obj{
-			"constant_score": es.Obj{
-				"filter": es.Obj{
-					"term": es.Obj{
-						"parent": accountId,
-					},
-				},
-			},
-		},
-	}
+	all := AccountSummaryResults{}
+	page := &SearchQuery{Size: SearchSizeMax}
+
+	for {
+		obj, err := page.page(childAccountsQuery(accountId), nil, SearchTiebreakDefault)
+		if err != nil {
+			return 0, all, nil, err
+		}
+
+		code, asResults, errorResponse, err := a.GetAdmChildAccountsPage(obj)
+		if err != nil || code != 200 {
+			return code, asResults, errorResponse, err
+		}
+
+		all.Hits.Total = asResults.Hits.Total
+		all.Hits.Hits = append(all.Hits.Hits, asResults.Hits.Hits...)
 
-	js, _ := json.Marshal(query)
+		if asResults.NextCursor == "" {
+			return code, all, nil, nil
+		}
 
-	pth := fmt.Sprintf("%s/_search", a.IdxPrefix+IdxAccount)
+		page.Cursor = asResults.NextCursor
+	}
+}
 
-	a.Logger.Info("Searching", zap.String("url", pth))
+// GetAdmChildAccountsPage searches child accounts with a paged
+// childAccountsQuery
+func (a *Api) GetAdmChildAccountsPage(searchObj *es.Obj) (int, AccountSummaryResults, *es.ErrorResponse, error) {
+	js, _ := json.Marshal(searchObj)
+
+	a.Logger.Info("Searching", zap.String("index", a.IdxPrefix+IdxAccount))
 	a.Logger.Info("Query", zap.ByteString("json", js))
 
 	asResults := &AccountSummaryResults{}
 
-	code, errorResponse, err := a.Elastic.PostObjUnmarshal(pth, query, asResults)
+	code, nextCursor, errorResponse, err := a.searchPage(IdxAccount, searchObj, asResults)
 	if err != nil {
 		return code, *asResults, errorResponse, err
 	}
 
-	return code, *asResults, nil, nil
+	asResults.NextCursor = nextCursor
+
+	return code, *asResults, errorResponse, nil
+}
+
+// childAccountsQuery matches the children of accountId
+func childAccountsQuery(accountId string) es.Obj {
+	return es.Obj{
+		"_source": []string{"id", "display_name", "description", "active", "modules"},
+		"query": es.Obj{
+			"constant_score": es.Obj{
+				"filter": es.Obj{
+					"term": es.Obj{
+						"parent": accountId,
+					},
+				},
+			},
+		},
+	}
 }
 
 // GetAdmChildAccountsHandler
@@ -110,7 +146,12 @@ func (a *Api) GetAdmChildAccountsHandler(c *gin.Context) {
 
 	parentAccountId := c.Param("parentAccount")
 
-	code, esResult, errorResponse, err := a.GetAdmChildAccounts(parentAccountId)
+	obj, ok := a.listObj(c, ak, childAccountsQuery(parentAccountId), SearchTiebreakDefault)
+	if !ok {
+		return
+	}
+
+	code, esResult, errorResponse, err := a.GetAdmChildAccountsPage(obj)
 	if err != nil {
 		a.Logger.Error("EsError", zap.Error(err))
 		ak.SetPayloadType("EsError")
@@ -133,6 +174,41 @@ func (a *Api) GetAdmChildAccountsHandler(c *gin.Context) {
 	ak.GinSend(esResult)
 }
 
+// AdmAccounts returns parentAccountId and the ids of its children
+func (a *Api) AdmAccounts(parentAccountId string) ([]string, error) {
+	accounts := []string{parentAccountId}
+
+	_, children, _, err := a.GetAdmChildAccounts(parentAccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hit := range children.Hits.Hits {
+		accounts = append(accounts, hit.Source.Id)
+	}
+
+	return accounts, nil
+}
+
+// IsAdmAccount returns true if accountId is parentAccountId or
+// a child of parentAccountId
+func (a *Api) IsAdmAccount(parentAccountId string, accountId string) (bool, error) {
+	if parentAccountId == accountId {
+		return true, nil
+	}
+
+	code, accountResult, err := a.GetAccount(accountId)
+	if code == 404 {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return accountResult.Source.Parent == parentAccountId, nil
+}
+
 // GetAdmAccountHandler
 func (a *Api) GetAdmAccountHandler(c *gin.Context) {
 	ak := ack.Gin(c)
@@ -283,6 +359,27 @@ func (a *Api) UpsertAdmChildAccountUserHandler(c *gin.Context) {
 		}
 	}
 
+	// role assignments must be in one of the validated accounts,
+	// global assignments are reserved for sysops
+	for _, ar := range user.Roles {
+		if ar.Account == "" || !stringInSlice(ar.Account, user.Accounts) {
+			ak.SetPayloadType("ValidationError")
+			ak.SetPayload("Role assignments must be for an account the user is associated with.")
+			ak.GinErrorAbort(400, "ValidationError", "Role assignment account not associated with user.")
+			return
+		}
+	}
+
+	// memberships must be in one of the validated accounts
+	for _, m := range user.Memberships {
+		if !stringInSlice(m.Account, user.Accounts) {
+			ak.SetPayloadType("ValidationError")
+			ak.SetPayload("Memberships must be for an account the user is associated with.")
+			ak.GinErrorAbort(400, "ValidationError", "Membership account not associated with user.")
+			return
+		}
+	}
+
 	// sanitize
 	user.Sysop = false
 
@@ -416,7 +513,15 @@ func (a *Api) UpsertAccount(account *Account) (int, es.Result, *es.ErrorResponse
 		return 500, es.Result{}, nil, err
 	}
 
-	return a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxAccount, account.Id), account)
+	code, esResult, errorResponse, err := a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxAccount, account.Id), account)
+
+	// parents may have changed
+	a.ancestors.reset()
+
+	// keys may have been rotated or deactivated
+	a.scimKeys.evict(account.Id)
+
+	return code, esResult, errorResponse, err
 }
 
 // CheckKeyHandler
@@ -458,7 +563,7 @@ func (a *Api) CheckKey(accountId string, key AccessKey) (bool, error) {
 	}
 
 	if code != 200 {
-		return false, errors.New("Got status code " + string(code) + " back from GetAccount.")
+		return false, errors.New("Got status code " + strconv.Itoa(code) + " back from GetAccount.")
 	}
 
 	for _, accessKey := range accountResult.Source.AccessKeys {
@@ -633,6 +738,10 @@ func GetAccountMapping(prefix string) es.IndexTemplate {
 					"modules": es.Obj{
 						"type": "keyword",
 					},
+					"auth": es.Obj{
+						"type":    "object",
+						"enabled": false,
+					},
 					"access_keys": es.Obj{
 						"type": "nested",
 						"properties": es.Obj{
@@ -0,0 +1,242 @@
+package provision
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"github.com/txn2/es/v2"
+	"go.uber.org/zap"
+)
+
+// AccountCacheTTLDefault in seconds
+const AccountCacheTTLDefault = 60
+
+// AccountAncestorsMax limits the depth of the account hierarchy
+const AccountAncestorsMax = 16
+
+// ancestorCacheEntry
+type ancestorCacheEntry struct {
+	ancestors []string
+	expires   time.Time
+}
+
+// ancestorCache caches the parent chain of accounts for
+// inherited admin checks.
+type ancestorCache struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]ancestorCacheEntry
+}
+
+// newAncestorCache
+func newAncestorCache(ttlSeconds int) *ancestorCache {
+	return &ancestorCache{
+		ttl:     time.Duration(ttlSeconds) * time.Second,
+		entries: make(map[string]ancestorCacheEntry),
+	}
+}
+
+// get
+func (ac *ancestorCache) get(id string) ([]string, bool) {
+	if ac == nil {
+		return nil, false
+	}
+
+	ac.mu.RLock()
+	entry, ok := ac.entries[id]
+	ac.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	return entry.ancestors, true
+}
+
+// set
+func (ac *ancestorCache) set(id string, ancestors []string) {
+	if ac == nil || ac.ttl <= 0 {
+		return
+	}
+
+	ac.mu.Lock()
+	ac.entries[id] = ancestorCacheEntry{
+		ancestors: ancestors,
+		expires:   time.Now().Add(ac.ttl),
+	}
+	ac.mu.Unlock()
+}
+
+// reset removes all accounts from the cache
+func (ac *ancestorCache) reset() {
+	if ac == nil {
+		return
+	}
+
+	ac.mu.Lock()
+	ac.entries = make(map[string]ancestorCacheEntry)
+	ac.mu.Unlock()
+}
+
+// AccountAncestors returns the parent, grandparent and so on
+// of an account. Unknown accounts have no ancestors.
+func (a *Api) AccountAncestors(id string) ([]string, error) {
+	if ancestors, ok := a.ancestors.get(id); ok {
+		return ancestors, nil
+	}
+
+	ancestors := make([]string, 0)
+	current := id
+
+	for len(ancestors) < AccountAncestorsMax {
+		code, accountResult, err := a.GetAccount(current)
+		if code == 404 {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := accountResult.Source.Parent
+
+		// stop at the root or on a cycle
+		if parent == "" || parent == id || stringInSlice(parent, ancestors) {
+			break
+		}
+
+		ancestors = append(ancestors, parent)
+		current = parent
+	}
+
+	a.ancestors.set(id, ancestors)
+
+	return ancestors, nil
+}
+
+// AccountDescendants returns the children, grandchildren and so
+// on of an account, to the depth of AccountAncestorsMax
+func (a *Api) AccountDescendants(id string) ([]string, error) {
+	descendants := make([]string, 0)
+	parents := []string{id}
+
+	for depth := 0; depth < AccountAncestorsMax && len(parents) > 0; depth++ {
+		query := es.Obj{
+			"_source": []string{"id"},
+			"query": es.Obj{
+				"constant_score": es.Obj{
+					"filter": es.Obj{
+						"terms": es.Obj{"parent": parents},
+					},
+				},
+			},
+		}
+
+		children := make([]string, 0)
+		page := &SearchQuery{Size: SearchSizeMax}
+
+		for {
+			obj, err := page.page(query, nil, SearchTiebreakDefault)
+			if err != nil {
+				return nil, err
+			}
+
+			results := &AccountSummaryResults{}
+			code, nextCursor, errorResponse, err := a.searchPage(IdxAccount, obj, results)
+			if err != nil {
+				return nil, err
+			}
+
+			if code != 200 {
+				if errorResponse != nil {
+					a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+				}
+				return nil, fmt.Errorf("got code %d finding child accounts", code)
+			}
+
+			for _, hit := range results.Hits.Hits {
+				child := hit.Source.Id
+
+				// skip cycles
+				if child == id || stringInSlice(child, descendants) || stringInSlice(child, children) {
+					continue
+				}
+
+				children = append(children, child)
+			}
+
+			if nextCursor == "" {
+				break
+			}
+
+			page.Cursor = nextCursor
+		}
+
+		descendants = append(descendants, children...)
+		parents = children
+	}
+
+	return descendants, nil
+}
+
+// HasAccess evaluates the Api policy access check with
+// account attributes and, when InheritAdmin is set, admin
+// inherited from parent accounts.
+func (a *Api) HasAccess(user *User, ac *AccessCheck) bool {
+	decision, err := a.Decide(user, PolicyCheckAccess, ac)
+	if err != nil {
+		a.Logger.Error("Access policy failure.", zap.Error(err))
+	}
+
+	return err == nil && decision.Allowed
+}
+
+// HasAdminAccess evaluates the Api policy admin check with
+// account attributes and, when InheritAdmin is set, admin
+// inherited from parent accounts.
+func (a *Api) HasAdminAccess(user *User, ac *AccessCheck) bool {
+	decision, err := a.Decide(user, PolicyCheckAdmin, ac)
+	if err != nil {
+		a.Logger.Error("Access policy failure.", zap.Error(err))
+	}
+
+	return err == nil && decision.Allowed
+}
+
+// AccountAccessCheckHandler is AccountAccessCheckHandler using
+// Api.HasAccess and Api.HasAdminAccess.
+func (a *Api) AccountAccessCheckHandler(checkAdmin bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userI, ok := c.Get("User")
+		if !ok {
+			ak := ack.Gin(c)
+			ak.SetPayload("Unable to get user from token.")
+			ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+			return
+		}
+
+		user := userI.(*User)
+
+		account := c.Param("account")
+		if account == "" {
+			ak := ack.Gin(c)
+			ak.SetPayload("No account specified.")
+			ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+			return
+		}
+
+		ac := &AccessCheck{
+			Accounts: []string{account},
+		}
+
+		if (!checkAdmin && a.HasAccess(user, ac)) || (checkAdmin && a.HasAdminAccess(user, ac)) {
+			return
+		}
+
+		ak := ack.Gin(c)
+		ak.SetPayload("User does not have required access.")
+		ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+	}
+}
@@ -78,6 +78,9 @@ type AssetSummaryResults struct {
 		MaxScore float64              `json:"max_score"`
 		Hits     []AssetSummaryResult `json:"hits"`
 	} `json:"hits"`
+
+	// position after the last hit, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // GetAdmAssetsHandler
@@ -190,7 +193,12 @@ func (a *Api) GetAdmAssetsHandler(c *gin.Context) {
 	//parentAccountId := c.Param("parentAccount")
 	accountId := c.Param("account")
 
-	code, esResult, errorResponse, err := a.AssetAdmAssoc(accountId)
+	obj, ok := a.listObj(c, ak, assetAssocQuery(accountId), SearchTiebreakDefault)
+	if !ok {
+		return
+	}
+
+	code, esResult, errorResponse, err := a.AssetAdmAssocPage(obj)
 	if err != nil {
 		a.Logger.Error("EsError", zap.Error(err))
 		ak.SetPayloadType("EsError")
@@ -218,8 +226,50 @@ func (a *Api) GetAdmAssetsHandler(c *gin.Context) {
 }
 
 // AssetAdmAssoc
+// get all assets with routes to an account
 func (a *Api) AssetAdmAssoc(accountId string) (int, AssetSummaryResults, *es.ErrorResponse, error) {
-	query := es.Obj{
+	all := AssetSummaryResults{}
+	page := &SearchQuery{Size: SearchSizeMax}
+
+	for {
+		obj, err := page.page(assetAssocQuery(accountId), nil, SearchTiebreakDefault)
+		if err != nil {
+			return 0, all, nil, err
+		}
+
+		code, asResults, errorResponse, err := a.AssetAdmAssocPage(obj)
+		if err != nil || code != 200 {
+			return code, asResults, errorResponse, err
+		}
+
+		all.Hits.Total = asResults.Hits.Total
+		all.Hits.Hits = append(all.Hits.Hits, asResults.Hits.Hits...)
+
+		if asResults.NextCursor == "" {
+			return code, all, nil, nil
+		}
+
+		page.Cursor = asResults.NextCursor
+	}
+}
+
+// AssetAdmAssocPage searches assets with a paged assetAssocQuery
+func (a *Api) AssetAdmAssocPage(searchObj *es.Obj) (int, AssetSummaryResults, *es.ErrorResponse, error) {
+	asResults := &AssetSummaryResults{}
+
+	code, nextCursor, errorResponse, err := a.searchPage(IdxAsset, searchObj, asResults)
+	if err != nil {
+		return code, *asResults, errorResponse, err
+	}
+
+	asResults.NextCursor = nextCursor
+
+	return code, *asResults, errorResponse, nil
+}
+
+// assetAssocQuery matches assets with routes to accountId
+func assetAssocQuery(accountId string) es.Obj {
+	return es.Obj{
 		"query": es.Obj{
 			"nested": es.Obj{
 				"path": "routes",
@@ -234,20 +284,7 @@ func (a *Api) AssetAdmAssoc(accountId string) (int, AssetSummaryResults, *es.Err
 				},
 			},
 		},
-		"size": 10000,
-		"sort": es.Obj{
-			"id": "asc",
-		},
 	}
-
-	asResults := &AssetSummaryResults{}
-
-	code, errorResponse, err := a.Elastic.PostObjUnmarshal(fmt.Sprintf("%s/_search", a.IdxPrefix+IdxAsset), query, asResults)
-	if err != nil {
-		return code, *asResults, errorResponse, err
-	}
-
-	return code, *asResults, errorResponse, nil
 }
 
 // UpsertAssetHandler
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/txn2/provision"
+)
+
+// UserHasAccess checks the access of the token user. A denied
+// check is not an error, the result Status is false. With
+// explain the result includes the policy trace.
+func (c *Client) UserHasAccess(ctx context.Context, ac provision.AccessCheck, explain bool) (*provision.AccessCheckResult, error) {
+	return c.accessCheck(ctx, "/userHasAccess", ac, explain)
+}
+
+// UserHasAdminAccess checks the token user is an admin of the
+// accounts. A denied check is not an error, the result Status
+// is false.
+func (c *Client) UserHasAdminAccess(ctx context.Context, ac provision.AccessCheck, explain bool) (*provision.AccessCheckResult, error) {
+	return c.accessCheck(ctx, "/userHasAdminAccess", ac, explain)
+}
+
+// UserHasAccessBatch evaluates up to provision.BatchAccessCheckMax
+// checks for the token user
+func (c *Client) UserHasAccessBatch(ctx context.Context, batch provision.BatchAccessCheck, explain bool) (*provision.BatchAccessCheckResult, error) {
+	req := post("/userHasAccessBatch", batch)
+	if explain {
+		req.query = url.Values{"explain": {"true"}}
+	}
+
+	results := &provision.BatchAccessCheckResult{}
+	err := c.do(ctx, req, results)
+
+	return results, err
+}
+
+// accessCheck
+func (c *Client) accessCheck(ctx context.Context, path string, ac provision.AccessCheck, explain bool) (*provision.AccessCheckResult, error) {
+	req := post(path, ac)
+	if explain {
+		req.query = url.Values{"explain": {"true"}}
+	}
+
+	acr := &provision.AccessCheckResult{}
+	err := c.do(ctx, req, acr)
+
+	// denied checks are 401 with the checked access
+	if e, ok := err.(*Error); ok && e.StatusCode == 401 && e.Code == "E401" {
+		denied := &provision.AccessCheckResult{}
+		if e.Unmarshal(denied) == nil && denied.AccessChecked != nil {
+			return denied, nil
+		}
+	}
+
+	return acr, err
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+
+	"github.com/txn2/es/v2"
+	"github.com/txn2/provision"
+)
+
+// UpsertAccount
+func (c *Client) UpsertAccount(ctx context.Context, account *provision.Account) (*es.Result, error) {
+	result := &es.Result{}
+	err := c.do(ctx, post("/account", account), result)
+
+	return result, err
+}
+
+// GetAccount
+func (c *Client) GetAccount(ctx context.Context, id string) (*provision.AccountResult, error) {
+	accountResult := &provision.AccountResult{}
+	err := c.do(ctx, get("/account/"+pathId(id)), accountResult)
+
+	return accountResult, err
+}
+
+// CheckKey returns true if the key is valid for the account
+func (c *Client) CheckKey(ctx context.Context, id string, key provision.AccessKey) (bool, error) {
+	err := c.do(ctx, post("/keyCheck/"+pathId(id), key), nil)
+
+	if e, ok := err.(*Error); ok && e.StatusCode == 401 && e.Code == "CheckKeyFailed" {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// SearchAccounts
+func (c *Client) SearchAccounts(ctx context.Context, query provision.SearchQuery) (*provision.AccountSearchResults, error) {
+	results := &provision.AccountSearchResults{}
+	err := c.do(ctx, post("/searchAccounts", query), results)
+
+	return results, err
+}
+
+// SearchAccountsDsl searches with Elasticsearch DSL, requires a
+// sysop token and raw search enabled on the server
+func (c *Client) SearchAccountsDsl(ctx context.Context, query es.Obj) (*provision.AccountSearchResults, error) {
+	results := &provision.AccountSearchResults{}
+	err := c.do(ctx, dsl(post("/searchAccounts", query)), results)
+
+	return results, err
+}
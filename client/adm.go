@@ -0,0 +1,210 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/txn2/es/v2"
+	"github.com/txn2/provision"
+)
+
+// AdmClient makes account admin requests for a parent account,
+// see Client.Adm
+type AdmClient struct {
+	client *Client
+	parent string
+}
+
+// Adm returns a client for the /adm routes of parentAccount
+func (c *Client) Adm(parentAccount string) *AdmClient {
+	return &AdmClient{client: c, parent: parentAccount}
+}
+
+// path
+func (ac *AdmClient) path(p string) string {
+	return "/adm/" + pathId(ac.parent) + p
+}
+
+// paged adds the size and cursor of page to a list request
+func paged(req request, page provision.Page) request {
+	req.query = url.Values{}
+	if page.Size > 0 {
+		req.query.Set("size", strconv.Itoa(page.Size))
+	}
+	if page.Cursor != "" {
+		req.query.Set("cursor", page.Cursor)
+	}
+
+	return req
+}
+
+// GetAccount gets the parent or one of its children
+func (ac *AdmClient) GetAccount(ctx context.Context, account string) (*provision.AccountResult, error) {
+	accountResult := &provision.AccountResult{}
+	err := ac.client.do(ctx, get(ac.path("/account/"+pathId(account))), accountResult)
+
+	return accountResult, err
+}
+
+// UpsertChildAccount
+func (ac *AdmClient) UpsertChildAccount(ctx context.Context, account *provision.Account) (*es.Result, error) {
+	result := &es.Result{}
+	err := ac.client.do(ctx, post(ac.path("/account"), account), result)
+
+	return result, err
+}
+
+// Children of the parent account
+func (ac *AdmClient) Children(ctx context.Context, page provision.Page) (*provision.AccountSummaryResults, error) {
+	results := &provision.AccountSummaryResults{}
+	err := ac.client.do(ctx, paged(get(ac.path("/children")), page), results)
+
+	return results, err
+}
+
+// Assets associated with an account
+func (ac *AdmClient) Assets(ctx context.Context, account string, page provision.Page) (*provision.AssetSummaryResults, error) {
+	results := &provision.AssetSummaryResults{}
+	err := ac.client.do(ctx, paged(get(ac.path("/assets/"+pathId(account))), page), results)
+
+	return results, err
+}
+
+// AssetAssoc re-associates the routes of an asset from one
+// account to another
+func (ac *AdmClient) AssetAssoc(ctx context.Context, asset string, accountFrom string, accountTo string) (*es.Result, error) {
+	result := &es.Result{}
+	err := ac.client.do(ctx, get(ac.path("/assetAssoc/"+pathId(asset)+"/"+pathId(accountFrom)+"/"+pathId(accountTo))), result)
+
+	return result, err
+}
+
+// SearchAssets owned by or routed to the parent and descendant
+// accounts
+func (ac *AdmClient) SearchAssets(ctx context.Context, query provision.SearchQuery) (*provision.AssetSearchResults, error) {
+	results := &provision.AssetSearchResults{}
+	err := ac.client.do(ctx, post(ac.path("/searchAssets"), query), results)
+
+	return results, err
+}
+
+// UpsertUser for the parent or child accounts
+func (ac *AdmClient) UpsertUser(ctx context.Context, user *provision.User) (*es.Result, error) {
+	result := &es.Result{}
+	err := ac.client.do(ctx, post(ac.path("/user"), user), result)
+
+	return result, err
+}
+
+// SearchUsers of the parent and descendant accounts
+func (ac *AdmClient) SearchUsers(ctx context.Context, query provision.SearchQuery) (*provision.UserSearchResults, error) {
+	results := &provision.UserSearchResults{}
+	err := ac.client.do(ctx, post(ac.path("/searchUsers"), query), results)
+
+	return results, err
+}
+
+// UpsertRole for the parent or a child account
+func (ac *AdmClient) UpsertRole(ctx context.Context, role *provision.Role) (*es.Result, error) {
+	result := &es.Result{}
+	err := ac.client.do(ctx, post(ac.path("/role"), role), result)
+
+	return result, err
+}
+
+// UpsertGroup owned by the parent or a child account
+func (ac *AdmClient) UpsertGroup(ctx context.Context, group *provision.Group) (*es.Result, error) {
+	result := &es.Result{}
+	err := ac.client.do(ctx, post(ac.path("/group"), group), result)
+
+	return result, err
+}
+
+// Groups owned by the parent and child accounts
+func (ac *AdmClient) Groups(ctx context.Context, page provision.Page) (*provision.GroupSearchResults, error) {
+	results := &provision.GroupSearchResults{}
+	err := ac.client.do(ctx, paged(get(ac.path("/groups")), page), results)
+
+	return results, err
+}
+
+// groupAccount adds the account owning a group to an adm group
+// request, the parent account if empty
+func groupAccount(req request, account string) request {
+	if account != "" {
+		req.query = url.Values{"account": {account}}
+	}
+
+	return req
+}
+
+// GetGroup of account, the parent account if empty
+func (ac *AdmClient) GetGroup(ctx context.Context, account string, id string) (*provision.Group, error) {
+	group := &provision.Group{}
+	err := ac.client.do(ctx, groupAccount(get(ac.path("/group/"+pathId(id))), account), group)
+
+	return group, err
+}
+
+// DeleteGroup of account, the parent account if empty
+func (ac *AdmClient) DeleteGroup(ctx context.Context, account string, id string) error {
+	return ac.client.do(ctx, groupAccount(request{
+		method:     http.MethodDelete,
+		path:       ac.path("/group/" + pathId(id)),
+		idempotent: true,
+	}, account), nil)
+}
+
+// AddGroupMember to a group of account, the parent account if
+// empty. The user must belong to the group's account.
+func (ac *AdmClient) AddGroupMember(ctx context.Context, account string, group string, user string) (*es.Result, error) {
+	result := &es.Result{}
+	err := ac.client.do(ctx, groupAccount(request{
+		method:     http.MethodPut,
+		path:       ac.path("/group/" + pathId(group) + "/member/" + pathId(user)),
+		idempotent: true,
+	}, account), result)
+
+	return result, err
+}
+
+// RemoveGroupMember from a group of account, the parent account
+// if empty
+func (ac *AdmClient) RemoveGroupMember(ctx context.Context, account string, group string, user string) (*es.Result, error) {
+	result := &es.Result{}
+	err := ac.client.do(ctx, groupAccount(request{
+		method:     http.MethodDelete,
+		path:       ac.path("/group/" + pathId(group) + "/member/" + pathId(user)),
+		idempotent: true,
+	}, account), result)
+
+	return result, err
+}
+
+// CreateInvite invites an email to the parent or a child account,
+// the result holds the only copy of the raw invite token
+func (ac *AdmClient) CreateInvite(ctx context.Context, invite provision.Invite) (*provision.InviteTokenResult, error) {
+	itr := &provision.InviteTokenResult{}
+	err := ac.client.do(ctx, request{method: http.MethodPost, path: ac.path("/invite"), body: invite}, itr)
+
+	return itr, err
+}
+
+// Invites pending for the parent and child accounts
+func (ac *AdmClient) Invites(ctx context.Context, page provision.Page) (*provision.InviteSearchResults, error) {
+	results := &provision.InviteSearchResults{}
+	err := ac.client.do(ctx, paged(get(ac.path("/invites")), page), results)
+
+	return results, err
+}
+
+// RevokeInvite
+func (ac *AdmClient) RevokeInvite(ctx context.Context, id string) error {
+	return ac.client.do(ctx, request{
+		method:     http.MethodDelete,
+		path:       ac.path("/invite/" + pathId(id)),
+		idempotent: true,
+	}, nil)
+}
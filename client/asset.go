@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+
+	"github.com/txn2/es/v2"
+	"github.com/txn2/provision"
+)
+
+// UpsertAsset
+func (c *Client) UpsertAsset(ctx context.Context, asset *provision.Asset) (*es.Result, error) {
+	result := &es.Result{}
+	err := c.do(ctx, post("/asset", asset), result)
+
+	return result, err
+}
+
+// GetAsset
+func (c *Client) GetAsset(ctx context.Context, id string) (*provision.AssetResult, error) {
+	assetResult := &provision.AssetResult{}
+	err := c.do(ctx, get("/asset/"+pathId(id)), assetResult)
+
+	return assetResult, err
+}
+
+// SearchAssets
+func (c *Client) SearchAssets(ctx context.Context, query provision.SearchQuery) (*provision.AssetSearchResults, error) {
+	results := &provision.AssetSearchResults{}
+	err := c.do(ctx, post("/searchAssets", query), results)
+
+	return results, err
+}
+
+// SearchAssetsDsl searches with Elasticsearch DSL, requires a
+// sysop token and raw search enabled on the server
+func (c *Client) SearchAssetsDsl(ctx context.Context, query es.Obj) (*provision.AssetSearchResults, error) {
+	results := &provision.AssetSearchResults{}
+	err := c.do(ctx, dsl(post("/searchAssets", query)), results)
+
+	return results, err
+}
@@ -0,0 +1,255 @@
+// Package client is a typed client for the provision HTTP API.
+//
+// Payloads are the types of the provision package and failures
+// are returned as *Error, comparable with errors.Is to ErrNotFound,
+// ErrUnauthorized, ErrValidation, ErrDatabase and ErrServer. The
+// SCIM and OIDC browser login routes are not covered.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/txn2/ack"
+)
+
+// RetriesDefault is the number of times a failed idempotent
+// request is retried
+const RetriesDefault = 2
+
+// RetryWaitDefault is the wait before the first retry, doubled
+// for each following retry
+const RetryWaitDefault = 250 * time.Millisecond
+
+// TimeoutDefault for the default http client
+const TimeoutDefault = 30 * time.Second
+
+// Config
+type Config struct {
+	// provision service, e.g. http://localhost:8070
+	Server string
+
+	// sent as a bearer token, a user token from AuthUser
+	// or a personal api token
+	Token string
+
+	// if nil, one will be created with TimeoutDefault
+	HttpClient *http.Client
+
+	// retries of idempotent requests failing to connect or
+	// with a 502, 503 or 504 response
+	// defaults to RetriesDefault, negative disables
+	Retries int
+
+	// defaults to RetryWaitDefault
+	RetryWait time.Duration
+}
+
+// Client
+type Client struct {
+	*Config
+}
+
+// New
+func New(cfg *Config) (*Client, error) {
+	if cfg.Server == "" {
+		return nil, errors.New("client requires a server")
+	}
+
+	if _, err := url.Parse(cfg.Server); err != nil {
+		return nil, err
+	}
+
+	if cfg.HttpClient == nil {
+		cfg.HttpClient = &http.Client{Timeout: TimeoutDefault}
+	}
+
+	if cfg.Retries == 0 {
+		cfg.Retries = RetriesDefault
+	}
+
+	if cfg.RetryWait == 0 {
+		cfg.RetryWait = RetryWaitDefault
+	}
+
+	return &Client{Config: cfg}, nil
+}
+
+// WithToken returns a copy of the client sending token
+func (c *Client) WithToken(token string) *Client {
+	cfg := *c.Config
+	cfg.Token = token
+
+	return &Client{Config: &cfg}
+}
+
+// ackResponse is an ack with the payload left for the caller
+type ackResponse struct {
+	ack.Ack
+	Payload json.RawMessage `json:"payload"`
+}
+
+// request
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+
+	// safe to send more than once
+	idempotent bool
+}
+
+// get is an idempotent GET request
+func get(path string) request {
+	return request{method: http.MethodGet, path: path, idempotent: true}
+}
+
+// post is an idempotent POST request, upserts, searches
+// and checks
+func post(path string, body interface{}) request {
+	return request{method: http.MethodPost, path: path, body: body, idempotent: true}
+}
+
+// dsl marks a search request as Elasticsearch DSL
+func dsl(req request) request {
+	req.query = url.Values{"dsl": []string{"true"}}
+	return req
+}
+
+// do sends a request and unmarshals the ack payload into out
+// if out is not nil. Unsuccessful acks are returned as *Error.
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	u := strings.TrimRight(c.Server, "/") + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	var body []byte
+	if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	retries := 0
+	if req.idempotent && c.Retries > 0 {
+		retries = c.Retries
+	}
+
+	wait := c.RetryWait
+
+	for attempt := 0; ; attempt++ {
+		ar, err := c.send(ctx, req.method, u, body)
+		if err == nil {
+			if ar.Success {
+				if out == nil || len(ar.Payload) == 0 {
+					return nil
+				}
+				return json.Unmarshal(ar.Payload, out)
+			}
+			err = newError(ar)
+		}
+
+		if attempt >= retries || !retryable(ctx, err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		wait *= 2
+	}
+}
+
+// send makes a single attempt
+func (c *Client) send(ctx context.Context, method string, u string, body []byte) (*ackResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Accept", "application/json")
+
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HttpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	ar := &ackResponse{}
+	err = json.Unmarshal(data, ar)
+	if err != nil || (ar.ServerCode == 0 && ar.Version == 0) {
+		// not an ack, e.g. from a proxy
+		return nil, &Error{
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(data)),
+		}
+	}
+
+	if ar.ServerCode == 0 {
+		ar.ServerCode = resp.StatusCode
+	}
+
+	return ar, nil
+}
+
+// retryable errors are connection failures and responses
+// from an unavailable server
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if e, ok := err.(*Error); ok {
+		return e.StatusCode == 502 || e.StatusCode == 503 || e.StatusCode == 504
+	}
+
+	_, ok := err.(*url.Error)
+	return ok
+}
+
+// pathId escapes an id for use in a path
+func pathId(id string) string {
+	return url.PathEscape(id)
+}
+
+// Prefix returns the index prefix of the service
+func (c *Client) Prefix(ctx context.Context) (string, error) {
+	prefix := ""
+	err := c.do(ctx, get("/prefix"), &prefix)
+
+	return prefix, err
+}
@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/txn2/ack"
+	"github.com/txn2/provision"
+)
+
+// ackServer responds to every request with an ack of status,
+// error code and payload
+func ackServer(t *testing.T, status int, code string, payload interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeAck(t, w, status, code, payload)
+	}))
+}
+
+func writeAck(t *testing.T, w http.ResponseWriter, status int, code string, payload interface{}) {
+	a := ack.Ack{
+		Version:    8,
+		Success:    status == 200,
+		ErrorCode:  code,
+		ServerCode: status,
+		Payload:    payload,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(a)
+	if err != nil {
+		t.Errorf("Encode: %s", err.Error())
+	}
+}
+
+func testClient(t *testing.T, srv *httptest.Server, token string) *Client {
+	c, err := New(&Config{Server: srv.URL, Token: token, RetryWait: time.Millisecond})
+	if err != nil {
+		t.Fatalf("New: %s", err.Error())
+	}
+
+	return c
+}
+
+func TestErrorIs(t *testing.T) {
+	all := []error{ErrNotFound, ErrUnauthorized, ErrValidation, ErrDatabase, ErrServer}
+
+	tt := []struct {
+		status int
+		code   string
+		want   error
+	}{
+		{404, "AccountNotFound", ErrNotFound},
+		{400, "NoAssociated", ErrNotFound},
+		{401, "E401", ErrUnauthorized},
+		{401, "AuthFailure", ErrUnauthorized},
+		{403, "", ErrUnauthorized},
+		{400, "ValidationError", ErrValidation},
+		{400, "UnmarshalError", ErrValidation},
+		{500, "EsError", ErrDatabase},
+		{500, "UpsertError", ErrServer},
+		{409, "Conflict", nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.code, func(t *testing.T) {
+			srv := ackServer(t, tc.status, tc.code, "failed")
+			defer srv.Close()
+
+			_, err := testClient(t, srv, "").GetAccount(context.Background(), "acme")
+
+			e, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("got %T %v, want *Error", err, err)
+			}
+			if e.StatusCode != tc.status || e.Code != tc.code || e.Detail() != "failed" {
+				t.Errorf("got %d %s %s, want %d %s failed", e.StatusCode, e.Code, e.Detail(), tc.status, tc.code)
+			}
+
+			for _, target := range all {
+				if got := errors.Is(err, target); got != (target == tc.want) {
+					t.Errorf("errors.Is(%v) = %v", target, got)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorNotAck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("bad gateway\n"))
+	}))
+	defer srv.Close()
+
+	c := testClient(t, srv, "")
+	c.Retries = -1
+
+	_, err := c.Prefix(context.Background())
+
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("got %T %v, want *Error", err, err)
+	}
+	if e.StatusCode != http.StatusBadGateway || e.Code != "" || e.Message != "bad gateway" {
+		t.Errorf("got %d %q %q", e.StatusCode, e.Code, e.Message)
+	}
+	if !errors.Is(err, ErrServer) {
+		t.Error("errors.Is(ErrServer) = false")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+
+		writeAck(t, w, http.StatusServiceUnavailable, "Unavailable", "try again")
+	}))
+	defer srv.Close()
+
+	c := testClient(t, srv, "")
+	ctx := context.Background()
+
+	tt := []struct {
+		name     string
+		call     func() error
+		key      string
+		requests int
+	}{
+		{"get", func() error { _, err := c.GetAccount(ctx, "acme"); return err },
+			"GET /account/acme", RetriesDefault + 1},
+		{"idempotent post", func() error { _, err := c.UpsertAccount(ctx, &provision.Account{Id: "acme"}); return err },
+			"POST /account", RetriesDefault + 1},
+		{"delete", func() error { return c.RevokeApiToken(ctx, "ci") },
+			"DELETE /apiTokens/ci", RetriesDefault + 1},
+		{"auth", func() error { _, err := c.AuthUser(ctx, provision.Auth{Id: "u1"}); return err },
+			"POST /authUser", 1},
+		{"refresh", func() error { _, err := c.RefreshToken(ctx, "rt"); return err },
+			"POST /token/refresh", 1},
+		{"create api token", func() error { _, err := c.CreateApiToken(ctx, provision.ApiToken{Name: "ci"}); return err },
+			"POST /apiTokens", 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			if !errors.Is(err, ErrServer) {
+				t.Fatalf("got %v, want ErrServer", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if requests[tc.key] != tc.requests {
+				t.Errorf("%s sent %d times, want %d", tc.key, requests[tc.key], tc.requests)
+			}
+		})
+	}
+}
+
+func TestRetrySucceeds(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+
+		if n == 1 {
+			writeAck(t, w, http.StatusGatewayTimeout, "Timeout", "")
+			return
+		}
+
+		writeAck(t, w, 200, "", "system_")
+	}))
+	defer srv.Close()
+
+	prefix, err := testClient(t, srv, "").Prefix(context.Background())
+	if err != nil {
+		t.Fatalf("Prefix: %s", err.Error())
+	}
+	if prefix != "system_" || requests != 2 {
+		t.Errorf("got %s after %d requests, want system_ after 2", prefix, requests)
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	var mu sync.Mutex
+	auth := make([]string, 0)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auth = append(auth, r.Header.Get("Authorization"))
+		mu.Unlock()
+
+		writeAck(t, w, 200, "", "system_")
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := testClient(t, srv, "t1")
+
+	calls := []func() error{
+		func() error { _, err := c.Prefix(ctx); return err },
+		func() error { _, err := c.WithToken("t2").Prefix(ctx); return err },
+		func() error { _, err := c.Prefix(ctx); return err },
+		func() error { _, err := c.WithToken("").Prefix(ctx); return err },
+	}
+
+	for _, call := range calls {
+		if err := call(); err != nil {
+			t.Fatalf("Prefix: %s", err.Error())
+		}
+	}
+
+	want := []string{"Bearer t1", "Bearer t2", "Bearer t1", ""}
+	for i := range want {
+		if auth[i] != want[i] {
+			t.Errorf("request %d Authorization = %q, want %q", i, auth[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrNotFound for unknown accounts, users, assets and so on
+	ErrNotFound = errors.New("not found")
+
+	// ErrUnauthorized for invalid tokens, credentials and keys
+	// and failed access checks
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrValidation for rejected requests
+	ErrValidation = errors.New("validation error")
+
+	// ErrDatabase for failures communicating with Elasticsearch
+	ErrDatabase = errors.New("database error")
+
+	// ErrServer for other server failures
+	ErrServer = errors.New("server error")
+)
+
+// errorCodes maps ack error codes to errors, codes not listed
+// are mapped by status code
+var errorCodes = map[string]error{
+	"E401":                    ErrUnauthorized,
+	"AuthFailure":             ErrUnauthorized,
+	"InviteInvalid":           ErrUnauthorized,
+	"ValidationError":         ErrValidation,
+	"AccountAssociationError": ErrValidation,
+	"NoAssociated":            ErrNotFound,
+	"OidcNotConfigured":       ErrNotFound,
+	"EsError":                 ErrDatabase,
+	"SearchError":             ErrDatabase,
+}
+
+// Error is an unsuccessful ack, or a response that was not an
+// ack in which case Code is empty and Message holds the body
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Payload    json.RawMessage
+}
+
+// newError
+func newError(ar *ackResponse) *Error {
+	return &Error{
+		StatusCode: ar.ServerCode,
+		Code:       ar.ErrorCode,
+		Message:    ar.ErrorMessage,
+		Payload:    ar.Payload,
+	}
+}
+
+// Error
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("provision: %d", e.StatusCode)
+	if e.Code != "" {
+		msg += " " + e.Code
+	}
+
+	detail := e.Detail()
+	if detail == "" {
+		detail = e.Message
+	}
+
+	if detail != "" {
+		msg += ": " + detail
+	}
+
+	return msg
+}
+
+// Detail returns the payload when it is a message, most errors
+// describe the failure there
+func (e *Error) Detail() string {
+	detail := ""
+	if json.Unmarshal(e.Payload, &detail) != nil {
+		return ""
+	}
+
+	return detail
+}
+
+// Unmarshal the error payload, e.g. the AccessCheckResult of
+// a failed access check
+func (e *Error) Unmarshal(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// Is maps the ack error code to ErrNotFound, ErrUnauthorized,
+// ErrValidation, ErrDatabase or ErrServer
+func (e *Error) Is(target error) bool {
+	if err, ok := errorCodes[e.Code]; ok {
+		return err == target
+	}
+
+	switch {
+	case strings.HasSuffix(e.Code, "NotFound") || e.StatusCode == 404:
+		return target == ErrNotFound
+	case e.StatusCode == 401 || e.StatusCode == 403:
+		return target == ErrUnauthorized
+	case e.StatusCode == 400:
+		return target == ErrValidation
+	case e.StatusCode >= 500:
+		return target == ErrServer
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/txn2/es/v2"
+	"github.com/txn2/provision"
+)
+
+// UpsertGroup
+func (c *Client) UpsertGroup(ctx context.Context, group *provision.Group) (*es.Result, error) {
+	result := &es.Result{}
+	err := c.do(ctx, post("/group", group), result)
+
+	return result, err
+}
+
+// GetGroup gets a group of an account
+func (c *Client) GetGroup(ctx context.Context, account string, id string) (*provision.GroupResult, error) {
+	req := get("/group/" + pathId(id))
+	req.query = url.Values{"account": {account}}
+
+	groupResult := &provision.GroupResult{}
+	err := c.do(ctx, req, groupResult)
+
+	return groupResult, err
+}
+
+// SearchGroups
+func (c *Client) SearchGroups(ctx context.Context, query provision.SearchQuery) (*provision.GroupSearchResults, error) {
+	results := &provision.GroupSearchResults{}
+	err := c.do(ctx, post("/searchGroups", query), results)
+
+	return results, err
+}
+
+// SearchGroupsDsl searches with Elasticsearch DSL, requires a
+// sysop token and raw search enabled on the server
+func (c *Client) SearchGroupsDsl(ctx context.Context, query es.Obj) (*provision.GroupSearchResults, error) {
+	results := &provision.GroupSearchResults{}
+	err := c.do(ctx, dsl(post("/searchGroups", query)), results)
+
+	return results, err
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/txn2/es/v2"
+	"github.com/txn2/provision"
+)
+
+// UpsertRole
+func (c *Client) UpsertRole(ctx context.Context, role *provision.Role) (*es.Result, error) {
+	result := &es.Result{}
+	err := c.do(ctx, post("/role", role), result)
+
+	return result, err
+}
+
+// GetRole gets a role of an account, or a global role if
+// account is empty
+func (c *Client) GetRole(ctx context.Context, account string, id string) (*provision.RoleResult, error) {
+	req := get("/role/" + pathId(id))
+	if account != "" {
+		req.query = url.Values{"account": {account}}
+	}
+
+	roleResult := &provision.RoleResult{}
+	err := c.do(ctx, req, roleResult)
+
+	return roleResult, err
+}
+
+// SearchRoles
+func (c *Client) SearchRoles(ctx context.Context, query provision.SearchQuery) (*provision.RoleSearchResults, error) {
+	results := &provision.RoleSearchResults{}
+	err := c.do(ctx, post("/searchRoles", query), results)
+
+	return results, err
+}
+
+// SearchRolesDsl searches with Elasticsearch DSL, requires a
+// sysop token and raw search enabled on the server
+func (c *Client) SearchRolesDsl(ctx context.Context, query es.Obj) (*provision.RoleSearchResults, error) {
+	results := &provision.RoleSearchResults{}
+	err := c.do(ctx, dsl(post("/searchRoles", query)), results)
+
+	return results, err
+}
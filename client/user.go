@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/txn2/es/v2"
+	"github.com/txn2/provision"
+)
+
+// UpsertUser
+func (c *Client) UpsertUser(ctx context.Context, user *provision.User) (*es.Result, error) {
+	result := &es.Result{}
+	err := c.do(ctx, post("/user", user), result)
+
+	return result, err
+}
+
+// GetUser
+func (c *Client) GetUser(ctx context.Context, id string) (*provision.UserResult, error) {
+	userResult := &provision.UserResult{}
+	err := c.do(ctx, get("/user/"+pathId(id)), userResult)
+
+	return userResult, err
+}
+
+// SearchUsers
+func (c *Client) SearchUsers(ctx context.Context, query provision.SearchQuery) (*provision.UserSearchResults, error) {
+	results := &provision.UserSearchResults{}
+	err := c.do(ctx, post("/searchUsers", query), results)
+
+	return results, err
+}
+
+// SearchUsersDsl searches with Elasticsearch DSL, requires a
+// sysop token and raw search enabled on the server
+func (c *Client) SearchUsersDsl(ctx context.Context, query es.Obj) (*provision.UserSearchResults, error) {
+	results := &provision.UserSearchResults{}
+	err := c.do(ctx, dsl(post("/searchUsers", query)), results)
+
+	return results, err
+}
+
+// AuthUser authenticates a user returning a token, use
+// WithToken to make requests as the user
+func (c *Client) AuthUser(ctx context.Context, auth provision.Auth) (*provision.UserTokenResult, error) {
+	utr := &provision.UserTokenResult{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/authUser", body: auth}, utr)
+
+	return utr, err
+}
+
+// RefreshToken exchanges a refresh token for a new token
+// and refresh token
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*provision.UserTokenResult, error) {
+	utr := &provision.UserTokenResult{}
+	err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/token/refresh",
+		body:   provision.TokenRefresh{RefreshToken: refreshToken},
+	}, utr)
+
+	return utr, err
+}
+
+// RevokeToken revokes the client token and, if not empty,
+// a refresh token of the same user
+func (c *Client) RevokeToken(ctx context.Context, refreshToken string) error {
+	return c.do(ctx, post("/token/revoke", provision.TokenRefresh{RefreshToken: refreshToken}), nil)
+}
+
+// ApiTokens lists the api tokens of the token user
+func (c *Client) ApiTokens(ctx context.Context) ([]provision.ApiToken, error) {
+	tokens := make([]provision.ApiToken, 0)
+	err := c.do(ctx, get("/apiTokens"), &tokens)
+
+	return tokens, err
+}
+
+// CreateApiToken creates an api token for the token user, the
+// result holds the only copy of the raw token
+func (c *Client) CreateApiToken(ctx context.Context, at provision.ApiToken) (*provision.ApiTokenResult, error) {
+	atr := &provision.ApiTokenResult{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/apiTokens", body: at}, atr)
+
+	return atr, err
+}
+
+// RevokeApiToken removes an api token of the token user
+func (c *Client) RevokeApiToken(ctx context.Context, name string) error {
+	return c.do(ctx, request{
+		method:     http.MethodDelete,
+		path:       "/apiTokens/" + pathId(name),
+		idempotent: true,
+	}, nil)
+}
+
+// AcceptInvite
+func (c *Client) AcceptInvite(ctx context.Context, accept provision.InviteAccept) (*provision.User, error) {
+	user := &provision.User{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/invite/accept", body: accept}, user)
+
+	return user, err
+}
+
+// WhoAmI returns the effective permissions of the token user
+func (c *Client) WhoAmI(ctx context.Context) (*provision.EffectivePermissions, error) {
+	ep := &provision.EffectivePermissions{}
+	err := c.do(ctx, get("/whoami"), ep)
+
+	return ep, err
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/provision"
+)
+
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes(router, &provision.Api{})
+
+	ops := make(map[string]bool, len(provision.OpenApiOperations))
+	for _, op := range provision.OpenApiOperations {
+		ops[op.Method+" "+op.Path] = true
+	}
+
+	for _, route := range router.Routes() {
+		key := route.Method + " " + route.Path
+		if !ops[key] {
+			t.Errorf("route %s has no OpenApiOperations entry", key)
+		}
+	}
+
+	_, err := provision.NewOpenApiSpec(router.Routes(), Version)
+	if err != nil {
+		t.Errorf("NewOpenApiSpec: %s", err.Error())
+	}
+}
@@ -2,15 +2,40 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/gin-gonic/gin"
 	"github.com/txn2/micro"
 	"github.com/txn2/provision"
 )
 
+// Version is set at build time
+var Version = "0.0.0"
+
 var (
 	elasticServerEnv = getEnv("ELASTIC_SERVER", "http://elasticsearch:9200")
 	systemPrefixEnv  = getEnv("SYSTEM_PREFIX", "system_")
+	refreshExpEnv    = getEnv("REFRESH_TOKEN_EXP", strconv.Itoa(provision.RefreshTokenExpDefault))
+	tokenUserRefEnv  = getEnv("TOKEN_USER_REF", "false")
+	userCacheTTLEnv  = getEnv("USER_CACHE_TTL", strconv.Itoa(provision.UserCacheTTLDefault))
+	policyFileEnv    = getEnv("POLICY_FILE", "")
+	inheritAdminEnv  = getEnv("INHERIT_ADMIN", "false")
+	accountCacheEnv  = getEnv("ACCOUNT_CACHE_TTL", strconv.Itoa(provision.AccountCacheTTLDefault))
+	grpcPortEnv      = getEnv("GRPC_PORT", "")
+	rawSearchEnv     = getEnv("RAW_SEARCH", "false")
+
+	oidcIssuerEnv            = getEnv("OIDC_ISSUER", "")
+	oidcClientIdEnv          = getEnv("OIDC_CLIENT_ID", "")
+	oidcClientSecretEnv      = getEnv("OIDC_CLIENT_SECRET", "")
+	oidcRedirectUrlEnv       = getEnv("OIDC_REDIRECT_URL", "")
+	oidcLinkEmailEnv         = getEnv("OIDC_LINK_EMAIL", "false")
+	oidcAutoProvisionEnv     = getEnv("OIDC_AUTO_PROVISION", "false")
+	oidcProvisionAccountEnv  = getEnv("OIDC_PROVISION_ACCOUNT", "")
+	oidcProvisionSectionsEnv = getEnv("OIDC_PROVISION_SECTIONS", "")
 )
 
 func main() {
@@ -18,69 +43,230 @@ func main() {
 	esServer := flag.String("esServer", elasticServerEnv, "Elasticsearch Server")
 	systemPrefix := flag.String("systemPrefix", systemPrefixEnv, "Prefix for system indices.")
 
+	refreshExpInt, err := strconv.Atoi(refreshExpEnv)
+	if err != nil {
+		fmt.Println("Parsing error, refresh token expiration must be an integer in minutes.")
+		os.Exit(1)
+	}
+
+	refreshExp := flag.Int("refreshTokenExp", refreshExpInt, "Refresh token expiration in minutes.")
+
+	userCacheTTLInt, err := strconv.Atoi(userCacheTTLEnv)
+	if err != nil {
+		fmt.Println("Parsing error, user cache TTL must be an integer in seconds.")
+		os.Exit(1)
+	}
+
+	userCacheTTL := flag.Int("userCacheTTL", userCacheTTLInt, "Seconds to cache users resolved for token validation.")
+	tokenUserRef := flag.Bool("tokenUserRef", tokenUserRefEnv == "true", "Tokens carry only a user reference, resolved from storage.")
+	accountCacheTTLInt, err := strconv.Atoi(accountCacheEnv)
+	if err != nil {
+		fmt.Println("Parsing error, account cache TTL must be an integer in seconds.")
+		os.Exit(1)
+	}
+
+	accountCacheTTL := flag.Int("accountCacheTTL", accountCacheTTLInt, "Seconds to cache the account hierarchy.")
+	inheritAdmin := flag.Bool("inheritAdmin", inheritAdminEnv == "true", "Admins of an account are admins of its descendants.")
+	policyFile := flag.String("policyFile", policyFileEnv, "YAML access policy file, defaults to the built in policy.")
+	grpcPort := flag.String("grpcPort", grpcPortEnv, "gRPC port, disabled if empty. Unauthenticated and without TLS, keep internal.")
+	rawSearch := flag.Bool("rawSearch", rawSearchEnv == "true", "Sysops may search with Elasticsearch DSL.")
+
+	oidcIssuer := flag.String("oidcIssuer", oidcIssuerEnv, "OpenID Connect issuer, enables OIDC login.")
+	oidcClientId := flag.String("oidcClientId", oidcClientIdEnv, "OpenID Connect client id.")
+	oidcClientSecret := flag.String("oidcClientSecret", oidcClientSecretEnv, "OpenID Connect client secret.")
+	oidcRedirectUrl := flag.String("oidcRedirectUrl", oidcRedirectUrlEnv, "OpenID Connect redirect url (/oidc/callback).")
+	oidcLinkEmail := flag.Bool("oidcLinkEmail", oidcLinkEmailEnv == "true", "Link existing non-sysop users by verified email on first OIDC login.")
+	oidcAutoProvision := flag.Bool("oidcAutoProvision", oidcAutoProvisionEnv == "true", "Create users on first OIDC login.")
+	oidcProvisionAccount := flag.String("oidcProvisionAccount", oidcProvisionAccountEnv, "Account for auto-provisioned OIDC users.")
+	oidcProvisionSections := flag.String("oidcProvisionSections", oidcProvisionSectionsEnv, "Comma separated sections for auto-provisioned OIDC users.")
+
 	serverCfg, _ := micro.NewServerCfg("Provision")
 	server := micro.NewServer(serverCfg)
 
+	var oidcCfg *provision.OidcCfg
+	if *oidcIssuer != "" {
+		oidcCfg = &provision.OidcCfg{
+			Issuer:           *oidcIssuer,
+			ClientId:         *oidcClientId,
+			ClientSecret:     *oidcClientSecret,
+			RedirectUrl:      *oidcRedirectUrl,
+			LinkEmail:        *oidcLinkEmail,
+			AutoProvision:    *oidcAutoProvision,
+			ProvisionAccount: *oidcProvisionAccount,
+		}
+
+		if *oidcProvisionSections != "" {
+			oidcCfg.ProvisionSections = strings.Split(*oidcProvisionSections, ",")
+		}
+	}
+
+	var policy *provision.Policy
+	if *policyFile != "" {
+		policy, err = provision.LoadPolicy(*policyFile)
+		if err != nil {
+			server.Logger.Fatal("failure to load access policy: " + err.Error())
+			os.Exit(1)
+		}
+	}
+
 	// Provision API
 	provApi, err := provision.NewApi(&provision.Config{
-		Logger:        server.Logger,
-		HttpClient:    server.Client,
-		ElasticServer: *esServer,
-		IdxPrefix:     *systemPrefix,
-		Token:         server.Token,
+		Logger:          server.Logger,
+		HttpClient:      server.Client,
+		ElasticServer:   *esServer,
+		IdxPrefix:       *systemPrefix,
+		Token:           server.Token,
+		RefreshTokenExp: *refreshExp,
+		TokenUserRef:    *tokenUserRef,
+		UserCacheTTL:    *userCacheTTL,
+		Oidc:            oidcCfg,
+		Policy:          policy,
+		InheritAdmin:    *inheritAdmin,
+		AccountCacheTTL: *accountCacheTTL,
+		RawSearch:       *rawSearch,
 	})
 	if err != nil {
 		server.Logger.Fatal("failure to instantiate the provisioning API: " + err.Error())
 		os.Exit(1)
 	}
 
+	// provisioning routes
+	routes(server.Router, provApi)
+
+	// gRPC API backed by the same Api
+	if *grpcPort != "" {
+		lis, err := net.Listen("tcp", serverCfg.Ip+":"+*grpcPort)
+		if err != nil {
+			server.Logger.Fatal("failure to listen for gRPC: " + err.Error())
+			os.Exit(1)
+		}
+
+		go func() {
+			err := provApi.NewGrpcServer().Serve(lis)
+			if err != nil {
+				server.Logger.Fatal("gRPC server failure: " + err.Error())
+			}
+		}()
+	}
+
+	// run provisioning server
+	server.Run()
+}
+
+// routes registers the provisioning API routes, each must be
+// documented in provision.OpenApiOperations
+func routes(router *gin.Engine, provApi *provision.Api) {
 	// system prefix
-	server.Router.GET("/prefix", provApi.PrefixHandler)
+	router.GET("/prefix", provApi.PrefixHandler)
+
+	// OpenAPI document of the routes below
+	router.GET("/openapi.json", provision.OpenApiHandler(router, Version))
 
 	// Upsert an account
-	server.Router.POST("/account", provApi.UpsertAccountHandler)
+	router.POST("/account", provApi.UpsertAccountHandler)
 
 	// Get an account
-	server.Router.GET("/account/:id", provApi.GetAccountHandler)
+	router.GET("/account/:id", provApi.GetAccountHandler)
 
 	// Check an account for an active key
-	server.Router.POST("/keyCheck/:id", provApi.CheckKeyHandler)
+	router.POST("/keyCheck/:id", provApi.CheckKeyHandler)
 
 	// Search accounts
-	server.Router.POST("/searchAccounts", provApi.SearchAccountsHandler)
+	router.POST("/searchAccounts", provApi.SearchAccountsHandler)
 
 	// Upsert a user
-	server.Router.POST("/user", provApi.UpsertUserHandler)
+	router.POST("/user", provApi.UpsertUserHandler)
 
 	// Get a user
-	server.Router.GET("/user/:id", provApi.GetUserHandler)
+	router.GET("/user/:id", provApi.GetUserHandler)
 
 	// Search users
-	server.Router.POST("/searchUsers", provApi.SearchUsersHandler)
+	router.POST("/searchUsers", provApi.SearchUsersHandler)
 
 	// User has basic access (checks token and access request object)
-	server.Router.POST("/userHasAccess", provision.UserTokenHandler(), provision.UserHasAccessHandler)
+	router.POST("/userHasAccess", provApi.UserTokenHandler(), provApi.UserHasAccessHandler)
 
 	// User has admin access (checks token and access request object)
-	server.Router.POST("/userHasAdminAccess", provision.UserTokenHandler(), provision.UserHasAdminAccessHandler)
+	router.POST("/userHasAdminAccess", provApi.UserTokenHandler(), provApi.UserHasAdminAccessHandler)
+
+	// Evaluate a list of access and admin checks for a token
+	router.POST("/userHasAccessBatch", provApi.UserTokenHandler(), provApi.UserHasAccessBatchHandler)
+
+	// Effective permissions of the token user
+	router.GET("/whoami", provApi.UserTokenHandler(), provApi.WhoAmIHandler)
 
 	// Auth a user
-	server.Router.POST("/authUser", provApi.AuthUserHandler)
+	router.POST("/authUser", provApi.AuthUserHandler)
+
+	// Start an OpenID Connect login
+	router.GET("/oidc/login", provApi.OidcLoginHandler)
+
+	// Complete an OpenID Connect login and receive a token
+	router.GET("/oidc/callback", provApi.OidcCallbackHandler)
+
+	// Accept an invite, creating or linking a user
+	router.POST("/invite/accept", provApi.AcceptInviteHandler)
+
+	// Exchange a refresh token for a new token pair
+	router.POST("/token/refresh", provApi.RefreshTokenHandler)
+
+	// Revoke the current token and optionally a refresh token
+	router.POST("/token/revoke", provApi.UserTokenHandler(), provApi.RevokeTokenHandler)
+
+	// List personal api tokens for the token user
+	router.GET("/apiTokens", provApi.UserTokenHandler(), provApi.ListApiTokensHandler)
+
+	// Create a personal api token for the token user
+	router.POST("/apiTokens", provApi.UserTokenHandler(), provApi.CreateApiTokenHandler)
+
+	// Revoke a personal api token of the token user
+	router.DELETE("/apiTokens/:name", provApi.UserTokenHandler(), provApi.RevokeApiTokenHandler)
+
+	// Upsert a role, global or for an account
+	router.POST("/role", provApi.UpsertRoleHandler)
+
+	// Get a role (?account= for an account role)
+	router.GET("/role/:id", provApi.GetRoleHandler)
+
+	// Search roles
+	router.POST("/searchRoles", provApi.SearchRolesHandler)
+
+	// Upsert a group
+	router.POST("/group", provApi.UpsertGroupHandler)
+
+	// Get a group
+	router.GET("/group/:id", provApi.GetGroupHandler)
+
+	// Search groups
+	router.POST("/searchGroups", provApi.SearchGroupsHandler)
 
 	// Upsert an asset
-	server.Router.POST("/asset", provApi.UpsertAssetHandler)
+	router.POST("/asset", provApi.UpsertAssetHandler)
 
 	// Get an asset
-	server.Router.GET("/asset/:id", provApi.GetAssetHandler)
+	router.GET("/asset/:id", provApi.GetAssetHandler)
 
 	// Search assets
-	server.Router.POST("/searchAssets", provApi.SearchAssetsHandler)
+	router.POST("/searchAssets", provApi.SearchAssetsHandler)
+
+	// SCIM 2.0 provisioning scoped to the account of the
+	// bearer token <account>:<scim access key>
+	scim := router.Group("/scim/v2", provApi.ScimTokenHandler())
+	scim.GET("/Users", provApi.ScimListUsersHandler)
+	scim.POST("/Users", provApi.ScimCreateUserHandler)
+	scim.GET("/Users/:id", provApi.ScimGetUserHandler)
+	scim.PUT("/Users/:id", provApi.ScimReplaceUserHandler)
+	scim.PATCH("/Users/:id", provApi.ScimPatchUserHandler)
+	scim.DELETE("/Users/:id", provApi.ScimDeleteUserHandler)
+	scim.GET("/Groups", provApi.ScimListGroupsHandler)
+	scim.GET("/Groups/:id", provApi.ScimGetGroupHandler)
+	scim.PATCH("/Groups/:id", provApi.ScimPatchGroupHandler)
 
 	// Account Admin Routes
 	// use internally with no authentication or
 	// use through the adm proxy externally which validates
 	// access to :parentAccount
-	adm := server.Router.Group("/adm/:parentAccount")
+	adm := router.Group("/adm/:parentAccount")
 
 	// Get Account
 	// must be the same account or a child
@@ -98,11 +284,44 @@ func main() {
 	// Asset Re-Association
 	adm.GET("/assetAssoc/:asset/:accountFrom/:accountTo", provApi.AssetAdmAssocHandler)
 
+	// Search assets of the parent and descendant accounts
+	adm.POST("/searchAssets", provApi.SearchAdmAssetsHandler)
+
 	// Upsert user for child account
 	adm.POST("/user", provApi.UpsertAdmChildAccountUserHandler)
 
-	// run provisioning server
-	server.Run()
+	// Search users of the parent and descendant accounts
+	adm.POST("/searchUsers", provApi.SearchAdmUsersHandler)
+
+	// Upsert a role for the parent or a child account
+	adm.POST("/role", provApi.UpsertAdmRoleHandler)
+
+	// Upsert a group owned by the parent or a child account
+	adm.POST("/group", provApi.UpsertAdmGroupHandler)
+
+	// Groups owned by the parent and child accounts
+	adm.GET("/groups", provApi.GetAdmGroupsHandler)
+
+	// Get a group
+	adm.GET("/group/:group", provApi.GetAdmGroupHandler)
+
+	// Remove a group
+	adm.DELETE("/group/:group", provApi.DeleteAdmGroupHandler)
+
+	// Add a user to a group
+	adm.PUT("/group/:group/member/:user", provApi.AddAdmGroupMemberHandler)
+
+	// Remove a user from a group
+	adm.DELETE("/group/:group/member/:user", provApi.RemoveAdmGroupMemberHandler)
+
+	// Invite an email to the parent or a child account
+	adm.POST("/invite", provApi.CreateAdmInviteHandler)
+
+	// Pending invites for the parent and child accounts
+	adm.GET("/invites", provApi.GetAdmInvitesHandler)
+
+	// Revoke a pending invite
+	adm.DELETE("/invite/:invite", provApi.RevokeAdmInviteHandler)
 }
 
 // getEnv gets an environment variable or sets a default if
@@ -0,0 +1,714 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/txn2/es/v2"
+	"github.com/txn2/provision"
+	"github.com/txn2/provision/client"
+	"golang.org/x/crypto/ssh/terminal"
+	"gopkg.in/yaml.v2"
+)
+
+// ListSizeDefault is the number of results of list and search
+const ListSizeDefault = 100
+
+// errDenied exits with status 3 after printing a failed access
+// or key check
+var errDenied = errors.New("denied")
+
+// configCmd manages contexts
+func (cl *cli) configCmd(args []string) error {
+	if len(args) < 1 {
+		return errors.New("config requires get-contexts, current-context, use-context, set-context or delete-context")
+	}
+
+	switch args[0] {
+	case "get-contexts":
+		// never print tokens
+		contexts := make([]Context, 0, len(cl.cfg.Contexts))
+		tbl := &table{header: []string{"CURRENT", "NAME", "SERVER", "USER"}}
+		for _, pctx := range cl.cfg.Contexts {
+			current := ""
+			if pctx.Name == cl.cfg.CurrentContext {
+				current = "*"
+			}
+			contexts = append(contexts, Context{Name: pctx.Name, Server: pctx.Server, User: pctx.User})
+			tbl.rows = append(tbl.rows, []string{current, pctx.Name, pctx.Server, pctx.User})
+		}
+		return cl.out.print(contexts, tbl)
+
+	case "current-context":
+		if cl.cfg.CurrentContext == "" {
+			return errors.New("no current context")
+		}
+		fmt.Println(cl.cfg.CurrentContext)
+		return nil
+
+	case "use-context":
+		if len(args) != 2 {
+			return errors.New("use-context requires a NAME")
+		}
+		if _, err := cl.cfg.context(args[1]); err != nil {
+			return err
+		}
+		cl.cfg.CurrentContext = args[1]
+		return cl.cfg.save()
+
+	case "set-context":
+		fs := flag.NewFlagSet("set-context", flag.ContinueOnError)
+		server := fs.String("server", "", "Provision server, e.g. http://localhost:8080.")
+		positional, err := parse(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(positional) != 1 {
+			return errors.New("set-context requires a NAME")
+		}
+
+		pctx := Context{Name: positional[0]}
+		if existing, err := cl.cfg.context(pctx.Name); err == nil {
+			pctx = *existing
+		}
+		if *server != "" {
+			pctx.Server = *server
+		}
+		if pctx.Server == "" {
+			return errors.New("set-context requires -server for a new context")
+		}
+
+		cl.cfg.setContext(pctx)
+		if cl.cfg.CurrentContext == "" {
+			cl.cfg.CurrentContext = pctx.Name
+		}
+		return cl.cfg.save()
+
+	case "delete-context":
+		if len(args) != 2 {
+			return errors.New("delete-context requires a NAME")
+		}
+		if !cl.cfg.deleteContext(args[1]) {
+			return errors.New("context " + args[1] + " not found")
+		}
+		return cl.cfg.save()
+	}
+
+	return errors.New("unknown config command " + args[0])
+}
+
+// loginCmd authenticates a user storing the token in the context
+func (cl *cli) loginCmd(args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	id := fs.String("id", "", "User id, defaults to the user of the context.")
+	password := fs.String("password", getEnv("PROVISIONCTL_PASSWORD", ""), "Password, prompted for if empty.")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	pctx, err := cl.cfg.context(cl.contextName)
+	if err != nil {
+		return err
+	}
+
+	if *id == "" {
+		*id = pctx.User
+	}
+	if *id == "" {
+		return errors.New("login requires -id")
+	}
+
+	if *password == "" {
+		*password, err = readPassword()
+		if err != nil {
+			return err
+		}
+	}
+
+	server := pctx.Server
+	if cl.server != "" {
+		server = cl.server
+	}
+
+	c, err := client.New(&client.Config{Server: server})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := cl.context()
+	defer cancel()
+
+	utr, err := c.AuthUser(ctx, provision.Auth{Id: *id, Password: *password})
+	if err != nil {
+		return err
+	}
+
+	pctx.User = *id
+	pctx.Token = utr.Token
+	pctx.RefreshToken = utr.RefreshToken
+	pctx.Expires = utr.Expires
+
+	err = cl.cfg.save()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Logged in to "+pctx.Name+" as "+*id+".")
+	return nil
+}
+
+// logoutCmd revokes and removes the token of the context
+func (cl *cli) logoutCmd(args []string) error {
+	pctx, err := cl.cfg.context(cl.contextName)
+	if err != nil {
+		return err
+	}
+
+	if pctx.Token != "" {
+		c, err := cl.client()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := cl.context()
+		defer cancel()
+
+		err = c.RevokeToken(ctx, pctx.RefreshToken)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Warning: unable to revoke token:", err)
+		}
+	}
+
+	pctx.Token = ""
+	pctx.RefreshToken = ""
+	pctx.Expires = 0
+
+	return cl.cfg.save()
+}
+
+// whoamiCmd prints the effective permissions of the token user
+func (cl *cli) whoamiCmd(args []string) error {
+	c, err := cl.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := cl.context()
+	defer cancel()
+
+	ep, err := c.WhoAmI(ctx)
+	if err != nil {
+		return err
+	}
+
+	tbl := &table{header: []string{"ACCOUNT", "DISPLAY NAME", "ADMIN", "SECTIONS", "DENY SECTIONS"}}
+	tbl.rows = append(tbl.rows, []string{"*", ep.User.Id, strconv.FormatBool(ep.Sysop), sections(ep.SectionsAll, ep.Sections), list(ep.DenySections)})
+	for _, ea := range ep.Accounts {
+		tbl.rows = append(tbl.rows, []string{ea.Id, ea.DisplayName, strconv.FormatBool(ea.Admin), sections(ea.SectionsAll, ea.Sections), list(ea.DenySections)})
+	}
+
+	return cl.out.print(ep, tbl)
+}
+
+// accessCmd checks the access of the token user
+func (cl *cli) accessCmd(args []string) error {
+	if len(args) < 1 || args[0] != "check" {
+		return errors.New("access requires check")
+	}
+
+	fs := flag.NewFlagSet("access check", flag.ContinueOnError)
+	secs := fs.String("sections", "", "Comma separated sections.")
+	accounts := fs.String("accounts", "", "Comma separated accounts.")
+	admin := fs.Bool("admin", false, "Check admin access to the accounts.")
+	mode := fs.String("mode", provision.AccessModeAll, "all or any of the accounts.")
+	explain := fs.Bool("explain", false, "Include the policy trace.")
+	if _, err := parse(fs, args[1:]); err != nil {
+		return err
+	}
+
+	c, err := cl.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := cl.context()
+	defer cancel()
+
+	ac := provision.AccessCheck{
+		Sections: split(*secs),
+		Accounts: split(*accounts),
+		Mode:     *mode,
+	}
+
+	check := c.UserHasAccess
+	if *admin {
+		check = c.UserHasAdminAccess
+	}
+
+	acr, err := check(ctx, ac, *explain)
+	if err != nil {
+		return err
+	}
+
+	tbl := &table{
+		header: []string{"STATUS", "MESSAGE"},
+		rows:   [][]string{{strconv.FormatBool(acr.Status), acr.Message}},
+	}
+	if acr.Explanation != nil {
+		tbl.header = append(tbl.header, "RULE")
+		tbl.rows[0] = append(tbl.rows[0], acr.Explanation.Decision.Rule)
+	}
+
+	err = cl.out.print(acr, tbl)
+	if err == nil && !acr.Status {
+		return errDenied
+	}
+
+	return err
+}
+
+// resourceCmd runs get, list, create, update and delete on an
+// account, user or asset
+func (cl *cli) resourceCmd(r *resource, args []string) error {
+	if len(args) < 1 {
+		return errors.New(r.name + " requires get, list, create, update or delete")
+	}
+
+	cmd := args[0]
+
+	fs := flag.NewFlagSet(r.name+" "+cmd, flag.ContinueOnError)
+	file := fs.String("f", "", "JSON or YAML file, - for stdin.")
+	query := fs.String("q", "", "Text query, e.g. jane.")
+	size := fs.Int("size", ListSizeDefault, "Maximum results.")
+	cursor := fs.String("cursor", "", "Continue a list after the previous page.")
+	description := fs.String("description", "", "Key description.")
+	positional, err := parse(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	c, err := cl.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := cl.context()
+	defer cancel()
+
+	switch cmd {
+	case "get":
+		if len(positional) != 1 {
+			return errors.New(r.name + " get requires an ID")
+		}
+		v, err := r.get(ctx, c, positional[0])
+		if err != nil {
+			return err
+		}
+		return cl.out.print(v, r.table([]interface{}{v}))
+
+	case "list":
+		items, total, next, err := r.search(ctx, c, provision.SearchQuery{Text: *query, Size: *size, Cursor: *cursor}, nil)
+		if err != nil {
+			return err
+		}
+		if total > len(items) {
+			fmt.Fprintf(os.Stderr, "Showing %d of %d, use -size or -q.\n", len(items), total)
+		}
+		nextPage(next)
+		return cl.out.print(items, r.table(items))
+
+	case "create", "update":
+		if *file == "" {
+			return errors.New(r.name + " " + cmd + " requires -f FILE")
+		}
+		v := r.new()
+		err := readObject(*file, v)
+		if err != nil {
+			return err
+		}
+		id := r.id(v)
+		if id == "" {
+			return errors.New(r.name + " requires an id")
+		}
+
+		// create must not replace, update must not create
+		_, err = r.get(ctx, c, id)
+		if cmd == "create" && err == nil {
+			return errors.New(r.name + " " + id + " exists, use update")
+		}
+		if (cmd == "create" && !notFound(err)) || (cmd == "update" && err != nil) {
+			return err
+		}
+
+		result, err := r.upsert(ctx, c, v)
+		if err != nil {
+			return err
+		}
+		return cl.out.print(result, resultTable(result))
+
+	case "delete":
+		if len(positional) != 1 {
+			return errors.New(r.name + " delete requires an ID")
+		}
+		v, err := r.get(ctx, c, positional[0])
+		if err != nil {
+			return err
+		}
+		r.deactivate(v)
+		result, err := r.upsert(ctx, c, v)
+		if err != nil {
+			return err
+		}
+		return cl.out.print(result, resultTable(result))
+
+	case "rotate-key":
+		if r != accountResource {
+			break
+		}
+		if len(positional) != 2 {
+			return errors.New("rotate-key requires an account ID and key NAME")
+		}
+		return cl.rotateKey(ctx, c, positional[0], positional[1], *description)
+
+	case "check-key":
+		if r != accountResource {
+			break
+		}
+		if len(positional) != 3 {
+			return errors.New("check-key requires an account ID, key NAME and KEY")
+		}
+		ok, err := c.CheckKey(ctx, positional[0], provision.AccessKey{Name: positional[1], Key: positional[2]})
+		if err != nil {
+			return err
+		}
+		err = cl.out.print(ok, &table{header: []string{"VALID"}, rows: [][]string{{strconv.FormatBool(ok)}}})
+		if err == nil && !ok {
+			return errDenied
+		}
+		return err
+	}
+
+	return errors.New("unknown " + r.name + " command " + cmd)
+}
+
+// KeyRotation is the output of rotate-key, the only time the
+// new key is available
+type KeyRotation struct {
+	Account string `json:"account" yaml:"account"`
+	Name    string `json:"name" yaml:"name"`
+	Key     string `json:"key" yaml:"key"`
+}
+
+// rotateKey replaces the access key name of an account with a
+// new random key, adding the key if the account has none by
+// that name
+func (cl *cli) rotateKey(ctx context.Context, c *client.Client, id string, name string, description string) error {
+	accountResult, err := c.GetAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+
+	key := hex.EncodeToString(secret)
+	account := &accountResult.Source
+
+	found := false
+	for i := range account.AccessKeys {
+		if account.AccessKeys[i].Name == name {
+			account.AccessKeys[i].Key = key
+			account.AccessKeys[i].Active = true
+			if description != "" {
+				account.AccessKeys[i].Description = description
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		account.AccessKeys = append(account.AccessKeys, provision.AccessKey{
+			Name:        name,
+			Description: description,
+			Key:         key,
+			Active:      true,
+		})
+	}
+
+	_, err = c.UpsertAccount(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	kr := KeyRotation{Account: id, Name: name, Key: key}
+
+	fmt.Fprintln(os.Stderr, "Store the key now, it can not be retrieved.")
+	return cl.out.print(kr, &table{
+		header: []string{"ACCOUNT", "NAME", "KEY"},
+		rows:   [][]string{{kr.Account, kr.Name, kr.Key}},
+	})
+}
+
+// searchCmd searches accounts, users, assets, roles or groups
+// with a SearchQuery, or Elasticsearch DSL with -dsl, from a file
+// or a query string
+func (cl *cli) searchCmd(args []string) error {
+	if len(args) < 1 {
+		return errors.New("search requires accounts, users, assets, roles or groups")
+	}
+
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	file := fs.String("f", "", "SearchQuery, or Elasticsearch query for -dsl, as a JSON or YAML file, - for stdin.")
+	query := fs.String("q", "", "Text query, query string query for -dsl.")
+	size := fs.Int("size", ListSizeDefault, "Maximum results.")
+	cursor := fs.String("cursor", "", "Continue a search after the previous page.")
+	dsl := fs.Bool("dsl", false, "Search with Elasticsearch DSL, sysops only.")
+	if _, err := parse(fs, args[1:]); err != nil {
+		return err
+	}
+
+	c, err := cl.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := cl.context()
+	defer cancel()
+
+	r := resources[strings.TrimSuffix(args[0], "s")]
+	if (r == nil || args[0] != r.name+"s") && args[0] != "roles" && args[0] != "groups" {
+		return errors.New("unknown search " + args[0])
+	}
+
+	// a SearchQuery, or Elasticsearch DSL in body with -dsl
+	sq := provision.SearchQuery{}
+	var body es.Obj
+
+	if *dsl {
+		if *cursor != "" {
+			return errors.New("-cursor can not be used with -dsl")
+		}
+
+		if *file != "" {
+			body = es.Obj{}
+			err := readObject(*file, &body)
+			if err != nil {
+				return err
+			}
+		}
+
+		body = searchQuery(body, *query, *size)
+	} else {
+		if *file != "" {
+			err := readObject(*file, &sq)
+			if err != nil {
+				return err
+			}
+		}
+
+		if *query != "" {
+			sq.Text = *query
+		}
+
+		if *cursor != "" {
+			sq.Cursor = *cursor
+		}
+
+		if sq.Size == 0 || *size != ListSizeDefault {
+			sq.Size = *size
+		}
+	}
+
+	switch args[0] {
+	case "roles":
+		var results *provision.RoleSearchResults
+		if *dsl {
+			results, err = c.SearchRolesDsl(ctx, body)
+		} else {
+			results, err = c.SearchRoles(ctx, sq)
+		}
+		if err != nil {
+			return err
+		}
+		items := make([]provision.Role, 0)
+		tbl := &table{header: []string{"ID", "ACCOUNT", "DISPLAY NAME", "SECTIONS"}}
+		for _, hit := range results.Hits.Hits {
+			role := hit.Source
+			items = append(items, role)
+			tbl.rows = append(tbl.rows, []string{role.Id, role.Account, role.DisplayName, sections(role.SectionsAll, role.Sections)})
+		}
+		nextPage(results.NextCursor)
+		return cl.out.print(items, tbl)
+
+	case "groups":
+		var results *provision.GroupSearchResults
+		if *dsl {
+			results, err = c.SearchGroupsDsl(ctx, body)
+		} else {
+			results, err = c.SearchGroups(ctx, sq)
+		}
+		if err != nil {
+			return err
+		}
+		items := make([]provision.Group, 0)
+		tbl := &table{header: []string{"ID", "ACCOUNT", "DISPLAY NAME", "MEMBERS"}}
+		for _, hit := range results.Hits.Hits {
+			group := hit.Source
+			items = append(items, group)
+			tbl.rows = append(tbl.rows, []string{group.Id, group.Account, group.DisplayName, list(group.Members)})
+		}
+		nextPage(results.NextCursor)
+		return cl.out.print(items, tbl)
+	}
+
+	items, _, next, err := r.search(ctx, c, sq, body)
+	if err != nil {
+		return err
+	}
+	nextPage(next)
+	return cl.out.print(items, r.table(items))
+}
+
+// nextPage prints the cursor of the next page
+func nextPage(cursor string) {
+	if cursor != "" {
+		fmt.Fprintf(os.Stderr, "Next page: -cursor %s\n", cursor)
+	}
+}
+
+// searchQuery sets the size of a search body and a query string
+// query, a nil body matches all
+func searchQuery(body es.Obj, query string, size int) es.Obj {
+	if body == nil {
+		body = es.Obj{"query": es.Obj{"match_all": es.Obj{}}}
+	}
+
+	if query != "" {
+		body["query"] = es.Obj{"query_string": es.Obj{"query": query}}
+	}
+
+	if _, ok := body["size"]; !ok || size != ListSizeDefault {
+		body["size"] = size
+	}
+
+	return body
+}
+
+// resultTable of an upsert
+func resultTable(result *es.Result) *table {
+	return &table{
+		header: []string{"ID", "RESULT", "VERSION"},
+		rows:   [][]string{{result.Id, result.ResultType, strconv.Itoa(result.Version)}},
+	}
+}
+
+// readObject unmarshals a JSON or YAML file, - reads stdin
+func readObject(file string, v interface{}) error {
+	var data []byte
+	var err error
+
+	if file == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return json.Unmarshal(data, v)
+	}
+
+	// yaml uses the json field names of the api
+	var doc interface{}
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(jsonValue(doc))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// jsonValue converts yaml maps to json objects
+func jsonValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		obj := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			obj[fmt.Sprintf("%v", k)] = jsonValue(item)
+		}
+		return obj
+	case []interface{}:
+		for i := range val {
+			val[i] = jsonValue(val[i])
+		}
+	}
+
+	return v
+}
+
+// readPassword prompts for a password, without echo on a terminal
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		pw, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(pw), err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// notFound is true for a not found error
+func notFound(err error) bool {
+	e, ok := err.(*client.Error)
+	return ok && e.Is(client.ErrNotFound)
+}
+
+// split comma separated values
+func split(v string) []string {
+	values := make([]string, 0)
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			values = append(values, s)
+		}
+	}
+
+	return values
+}
+
+// sections for a table cell
+func sections(all bool, secs []string) string {
+	if all {
+		return "*"
+	}
+
+	return list(secs)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Context is a provision environment and the token of the
+// user logged in to it
+type Context struct {
+	Name         string `yaml:"name"`
+	Server       string `yaml:"server"`
+	User         string `yaml:"user,omitempty"`
+	Token        string `yaml:"token,omitempty"`
+	RefreshToken string `yaml:"refreshToken,omitempty"`
+	Expires      int64  `yaml:"expires,omitempty"`
+}
+
+// Config is stored in the config file, readable only by its
+// owner as it holds tokens
+type Config struct {
+	CurrentContext string    `yaml:"currentContext"`
+	Contexts       []Context `yaml:"contexts"`
+
+	file string
+}
+
+// defaultConfigFile is ~/.provisionctl.yaml
+func defaultConfigFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".provisionctl.yaml"
+	}
+
+	return filepath.Join(home, ".provisionctl.yaml")
+}
+
+// loadConfig reads the config file, a missing file is an
+// empty config
+func loadConfig(file string) (*Config, error) {
+	cfg := &Config{file: file}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(data, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// save writes the config file
+func (cfg *Config) save() error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(cfg.file, data, 0600)
+}
+
+// context returns a context by name, the current context if
+// name is empty
+func (cfg *Config) context(name string) (*Context, error) {
+	if name == "" {
+		name = cfg.CurrentContext
+	}
+
+	if name == "" {
+		return nil, errors.New("no context, use: provisionctl config set-context NAME -server URL")
+	}
+
+	for i := range cfg.Contexts {
+		if cfg.Contexts[i].Name == name {
+			return &cfg.Contexts[i], nil
+		}
+	}
+
+	return nil, errors.New("context " + name + " not found")
+}
+
+// setContext adds or replaces a context
+func (cfg *Config) setContext(ctx Context) {
+	for i := range cfg.Contexts {
+		if cfg.Contexts[i].Name == ctx.Name {
+			cfg.Contexts[i] = ctx
+			return
+		}
+	}
+
+	cfg.Contexts = append(cfg.Contexts, ctx)
+}
+
+// deleteContext removes a context
+func (cfg *Config) deleteContext(name string) bool {
+	for i := range cfg.Contexts {
+		if cfg.Contexts[i].Name == name {
+			cfg.Contexts = append(cfg.Contexts[:i], cfg.Contexts[i+1:]...)
+			if cfg.CurrentContext == name {
+				cfg.CurrentContext = ""
+			}
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+const OutputTable = "table"
+const OutputJson = "json"
+const OutputYaml = "yaml"
+
+// table is the table output of a command
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// printer writes command results in the selected format
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+// print v as json or yaml, or tbl as a table. Commands without
+// a table print yaml.
+func (p *printer) print(v interface{}, tbl *table) error {
+	switch p.format {
+	case OutputJson:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(p.w, string(data))
+		return err
+	case OutputYaml:
+		return p.yaml(v)
+	case OutputTable:
+		if tbl == nil {
+			return p.yaml(v)
+		}
+		return p.table(tbl)
+	}
+
+	return errors.New("unknown output " + p.format + ", use table, json or yaml")
+}
+
+// yaml with the json field names of the api
+func (p *printer) yaml(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var doc interface{}
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return err
+	}
+
+	data, err = yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.w.Write(data)
+	return err
+}
+
+// table
+func (p *printer) table(tbl *table) error {
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join(tbl.header, "\t"))
+	for _, row := range tbl.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// list joins values for a table cell
+func list(values []string) string {
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/txn2/provision/client"
+)
+
+// Version is set at build time
+var Version = "0.0.0"
+
+var (
+	configFileEnv = getEnv("PROVISIONCTL_CONFIG", defaultConfigFile())
+	contextEnv    = getEnv("PROVISIONCTL_CONTEXT", "")
+	tokenEnv      = getEnv("PROVISION_TOKEN", "")
+)
+
+const usageText = `provisionctl manages accounts, users and assets of a provision service.
+
+Usage:
+  provisionctl [flags] COMMAND
+
+Commands:
+  config get-contexts
+  config current-context
+  config use-context NAME
+  config set-context NAME -server URL
+  config delete-context NAME
+
+  login [-id USER] [-password PASSWORD]
+  logout
+  whoami
+
+  account|user|asset get ID
+  account|user|asset list [-q TEXT] [-size N] [-cursor CURSOR]
+  account|user|asset create -f FILE
+  account|user|asset update -f FILE
+  account|user|asset delete ID
+
+  account rotate-key ID NAME [-description TEXT]
+  account check-key ID NAME KEY
+
+  access check -sections LIST [-accounts LIST] [-admin] [-mode all|any] [-explain]
+
+  search accounts|users|assets|roles|groups [-f FILE] [-q TEXT] [-size N] [-cursor CURSOR] [-dsl]
+
+Objects for create and update are JSON or YAML, - reads stdin.
+Delete deactivates, provision keeps account, user and asset records.
+
+Flags:
+`
+
+// cli holds the global flags
+type cli struct {
+	cfg         *Config
+	contextName string
+	server      string
+	token       string
+	timeout     time.Duration
+	out         *printer
+}
+
+func main() {
+	configFile := flag.String("config", configFileEnv, "Config file holding contexts and tokens.")
+	contextName := flag.String("context", contextEnv, "Context to use, defaults to the current context.")
+	server := flag.String("server", "", "Provision server, overrides the context.")
+	tkn := flag.String("token", tokenEnv, "Bearer token, overrides the context.")
+	output := flag.String("o", OutputTable, "Output format: table, json or yaml.")
+	timeout := flag.Duration("timeout", client.TimeoutDefault, "Request timeout.")
+	version := flag.Bool("version", false, "Print the version.")
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usageText)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if *version {
+		fmt.Println(Version)
+		return
+	}
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: unable to read config:", err)
+		os.Exit(1)
+	}
+
+	cl := &cli{
+		cfg:         cfg,
+		contextName: *contextName,
+		server:      *server,
+		token:       *tkn,
+		timeout:     *timeout,
+		out:         &printer{format: *output, w: os.Stdout},
+	}
+
+	err = cl.run(flag.Args())
+	if err == errDenied {
+		os.Exit(3)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// run a command
+func (cl *cli) run(args []string) error {
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case "config":
+		return cl.configCmd(args)
+	case "login":
+		return cl.loginCmd(args)
+	case "logout":
+		return cl.logoutCmd(args)
+	case "whoami":
+		return cl.whoamiCmd(args)
+	case "access":
+		return cl.accessCmd(args)
+	case "search":
+		return cl.searchCmd(args)
+	}
+
+	if r, ok := resources[cmd]; ok {
+		return cl.resourceCmd(r, args)
+	}
+
+	return fmt.Errorf("unknown command %s, see provisionctl -h", cmd)
+}
+
+// context for a request
+func (cl *cli) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), cl.timeout)
+}
+
+// client for the selected context, refreshing an expired
+// login token
+func (cl *cli) client() (*client.Client, error) {
+	server, token := cl.server, cl.token
+
+	pctx, err := cl.cfg.context(cl.contextName)
+	if err != nil && server == "" {
+		return nil, err
+	}
+
+	if pctx != nil {
+		if server == "" {
+			server = pctx.Server
+		}
+
+		if token == "" {
+			token, err = cl.contextToken(pctx, server)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return client.New(&client.Config{Server: server, Token: token})
+}
+
+// contextToken returns the token of a context, exchanging the
+// refresh token for a new one when it expires within a minute
+func (cl *cli) contextToken(pctx *Context, server string) (string, error) {
+	if pctx.RefreshToken == "" || pctx.Expires == 0 || time.Now().Unix() < pctx.Expires-60 {
+		return pctx.Token, nil
+	}
+
+	c, err := client.New(&client.Config{Server: server})
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := cl.context()
+	defer cancel()
+
+	utr, err := c.RefreshToken(ctx, pctx.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("login for context %s expired, login again: %s", pctx.Name, err.Error())
+	}
+
+	pctx.Token = utr.Token
+	pctx.RefreshToken = utr.RefreshToken
+	pctx.Expires = utr.Expires
+
+	return pctx.Token, cl.cfg.save()
+}
+
+// parse subcommand flags allowing them after positional
+// arguments, returning the positional arguments
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// getEnv gets an environment variable or sets a default if
+// one does not exist.
+func getEnv(key, fallback string) string {
+	value := os.Getenv(key)
+	if len(value) == 0 {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/txn2/es/v2"
+	"github.com/txn2/provision"
+	"github.com/txn2/provision/client"
+)
+
+// resource is an account, user or asset, managed by the get,
+// list, create, update and delete commands
+type resource struct {
+	name string
+
+	// new returns an empty object for create and update
+	new func() interface{}
+	id  func(v interface{}) string
+
+	get func(ctx context.Context, c *client.Client, id string) (interface{}, error)
+
+	// search posts dsl in place of query when not nil, returning
+	// the items, total and next cursor
+	search func(ctx context.Context, c *client.Client, query provision.SearchQuery, dsl es.Obj) ([]interface{}, int, string, error)
+	upsert func(ctx context.Context, c *client.Client, v interface{}) (*es.Result, error)
+
+	// deactivate for delete, provision keeps records
+	deactivate func(v interface{})
+
+	header []string
+	row    func(v interface{}) []string
+}
+
+// resources by name
+var resources = map[string]*resource{
+	"account": accountResource,
+	"user":    userResource,
+	"asset":   assetResource,
+}
+
+var accountResource = &resource{
+	name: "account",
+	new:  func() interface{} { return &provision.Account{} },
+	id:   func(v interface{}) string { return v.(*provision.Account).Id },
+	get: func(ctx context.Context, c *client.Client, id string) (interface{}, error) {
+		accountResult, err := c.GetAccount(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &accountResult.Source, nil
+	},
+	search: func(ctx context.Context, c *client.Client, query provision.SearchQuery, dsl es.Obj) ([]interface{}, int, string, error) {
+		var results *provision.AccountSearchResults
+		var err error
+		if dsl != nil {
+			results, err = c.SearchAccountsDsl(ctx, dsl)
+		} else {
+			results, err = c.SearchAccounts(ctx, query)
+		}
+		if err != nil {
+			return nil, 0, "", err
+		}
+		items := make([]interface{}, 0, len(results.Hits.Hits))
+		for i := range results.Hits.Hits {
+			items = append(items, &results.Hits.Hits[i].Source)
+		}
+		return items, results.Hits.Total, results.NextCursor, nil
+	},
+	upsert: func(ctx context.Context, c *client.Client, v interface{}) (*es.Result, error) {
+		return c.UpsertAccount(ctx, v.(*provision.Account))
+	},
+	deactivate: func(v interface{}) { v.(*provision.Account).Active = false },
+	header:     []string{"ID", "DISPLAY NAME", "PARENT", "ACTIVE", "MODULES"},
+	row: func(v interface{}) []string {
+		account := v.(*provision.Account)
+		return []string{
+			account.Id,
+			account.DisplayName,
+			account.Parent,
+			strconv.FormatBool(account.Active),
+			list(account.Modules),
+		}
+	},
+}
+
+var userResource = &resource{
+	name: "user",
+	new:  func() interface{} { return &provision.User{} },
+	id:   func(v interface{}) string { return v.(*provision.User).Id },
+	get: func(ctx context.Context, c *client.Client, id string) (interface{}, error) {
+		userResult, err := c.GetUser(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &userResult.Source, nil
+	},
+	search: func(ctx context.Context, c *client.Client, query provision.SearchQuery, dsl es.Obj) ([]interface{}, int, string, error) {
+		var results *provision.UserSearchResults
+		var err error
+		if dsl != nil {
+			results, err = c.SearchUsersDsl(ctx, dsl)
+		} else {
+			results, err = c.SearchUsers(ctx, query)
+		}
+		if err != nil {
+			return nil, 0, "", err
+		}
+		items := make([]interface{}, 0, len(results.Hits.Hits))
+		for i := range results.Hits.Hits {
+			items = append(items, &results.Hits.Hits[i].Source)
+		}
+		return items, results.Hits.Total, results.NextCursor, nil
+	},
+	upsert: func(ctx context.Context, c *client.Client, v interface{}) (*es.Result, error) {
+		return c.UpsertUser(ctx, v.(*provision.User))
+	},
+	deactivate: func(v interface{}) { v.(*provision.User).Active = false },
+	header:     []string{"ID", "DISPLAY NAME", "ACTIVE", "SYSOP", "ACCOUNTS", "ADMIN ACCOUNTS"},
+	row: func(v interface{}) []string {
+		user := v.(*provision.User)
+		return []string{
+			user.Id,
+			user.DisplayName,
+			strconv.FormatBool(user.Active),
+			strconv.FormatBool(user.Sysop),
+			list(user.Accounts),
+			list(user.AdminAccounts),
+		}
+	},
+}
+
+var assetResource = &resource{
+	name: "asset",
+	new:  func() interface{} { return &provision.Asset{} },
+	id:   func(v interface{}) string { return v.(*provision.Asset).Id },
+	get: func(ctx context.Context, c *client.Client, id string) (interface{}, error) {
+		assetResult, err := c.GetAsset(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &assetResult.Source, nil
+	},
+	search: func(ctx context.Context, c *client.Client, query provision.SearchQuery, dsl es.Obj) ([]interface{}, int, string, error) {
+		var results *provision.AssetSearchResults
+		var err error
+		if dsl != nil {
+			results, err = c.SearchAssetsDsl(ctx, dsl)
+		} else {
+			results, err = c.SearchAssets(ctx, query)
+		}
+		if err != nil {
+			return nil, 0, "", err
+		}
+		items := make([]interface{}, 0, len(results.Hits.Hits))
+		for i := range results.Hits.Hits {
+			items = append(items, &results.Hits.Hits[i].Source)
+		}
+		return items, results.Hits.Total, results.NextCursor, nil
+	},
+	upsert: func(ctx context.Context, c *client.Client, v interface{}) (*es.Result, error) {
+		return c.UpsertAsset(ctx, v.(*provision.Asset))
+	},
+	deactivate: func(v interface{}) { v.(*provision.Asset).Active = false },
+	header:     []string{"ID", "ACCOUNT", "CLASS", "ACTIVE", "DISPLAY NAME"},
+	row: func(v interface{}) []string {
+		asset := v.(*provision.Asset)
+		return []string{
+			asset.Id,
+			asset.AccountId,
+			asset.AssetClass,
+			strconv.FormatBool(asset.Active),
+			asset.DisplayName,
+		}
+	},
+}
+
+// table of resource items
+func (r *resource) table(items []interface{}) *table {
+	tbl := &table{header: r.header}
+	for _, item := range items {
+		tbl.rows = append(tbl.rows, r.row(item))
+	}
+
+	return tbl
+}
@@ -3,7 +3,10 @@ module github.com/txn2/provision
 go 1.12
 
 require (
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/gin-gonic/gin v1.3.0
+	github.com/go-ldap/ldap/v3 v3.1.3
+	github.com/golang/protobuf v1.3.1
 	github.com/json-iterator/go v1.1.6 // indirect
 	github.com/mitchellh/mapstructure v1.1.2
 	github.com/stretchr/objx v0.2.0 // indirect
@@ -16,4 +19,6 @@ require (
 	go.uber.org/zap v1.10.0
 	golang.org/x/crypto v0.0.0-20190611184440-5c40567a22f8
 	golang.org/x/net v0.0.0-20190503192946-f4e77d36d62c // indirect
+	google.golang.org/grpc v1.18.0
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,568 @@
+package provision
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"github.com/txn2/es/v2"
+	"go.uber.org/zap"
+)
+
+const IdxGroup = "group"
+
+// Group is a set of users owned by an account. Members receive
+// the group's grants as if they were their own memberships.
+type Group struct {
+	Id          string       `json:"id" yaml:"id"`
+	Account     string       `json:"account" yaml:"account"`
+	DisplayName string       `json:"display_name" yaml:"displayName"`
+	Description string       `json:"description" yaml:"description"`
+	Members     []string     `json:"members" yaml:"members"`
+	Grants      []Membership `json:"grants" yaml:"grants"`
+}
+
+// GroupResult returned from Elastic
+type GroupResult struct {
+	es.Result
+	Source Group `json:"_source"`
+}
+
+// GroupResultAck
+type GroupResultAck struct {
+	ack.Ack
+	Payload GroupResult `json:"payload"`
+}
+
+// GroupSearchResults
+type GroupSearchResults struct {
+	es.SearchResults
+	Hits struct {
+		Total    int           `json:"total"`
+		MaxScore float64       `json:"max_score"`
+		Hits     []GroupResult `json:"hits"`
+	} `json:"hits"`
+
+	// position after the last hit, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// groupDocId namespaces a group id by its account
+func groupDocId(account string, id string) string {
+	return fmt.Sprintf("%s:%s", account, id)
+}
+
+// UpsertGroup inserts or updates a group
+func (a *Api) UpsertGroup(group *Group) (int, es.Result, *es.ErrorResponse, error) {
+	a.Logger.Info("Upsert group record", zap.String("id", group.Id), zap.String("account", group.Account))
+
+	if group.Id == "" || group.Account == "" {
+		return 400, es.Result{}, nil, errors.New("group requires an id and account")
+	}
+
+	if group.Members == nil {
+		group.Members = make([]string, 0)
+	}
+
+	code, esResult, errorResponse, err := a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxGroup, groupDocId(group.Account, group.Id)), group)
+
+	// cached users carry resolved group grants
+	a.userCache.reset()
+
+	return code, esResult, errorResponse, err
+}
+
+// UpsertGroupHandler
+func (a *Api) UpsertGroupHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	group := &Group{}
+	err := ak.UnmarshalPostAbort(group)
+	if err != nil {
+		a.Logger.Error("Upsert failure.", zap.Error(err))
+		return
+	}
+
+	a.upsertGroupSend(ak, group)
+}
+
+// upsertGroupSend
+func (a *Api) upsertGroupSend(ak ack.GinAck, group *Group) {
+	code, esResult, errorResponse, err := a.UpsertGroup(group)
+	if err != nil {
+		a.Logger.Error("Upsert failure.", zap.Error(err))
+		ak.SetPayloadType("ErrorMessage")
+		ak.SetPayload("there was a problem upserting the group")
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+			ak.SetPayloadType("EsErrorResponse")
+			ak.SetPayload(errorResponse)
+		}
+		ak.GinErrorAbort(500, "UpsertError", err.Error())
+		return
+	}
+
+	if code < 200 || code >= 300 {
+		a.Logger.Error("Es returned a non 200")
+		ak.SetPayloadType("EsError")
+		ak.SetPayload(esResult)
+		ak.GinErrorAbort(500, "EsError", "Es returned a non 200")
+		return
+	}
+
+	ak.SetPayloadType("EsResult")
+	ak.GinSend(esResult)
+}
+
+// GetGroup gets a group of an account
+func (a *Api) GetGroup(account string, id string) (int, *GroupResult, error) {
+	groupResult := &GroupResult{}
+
+	code, ret, err := a.Elastic.Get(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxGroup, groupDocId(account, id)))
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		return code, groupResult, err
+	}
+
+	err = json.Unmarshal(ret, groupResult)
+	if err != nil {
+		return code, groupResult, err
+	}
+
+	return code, groupResult, nil
+}
+
+// GetGroupHandler gets a group by ID of the account query
+// parameter.
+func (a *Api) GetGroupHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	id := c.Param("id")
+	code, groupResult, err := a.GetGroup(c.Query("account"), id)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	if code >= 400 && code < 500 {
+		ak.SetPayload("Group " + id + " not found.")
+		ak.GinErrorAbort(404, "GroupNotFound", "Group not found")
+		return
+	}
+
+	ak.SetPayloadType("GroupResult")
+	ak.GinSend(groupResult)
+}
+
+// SearchGroups
+func (a *Api) SearchGroups(searchObj *es.Obj) (int, GroupSearchResults, *es.ErrorResponse, error) {
+	gsResults := &GroupSearchResults{}
+
+	code, nextCursor, errorResponse, err := a.searchPage(IdxGroup, searchObj, gsResults)
+	if err != nil {
+		return code, *gsResults, errorResponse, err
+	}
+
+	gsResults.NextCursor = nextCursor
+
+	return code, *gsResults, nil, nil
+}
+
+// SearchGroupsHandler searches groups with a posted SearchQuery.
+// With Config.RawSearch sysops may post Elasticsearch DSL
+// with dsl=true.
+func (a *Api) SearchGroupsHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	obj, ok := a.searchObj(c, ak, GroupSearchFields)
+	if !ok {
+		return
+	}
+
+	a.searchGroupsSend(ak, obj)
+}
+
+// searchGroupsSend
+func (a *Api) searchGroupsSend(ak ack.GinAck, obj *es.Obj) {
+	code, esResult, errorResponse, err := a.SearchGroups(obj)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+			ak.SetPayloadType("EsErrorResponse")
+			ak.SetPayload(errorResponse)
+		}
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	if code >= 400 && code < 500 {
+		ak.SetPayload(esResult)
+		ak.GinErrorAbort(500, "SearchError", "There was a problem searching")
+		return
+	}
+
+	ak.SetPayloadType("GroupSearchResults")
+	ak.GinSend(esResult)
+}
+
+// ResolveUserGroups populates the user's GroupGrants from the
+// groups the user is a member of.
+func (a *Api) ResolveUserGroups(user *User) error {
+	user.GroupGrants = nil
+
+	query := es.Obj{
+		"query": es.Obj{
+			"term": es.Obj{"members": user.Id},
+		},
+	}
+
+	page := &SearchQuery{Size: SearchSizeMax}
+
+	for {
+		obj, err := page.page(query, nil, GroupSearchFields.Tiebreak)
+		if err != nil {
+			return err
+		}
+
+		code, results, errorResponse, err := a.SearchGroups(obj)
+		if err != nil {
+			return err
+		}
+
+		// no groups have been defined
+		if code == 404 {
+			return nil
+		}
+
+		if code != 200 {
+			if errorResponse != nil {
+				a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+			}
+			return fmt.Errorf("got code %d finding user groups", code)
+		}
+
+		for _, hit := range results.Hits.Hits {
+			user.GroupGrants = append(user.GroupGrants, hit.Source.Grants...)
+		}
+
+		if results.NextCursor == "" {
+			return nil
+		}
+
+		page.Cursor = results.NextCursor
+	}
+}
+
+// ResolveUserGrants populates the user's group and role grants
+func (a *Api) ResolveUserGrants(user *User) error {
+	err := a.ResolveUserGroups(user)
+	if err != nil {
+		return err
+	}
+
+	return a.ResolveUserRoles(user)
+}
+
+// UpsertAdmGroupHandler upserts a group owned by :parentAccount
+// or one of its children. Grants are limited to the same accounts
+// and new members to users of the group account.
+func (a *Api) UpsertAdmGroupHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	group := &Group{}
+	err := ak.UnmarshalPostAbort(group)
+	if err != nil {
+		a.Logger.Error("Upsert failure.", zap.Error(err))
+		return
+	}
+
+	parentAccountId := c.Param("parentAccount")
+	if group.Account == "" {
+		group.Account = parentAccountId
+	}
+
+	accounts := []string{group.Account}
+	for _, g := range group.Grants {
+		accounts = append(accounts, g.Account)
+	}
+
+	for _, acc := range accounts {
+		ok, err := a.IsAdmAccount(parentAccountId, acc)
+		if err != nil {
+			ak.SetPayloadType("AccountLookupError")
+			ak.SetPayload("Unable to lookup account.")
+			ak.GinErrorAbort(500, "AccountLookupError", err.Error())
+			return
+		}
+
+		if !ok {
+			ak.SetPayloadType("ValidationError")
+			ak.SetPayload("Group account and grants must be the requester or a child of the requester.")
+			ak.GinErrorAbort(400, "ValidationError", "Group account does not belong to parent.")
+			return
+		}
+	}
+
+	// an existing group must belong to the requester and keeps
+	// its members unless they are provided
+	existing, ok := a.admGroup(ak, parentAccountId, group.Account, group.Id, false)
+	if !ok {
+		return
+	}
+
+	if existing != nil && group.Members == nil {
+		group.Members = existing.Members
+	}
+
+	// members not already in the group must belong to its account
+	for _, member := range group.Members {
+		if existing != nil && stringInSlice(member, existing.Members) {
+			continue
+		}
+
+		if !a.admGroupMember(ak, group.Account, member) {
+			return
+		}
+	}
+
+	a.upsertGroupSend(ak, group)
+}
+
+// GetAdmGroupHandler gets a group owned by :parentAccount or one
+// of its children.
+func (a *Api) GetAdmGroupHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	group, ok := a.admGroup(ak, c.Param("parentAccount"), admGroupAccount(c), c.Param("group"), true)
+	if !ok {
+		return
+	}
+
+	ak.SetPayloadType("Group")
+	ak.GinSend(group)
+}
+
+// GetAdmGroupsHandler lists groups owned by :parentAccount and
+// its children.
+func (a *Api) GetAdmGroupsHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	accounts, err := a.AdmAccounts(c.Param("parentAccount"))
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	obj, ok := a.listObj(c, ak, es.Obj{
+		"query": es.Obj{
+			"terms": es.Obj{"account": accounts},
+		},
+	}, GroupSearchFields.Tiebreak)
+	if !ok {
+		return
+	}
+
+	a.searchGroupsSend(ak, obj)
+}
+
+// DeleteAdmGroupHandler removes a group owned by :parentAccount
+// or one of its children.
+func (a *Api) DeleteAdmGroupHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	group, ok := a.admGroup(ak, c.Param("parentAccount"), admGroupAccount(c), c.Param("group"), true)
+	if !ok {
+		return
+	}
+
+	_, err := a.esDelete(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxGroup, groupDocId(group.Account, group.Id)))
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	a.userCache.reset()
+
+	ak.SetPayloadType("DeleteResult")
+	ak.GinSend(true)
+}
+
+// AddAdmGroupMemberHandler adds :user to a group owned by
+// :parentAccount or one of its children. The user must belong to
+// the group's account.
+func (a *Api) AddAdmGroupMemberHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	group, ok := a.admGroup(ak, c.Param("parentAccount"), admGroupAccount(c), c.Param("group"), true)
+	if !ok {
+		return
+	}
+
+	userId := c.Param("user")
+	if !a.admGroupMember(ak, group.Account, userId) {
+		return
+	}
+
+	group.Members = appendUnique(group.Members, userId)
+
+	a.upsertGroupSend(ak, group)
+}
+
+// RemoveAdmGroupMemberHandler removes :user from a group owned by
+// :parentAccount or one of its children.
+func (a *Api) RemoveAdmGroupMemberHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	group, ok := a.admGroup(ak, c.Param("parentAccount"), admGroupAccount(c), c.Param("group"), true)
+	if !ok {
+		return
+	}
+
+	group.Members = removeString(group.Members, c.Param("user"))
+
+	a.upsertGroupSend(ak, group)
+}
+
+// admGroupMember returns true if the user belongs to the group
+// account, aborting otherwise.
+func (a *Api) admGroupMember(ak ack.GinAck, account string, userId string) bool {
+	code, userResult, err := a.GetUser(userId)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return false
+	}
+
+	if code != 200 {
+		ak.SetPayload("User " + userId + " not found.")
+		ak.GinErrorAbort(404, "UserNotFound", "User not found")
+		return false
+	}
+
+	if !userResult.Source.isAccountMember(account) {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload("User " + userId + " is not a member of account " + account + ".")
+		ak.GinErrorAbort(400, "ValidationError", "User does not belong to the group account.")
+		return false
+	}
+
+	return true
+}
+
+// admGroupAccount is the account query parameter of an adm
+// group route, :parentAccount if not provided.
+func admGroupAccount(c *gin.Context) string {
+	if account := c.Query("account"); account != "" {
+		return account
+	}
+
+	return c.Param("parentAccount")
+}
+
+// admGroup returns a group if it is owned by parentAccountId or
+// one of its children, aborting otherwise. A missing group aborts
+// only if required, returning nil.
+func (a *Api) admGroup(ak ack.GinAck, parentAccountId string, account string, id string, required bool) (*Group, bool) {
+	code, groupResult, err := a.GetGroup(account, id)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return nil, false
+	}
+
+	if code != 200 {
+		if !required {
+			return nil, true
+		}
+
+		ak.SetPayload("Group " + id + " not found.")
+		ak.GinErrorAbort(404, "GroupNotFound", "Group not found")
+		return nil, false
+	}
+
+	ok, err := a.IsAdmAccount(parentAccountId, groupResult.Source.Account)
+	if err != nil || !ok {
+		ak.SetPayload("Group " + id + " not found.")
+		ak.GinErrorAbort(404, "GroupNotFound", "Group not found")
+		return nil, false
+	}
+
+	return &groupResult.Source, true
+}
+
+// GetGroupMapping
+func GetGroupMapping(prefix string) es.IndexTemplate {
+	template := es.Obj{
+		"index_patterns": []string{prefix + IdxGroup},
+		"settings": es.Obj{
+			"number_of_shards": 2,
+		},
+		"mappings": es.Obj{
+			"_doc": es.Obj{
+				"_source": es.Obj{
+					"enabled": true,
+				},
+				"properties": es.Obj{
+					"id": es.Obj{
+						"type": "keyword",
+					},
+					"account": es.Obj{
+						"type": "keyword",
+					},
+					"display_name": es.Obj{
+						"type": "text",
+					},
+					"description": es.Obj{
+						"type": "text",
+					},
+					"members": es.Obj{
+						"type": "keyword",
+					},
+					"grants": es.Obj{
+						"properties": es.Obj{
+							"account": es.Obj{
+								"type": "keyword",
+							},
+							"sections": es.Obj{
+								"type": "keyword",
+							},
+							"sections_all": es.Obj{
+								"type": "boolean",
+							},
+							"deny_sections": es.Obj{
+								"type": "keyword",
+							},
+							"roles": es.Obj{
+								"type": "keyword",
+							},
+							"admin": es.Obj{
+								"type": "boolean",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return es.IndexTemplate{
+		Name:     prefix + IdxGroup,
+		Template: template,
+	}
+}
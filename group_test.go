@@ -0,0 +1,61 @@
+package provision
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpsertAdmGroupMembers(t *testing.T) {
+	stored := 0
+	a, srv := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/group/_doc/acme:g1"):
+			w.Write([]byte(`{"found":true,"_source":{"id":"g1","account":"acme","members":["u3"]}}`))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/user/_doc/u1"):
+			w.Write([]byte(`{"found":true,"_source":{"id":"u1","accounts":["other"]}}`))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/user/_doc/u2"):
+			w.Write([]byte(`{"found":true,"_source":{"id":"u2","memberships":[{"account":"acme"}]}}`))
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/group/_doc/acme:g1"):
+			stored++
+			w.Write([]byte(`{"result":"updated"}`))
+		default:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"found":false}`))
+		}
+	})
+	defer srv.Close()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/adm/:parentAccount/group", a.UpsertAdmGroupHandler)
+
+	tt := []struct {
+		name    string
+		members string
+		code    int
+		stored  int
+	}{
+		{"member of another account", `["u1"]`, 400, 0},
+		{"unknown user", `["u4"]`, 404, 0},
+		{"member of the account", `["u2"]`, 200, 1},
+		{"existing member", `["u2", "u3"]`, 200, 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stored = 0
+			body := `{"id": "g1", "members": ` + tc.members + `}`
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/adm/acme/group", strings.NewReader(body)))
+
+			if w.Code != tc.code || stored != tc.stored {
+				t.Errorf("got %d stored %d, want %d stored %d: %s", w.Code, stored, tc.code, tc.stored, w.Body.String())
+			}
+		})
+	}
+}
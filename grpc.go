@@ -0,0 +1,409 @@
+package provision
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jwt_lib "github.com/dgrijalva/jwt-go"
+	"github.com/txn2/es/v2"
+	pb "github.com/txn2/provision/provisionpb"
+	"github.com/txn2/token"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcTokenMethods require a user token, like the routes
+// preceded by UserTokenHandler
+var GrpcTokenMethods = map[string]bool{
+	"/provision.Provision/UserHasAccess":      true,
+	"/provision.Provision/UserHasAdminAccess": true,
+}
+
+// grpcUserKey is the context key of the token user
+type grpcUserKey struct{}
+
+// GrpcServer implements provisionpb.ProvisionServer with the
+// same Api methods as the JSON routes
+type GrpcServer struct {
+	Api *Api
+}
+
+// NewGrpcServer returns a grpc.Server with the Provision service
+// registered and GrpcTokenInterceptor installed. Like the JSON
+// service routes, methods other than GrpcTokenMethods are not
+// authenticated and there is no TLS unless given in opts, the
+// listener must only be reachable by internal services.
+func (a *Api) NewGrpcServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.UnaryInterceptor(a.GrpcTokenInterceptor()))
+
+	s := grpc.NewServer(opts...)
+	pb.RegisterProvisionServer(s, &GrpcServer{Api: a})
+
+	return s
+}
+
+// GrpcTokenInterceptor performs the checks of UserTokenHandler
+// for GrpcTokenMethods with the bearer token of the
+// authorization metadata
+func (a *Api) GrpcTokenInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !GrpcTokenMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		user, err := a.grpcTokenUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(context.WithValue(ctx, grpcUserKey{}, user), req)
+	}
+}
+
+// grpcTokenUser returns the user of the bearer token in the
+// authorization metadata, the error is a grpc status
+func (a *Api) grpcTokenUser(ctx context.Context) (*User, error) {
+	raw := ""
+	md, _ := metadata.FromIncomingContext(ctx)
+	authHeader := strings.Split(strings.Join(md.Get("authorization"), ""), " ")
+	if len(authHeader) > 1 && authHeader[0] == "Bearer" {
+		raw = authHeader[1]
+	}
+
+	apiToken := ""
+	var tok *token.Tok
+	if strings.HasPrefix(raw, ApiTokenPrefix) {
+		apiToken = raw
+	} else if raw != "" {
+		tok = a.parseTok(raw)
+	}
+
+	user, _, tf := a.tokenUser(apiToken, tok)
+	if tf != nil {
+		if tf.err != nil {
+			a.Logger.Error("Token check failure.", zap.String("code", tf.code), zap.Error(tf.err))
+			return nil, status.Error(codes.Internal, tf.message)
+		}
+
+		return nil, status.Error(codes.Unauthenticated, tf.message)
+	}
+
+	return user, nil
+}
+
+// GrpcUser returns the user set by GrpcTokenInterceptor
+func GrpcUser(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(grpcUserKey{}).(*User)
+	return user, ok
+}
+
+// parseTok parses a jwt as the token middleware does
+func (a *Api) parseTok(raw string) *token.Tok {
+	tok := &token.Tok{}
+
+	if a.Config.Token == nil {
+		tok.Err = fmt.Errorf("no token configuration")
+		return tok
+	}
+
+	t, err := jwt_lib.Parse(raw, func(t *jwt_lib.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt_lib.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+
+		return a.Config.Token.Cfg.EncKey, nil
+	})
+	if err != nil {
+		tok.Err = err
+		return tok
+	}
+
+	claims, ok := t.Claims.(jwt_lib.MapClaims)
+	tok.Claims = claims
+	tok.Valid = ok && t.Valid
+
+	return tok
+}
+
+// esError is the status of a failed Elasticsearch request
+func (s *GrpcServer) esError(err error, errorResponse *es.ErrorResponse) error {
+	s.Api.Logger.Error("EsError", zap.Error(err))
+	if errorResponse != nil {
+		s.Api.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+	}
+
+	return status.Error(codes.Internal, "error communicating with database")
+}
+
+// upsertResult
+func (s *GrpcServer) upsertResult(code int, esResult es.Result, errorResponse *es.ErrorResponse, err error) (*pb.UpsertResult, error) {
+	if err != nil {
+		return nil, s.esError(err, errorResponse)
+	}
+
+	if code < 200 || code >= 300 {
+		s.Api.Logger.Error("Es returned a non 200")
+		return nil, status.Errorf(codes.Internal, "database returned code %d", code)
+	}
+
+	return pbUpsertResult(esResult), nil
+}
+
+// searchObj translates a SearchRequest, Elasticsearch DSL
+// requires Config.RawSearch and a sysop token
+func (s *GrpcServer) searchObj(ctx context.Context, req *pb.SearchRequest, fields SearchFields) (*es.Obj, error) {
+	if len(req.Dsl) > 0 {
+		if !s.Api.RawSearch {
+			return nil, status.Error(codes.InvalidArgument, "Elasticsearch DSL search is disabled")
+		}
+
+		user, err := s.Api.grpcTokenUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if !user.Sysop {
+			return nil, status.Error(codes.PermissionDenied, "Elasticsearch DSL search requires a sysop")
+		}
+
+		obj := &es.Obj{}
+		err = json.Unmarshal(req.Dsl, obj)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "dsl is not a JSON object: "+err.Error())
+		}
+
+		err = dslPage(obj, req.Cursor)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		return obj, nil
+	}
+
+	obj, err := searchQueryFromPb(req).Obj(fields)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return obj, nil
+}
+
+// searchCode
+func searchCode(code int) error {
+	if code >= 400 && code < 500 {
+		return status.Errorf(codes.InvalidArgument, "there was a problem searching, database returned code %d", code)
+	}
+
+	return nil
+}
+
+// UpsertAccount
+func (s *GrpcServer) UpsertAccount(ctx context.Context, req *pb.Account) (*pb.UpsertResult, error) {
+	return s.upsertResult(s.Api.UpsertAccount(accountFromPb(req)))
+}
+
+// GetAccount
+func (s *GrpcServer) GetAccount(ctx context.Context, req *pb.GetRequest) (*pb.AccountResult, error) {
+	// GetAccount errors on a missing account
+	code, accountResult, err := s.Api.GetAccount(req.Id)
+	if code >= 400 && code < 500 {
+		return nil, status.Error(codes.NotFound, "account "+req.Id+" not found")
+	}
+
+	if err != nil {
+		return nil, s.esError(err, nil)
+	}
+
+	// Redact keys
+	for i := range accountResult.Source.AccessKeys {
+		accountResult.Source.AccessKeys[i].Key = RedactMsg
+	}
+
+	return pbAccountResult(*accountResult), nil
+}
+
+// SearchAccounts
+func (s *GrpcServer) SearchAccounts(ctx context.Context, req *pb.SearchRequest) (*pb.AccountSearchResults, error) {
+	obj, err := s.searchObj(ctx, req, AccountSearchFields)
+	if err != nil {
+		return nil, err
+	}
+
+	code, results, errorResponse, err := s.Api.SearchAccounts(obj)
+	if err != nil {
+		return nil, s.esError(err, errorResponse)
+	}
+
+	if err := searchCode(code); err != nil {
+		return nil, err
+	}
+
+	pr := &pb.AccountSearchResults{Total: int64(results.Hits.Total), MaxScore: results.Hits.MaxScore, NextCursor: results.NextCursor}
+	for _, hit := range results.Hits.Hits {
+		pr.Hits = append(pr.Hits, pbAccountResult(hit))
+	}
+
+	return pr, nil
+}
+
+// CheckKey
+func (s *GrpcServer) CheckKey(ctx context.Context, req *pb.CheckKeyRequest) (*pb.CheckKeyResult, error) {
+	ok, err := s.Api.CheckKey(req.Account, accessKeyFromPb(req.Key))
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "access key check failure: "+err.Error())
+	}
+
+	return &pb.CheckKeyResult{Valid: ok}, nil
+}
+
+// UpsertUser
+func (s *GrpcServer) UpsertUser(ctx context.Context, req *pb.User) (*pb.UpsertResult, error) {
+	return s.upsertResult(s.Api.UpsertUser(userFromPb(req)))
+}
+
+// GetUser
+func (s *GrpcServer) GetUser(ctx context.Context, req *pb.GetRequest) (*pb.UserResult, error) {
+	code, userResult, err := s.Api.GetUser(req.Id)
+	if err != nil {
+		return nil, s.esError(err, nil)
+	}
+
+	if code >= 400 && code < 500 {
+		return nil, status.Error(codes.NotFound, "user "+req.Id+" not found")
+	}
+
+	userResult.Source.Password = RedactMsg
+	userResult.Source.RedactApiTokens()
+
+	return pbUserResult(*userResult), nil
+}
+
+// SearchUsers
+func (s *GrpcServer) SearchUsers(ctx context.Context, req *pb.SearchRequest) (*pb.UserSearchResults, error) {
+	obj, err := s.searchObj(ctx, req, UserSearchFields)
+	if err != nil {
+		return nil, err
+	}
+
+	code, results, errorResponse, err := s.Api.SearchUsers(obj)
+	if err != nil {
+		return nil, s.esError(err, errorResponse)
+	}
+
+	if err := searchCode(code); err != nil {
+		return nil, err
+	}
+
+	pr := &pb.UserSearchResults{Total: int64(results.Hits.Total), MaxScore: results.Hits.MaxScore, NextCursor: results.NextCursor}
+	for _, hit := range results.Hits.Hits {
+		pr.Hits = append(pr.Hits, pbUserResult(hit))
+	}
+
+	return pr, nil
+}
+
+// UpsertAsset
+func (s *GrpcServer) UpsertAsset(ctx context.Context, req *pb.Asset) (*pb.UpsertResult, error) {
+	return s.upsertResult(s.Api.UpsertAsset(assetFromPb(req)))
+}
+
+// GetAsset
+func (s *GrpcServer) GetAsset(ctx context.Context, req *pb.GetRequest) (*pb.AssetResult, error) {
+	code, assetResult, err := s.Api.GetAsset(req.Id)
+	if err != nil {
+		return nil, s.esError(err, nil)
+	}
+
+	if code >= 400 && code < 500 {
+		return nil, status.Error(codes.NotFound, "asset "+req.Id+" not found")
+	}
+
+	return pbAssetResult(*assetResult), nil
+}
+
+// SearchAssets
+func (s *GrpcServer) SearchAssets(ctx context.Context, req *pb.SearchRequest) (*pb.AssetSearchResults, error) {
+	obj, err := s.searchObj(ctx, req, AssetSearchFields)
+	if err != nil {
+		return nil, err
+	}
+
+	code, results, errorResponse, err := s.Api.SearchAssets(obj)
+	if err != nil {
+		return nil, s.esError(err, errorResponse)
+	}
+
+	if err := searchCode(code); err != nil {
+		return nil, err
+	}
+
+	pr := &pb.AssetSearchResults{Total: int64(results.Hits.Total), MaxScore: results.Hits.MaxScore, NextCursor: results.NextCursor}
+	for _, hit := range results.Hits.Hits {
+		pr.Hits = append(pr.Hits, pbAssetResult(hit))
+	}
+
+	return pr, nil
+}
+
+// UserHasAccess
+func (s *GrpcServer) UserHasAccess(ctx context.Context, req *pb.AccessCheckRequest) (*pb.AccessCheckResult, error) {
+	return s.userHasAccess(ctx, req, PolicyCheckAccess)
+}
+
+// UserHasAdminAccess
+func (s *GrpcServer) UserHasAdminAccess(ctx context.Context, req *pb.AccessCheckRequest) (*pb.AccessCheckResult, error) {
+	return s.userHasAccess(ctx, req, PolicyCheckAdmin)
+}
+
+// userHasAccess evaluates the Api policy for the token user
+// like Api.UserHasAccessHandler. A denied check is a result with
+// status false.
+func (s *GrpcServer) userHasAccess(ctx context.Context, req *pb.AccessCheckRequest, check string) (*pb.AccessCheckResult, error) {
+	user, ok := GrpcUser(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "no user object in token")
+	}
+
+	ac := accessCheckFromPb(req.Check)
+	acr := AccessCheckResult{AccessChecked: ac}
+
+	// you can not be an admin of nothing
+	if check == PolicyCheckAdmin && len(ac.Accounts) < 1 {
+		return nil, status.Error(codes.InvalidArgument, "admin check requires at least one account")
+	}
+
+	in, err := s.Api.PolicyInput(user, ac)
+	if err != nil {
+		s.Api.Logger.Error("Account lookup failure.", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to lookup accounts")
+	}
+
+	var decision PolicyDecision
+	if req.Explain {
+		decision, acr.Explanation, err = s.Api.PolicyEvaluator().Explain(check, in)
+	} else {
+		decision, err = s.Api.PolicyEvaluator().Evaluate(check, in)
+	}
+
+	if err != nil {
+		s.Api.Logger.Error("Policy failure.", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to evaluate access policy")
+	}
+
+	switch {
+	case decision.Allowed:
+		acr.Status = true
+		acr.Message = "Has access."
+	case check == PolicyCheckAdmin:
+		acr.Message = "User does not have admin access."
+	default:
+		acr.Message = "User does not have basic access."
+	}
+
+	return pbAccessCheckResult(acr), nil
+}
@@ -0,0 +1,441 @@
+package provision
+
+import (
+	"github.com/txn2/es/v2"
+	pb "github.com/txn2/provision/provisionpb"
+)
+
+// conversions between the api models and the protobuf messages
+// of the gRPC service
+
+// pbUpsertResult
+func pbUpsertResult(r es.Result) *pb.UpsertResult {
+	return &pb.UpsertResult{
+		Index:   r.Index,
+		Id:      r.Id,
+		Version: int64(r.Version),
+		Result:  r.ResultType,
+	}
+}
+
+// searchQueryFromPb
+func searchQueryFromPb(req *pb.SearchRequest) *SearchQuery {
+	sq := &SearchQuery{
+		Text:   req.Text,
+		Ids:    req.Ids,
+		From:   int(req.From),
+		Size:   int(req.Size),
+		Cursor: req.Cursor,
+	}
+
+	if len(req.Filters) > 0 {
+		sq.Filters = make(map[string][]string, len(req.Filters))
+		for field, fv := range req.Filters {
+			sq.Filters[field] = fv.GetValues()
+		}
+	}
+
+	for _, ps := range req.Sort {
+		sq.Sort = append(sq.Sort, SearchSort{Field: ps.Field, Desc: ps.Desc})
+	}
+
+	return sq
+}
+
+// pbAccessKeys
+func pbAccessKeys(keys []AccessKey) []*pb.AccessKey {
+	pks := make([]*pb.AccessKey, 0, len(keys))
+	for _, k := range keys {
+		pks = append(pks, pbAccessKey(k))
+	}
+
+	return pks
+}
+
+// pbAccessKey
+func pbAccessKey(k AccessKey) *pb.AccessKey {
+	return &pb.AccessKey{
+		Name:        k.Name,
+		Description: k.Description,
+		Key:         k.Key,
+		Active:      k.Active,
+	}
+}
+
+// accessKeyFromPb
+func accessKeyFromPb(pk *pb.AccessKey) AccessKey {
+	if pk == nil {
+		return AccessKey{}
+	}
+
+	return AccessKey{
+		Name:        pk.Name,
+		Description: pk.Description,
+		Key:         pk.Key,
+		Active:      pk.Active,
+	}
+}
+
+// pbAuthCfg
+func pbAuthCfg(cfg *AuthCfg) *pb.AuthCfg {
+	if cfg == nil {
+		return nil
+	}
+
+	pcfg := &pb.AuthCfg{Type: cfg.Type}
+	if cfg.Ldap != nil {
+		ldap := cfg.Ldap
+		pcfg.Ldap = &pb.LdapCfg{
+			Url:                ldap.Url,
+			StartTls:           ldap.StartTls,
+			InsecureSkipVerify: ldap.InsecureSkipVerify,
+			Timeout:            int32(ldap.Timeout),
+			BindDn:             ldap.BindDn,
+			GroupBaseDn:        ldap.GroupBaseDn,
+			GroupFilter:        ldap.GroupFilter,
+			GroupAttr:          ldap.GroupAttr,
+		}
+
+		for _, gm := range ldap.GroupMappings {
+			pcfg.Ldap.GroupMappings = append(pcfg.Ldap.GroupMappings, &pb.LdapGroupMapping{
+				Group:       gm.Group,
+				Sections:    gm.Sections,
+				SectionsAll: gm.SectionsAll,
+				Admin:       gm.Admin,
+			})
+		}
+	}
+
+	return pcfg
+}
+
+// authCfgFromPb
+func authCfgFromPb(pcfg *pb.AuthCfg) *AuthCfg {
+	if pcfg == nil {
+		return nil
+	}
+
+	cfg := &AuthCfg{Type: pcfg.Type}
+	if pcfg.Ldap != nil {
+		ldap := pcfg.Ldap
+		cfg.Ldap = &LdapCfg{
+			Url:                ldap.Url,
+			StartTls:           ldap.StartTls,
+			InsecureSkipVerify: ldap.InsecureSkipVerify,
+			Timeout:            int(ldap.Timeout),
+			BindDn:             ldap.BindDn,
+			GroupBaseDn:        ldap.GroupBaseDn,
+			GroupFilter:        ldap.GroupFilter,
+			GroupAttr:          ldap.GroupAttr,
+		}
+
+		for _, gm := range ldap.GroupMappings {
+			cfg.Ldap.GroupMappings = append(cfg.Ldap.GroupMappings, LdapGroupMapping{
+				Group:       gm.Group,
+				Sections:    gm.Sections,
+				SectionsAll: gm.SectionsAll,
+				Admin:       gm.Admin,
+			})
+		}
+	}
+
+	return cfg
+}
+
+// pbAccount
+func pbAccount(acc Account) *pb.Account {
+	return &pb.Account{
+		Id:          acc.Id,
+		Parent:      acc.Parent,
+		Description: acc.Description,
+		DisplayName: acc.DisplayName,
+		Active:      acc.Active,
+		Modules:     acc.Modules,
+		OrgId:       int32(acc.OrgId),
+		AccessKeys:  pbAccessKeys(acc.AccessKeys),
+		Auth:        pbAuthCfg(acc.Auth),
+	}
+}
+
+// accountFromPb
+func accountFromPb(pacc *pb.Account) *Account {
+	acc := &Account{
+		Id:          pacc.Id,
+		Parent:      pacc.Parent,
+		Description: pacc.Description,
+		DisplayName: pacc.DisplayName,
+		Active:      pacc.Active,
+		Modules:     pacc.Modules,
+		OrgId:       int(pacc.OrgId),
+		AccessKeys:  make([]AccessKey, 0, len(pacc.AccessKeys)),
+		Auth:        authCfgFromPb(pacc.Auth),
+	}
+
+	for _, pk := range pacc.AccessKeys {
+		acc.AccessKeys = append(acc.AccessKeys, accessKeyFromPb(pk))
+	}
+
+	return acc
+}
+
+// pbAccountResult
+func pbAccountResult(r AccountResult) *pb.AccountResult {
+	return &pb.AccountResult{
+		Id:      r.Id,
+		Version: int64(r.Version),
+		Found:   r.Found,
+		Source:  pbAccount(r.Source),
+	}
+}
+
+// pbMemberships
+func pbMemberships(memberships []Membership) []*pb.Membership {
+	pms := make([]*pb.Membership, 0, len(memberships))
+	for _, m := range memberships {
+		pms = append(pms, &pb.Membership{
+			Account:      m.Account,
+			Sections:     m.Sections,
+			SectionsAll:  m.SectionsAll,
+			DenySections: m.DenySections,
+			Roles:        m.Roles,
+			Admin:        m.Admin,
+		})
+	}
+
+	return pms
+}
+
+// membershipsFromPb
+func membershipsFromPb(pms []*pb.Membership) []Membership {
+	ms := make([]Membership, 0, len(pms))
+	for _, pm := range pms {
+		ms = append(ms, Membership{
+			Account:      pm.Account,
+			Sections:     pm.Sections,
+			SectionsAll:  pm.SectionsAll,
+			DenySections: pm.DenySections,
+			Roles:        pm.Roles,
+			Admin:        pm.Admin,
+		})
+	}
+
+	return ms
+}
+
+// pbUser
+func pbUser(u User) *pb.User {
+	pu := &pb.User{
+		Id:            u.Id,
+		Description:   u.Description,
+		DisplayName:   u.DisplayName,
+		Name:          u.Name,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		Picture:       u.Picture,
+		Active:        u.Active,
+		Sysop:         u.Sysop,
+		Password:      u.Password,
+		Sections:      u.Sections,
+		SectionsAll:   u.SectionsAll,
+		DenySections:  u.DenySections,
+		Accounts:      u.Accounts,
+		AdminAccounts: u.AdminAccounts,
+		Epoch:         u.Epoch,
+		Identities:    u.Identities,
+		Memberships:   pbMemberships(u.Memberships),
+		GroupGrants:   pbMemberships(u.GroupGrants),
+	}
+
+	for _, at := range u.ApiTokens {
+		pu.ApiTokens = append(pu.ApiTokens, &pb.ApiToken{
+			Name:     at.Name,
+			Hash:     at.Hash,
+			Created:  at.Created,
+			Expires:  at.Expires,
+			Sections: at.Sections,
+			Accounts: at.Accounts,
+		})
+	}
+
+	for _, ar := range u.Roles {
+		pu.Roles = append(pu.Roles, &pb.AccountRoles{Account: ar.Account, Roles: ar.Roles})
+	}
+
+	for _, rg := range u.RoleGrants {
+		pu.RoleGrants = append(pu.RoleGrants, &pb.RoleGrant{
+			Account:     rg.Account,
+			Sections:    rg.Sections,
+			SectionsAll: rg.SectionsAll,
+		})
+	}
+
+	return pu
+}
+
+// userFromPb, api tokens and resolved grants are not accepted
+// from clients
+func userFromPb(pu *pb.User) *User {
+	u := &User{
+		Id:            pu.Id,
+		Description:   pu.Description,
+		DisplayName:   pu.DisplayName,
+		Name:          pu.Name,
+		Email:         pu.Email,
+		EmailVerified: pu.EmailVerified,
+		Picture:       pu.Picture,
+		Active:        pu.Active,
+		Sysop:         pu.Sysop,
+		Password:      pu.Password,
+		Sections:      pu.Sections,
+		SectionsAll:   pu.SectionsAll,
+		DenySections:  pu.DenySections,
+		Accounts:      pu.Accounts,
+		AdminAccounts: pu.AdminAccounts,
+		Epoch:         pu.Epoch,
+		Identities:    pu.Identities,
+		Memberships:   membershipsFromPb(pu.Memberships),
+	}
+
+	for _, ar := range pu.Roles {
+		u.Roles = append(u.Roles, AccountRoles{Account: ar.Account, Roles: ar.Roles})
+	}
+
+	return u
+}
+
+// pbUserResult
+func pbUserResult(r UserResult) *pb.UserResult {
+	return &pb.UserResult{
+		Id:      r.Id,
+		Version: int64(r.Version),
+		Found:   r.Found,
+		Source:  pbUser(r.Source),
+	}
+}
+
+// pbAsset
+func pbAsset(as Asset) *pb.Asset {
+	pas := &pb.Asset{
+		Id:          as.Id,
+		AccountId:   as.AccountId,
+		Description: as.Description,
+		DisplayName: as.DisplayName,
+		AssetClass:  as.AssetClass,
+		AssetCfg:    as.AssetCfg,
+		Active:      as.Active,
+	}
+
+	for _, r := range as.Routes {
+		pr := &pb.Route{AccountId: r.AccountId, ModelId: r.ModelId, Type: r.Type}
+		for _, cond := range r.Conditions {
+			pr.Conditions = append(pr.Conditions, &pb.Condition{Parser: cond.Parser, Condition: cond.Condition})
+		}
+		pas.Routes = append(pas.Routes, pr)
+	}
+
+	return pas
+}
+
+// assetFromPb
+func assetFromPb(pas *pb.Asset) *Asset {
+	as := &Asset{
+		Id:          pas.Id,
+		AccountId:   pas.AccountId,
+		Description: pas.Description,
+		DisplayName: pas.DisplayName,
+		AssetClass:  pas.AssetClass,
+		AssetCfg:    pas.AssetCfg,
+		Active:      pas.Active,
+		Routes:      make([]Route, 0, len(pas.Routes)),
+	}
+
+	for _, pr := range pas.Routes {
+		r := Route{AccountId: pr.AccountId, ModelId: pr.ModelId, Type: pr.Type}
+		for _, cond := range pr.Conditions {
+			r.Conditions = append(r.Conditions, ConditionCfg{Parser: cond.Parser, Condition: cond.Condition})
+		}
+		as.Routes = append(as.Routes, r)
+	}
+
+	return as
+}
+
+// pbAssetResult
+func pbAssetResult(r AssetResult) *pb.AssetResult {
+	return &pb.AssetResult{
+		Id:      r.Id,
+		Version: int64(r.Version),
+		Found:   r.Found,
+		Source:  pbAsset(r.Source),
+	}
+}
+
+// pbAccessCheck
+func pbAccessCheck(ac *AccessCheck) *pb.AccessCheck {
+	if ac == nil {
+		return nil
+	}
+
+	return &pb.AccessCheck{
+		Sections: ac.Sections,
+		Accounts: ac.Accounts,
+		Mode:     ac.Mode,
+	}
+}
+
+// accessCheckFromPb
+func accessCheckFromPb(pac *pb.AccessCheck) *AccessCheck {
+	if pac == nil {
+		return &AccessCheck{}
+	}
+
+	return &AccessCheck{
+		Sections: pac.Sections,
+		Accounts: pac.Accounts,
+		Mode:     pac.Mode,
+	}
+}
+
+// pbAccessCheckResult
+func pbAccessCheckResult(acr AccessCheckResult) *pb.AccessCheckResult {
+	pacr := &pb.AccessCheckResult{
+		AccessChecked: pbAccessCheck(acr.AccessChecked),
+		Status:        acr.Status,
+		Message:       acr.Message,
+	}
+
+	if acr.Explanation != nil {
+		ex := acr.Explanation
+		pacr.Explanation = &pb.PolicyExplanation{
+			Policy: ex.Policy,
+			Check:  ex.Check,
+			Decision: &pb.PolicyDecision{
+				Allowed: ex.Decision.Allowed,
+				Rule:    ex.Decision.Rule,
+			},
+		}
+
+		for _, rt := range ex.Rules {
+			prt := &pb.PolicyRuleTrace{
+				Rule:    rt.Rule,
+				Effect:  rt.Effect,
+				When:    rt.When,
+				Matched: rt.Matched,
+				Error:   rt.Error,
+			}
+
+			for _, d := range rt.Details {
+				prt.Details = append(prt.Details, &pb.PolicyTraceDetail{
+					Quantifier: d.Quantifier,
+					Var:        d.Var,
+					Value:      d.Value,
+					Result:     d.Result,
+				})
+			}
+
+			pacr.Explanation.Rules = append(pacr.Explanation.Rules, prt)
+		}
+	}
+
+	return pacr
+}
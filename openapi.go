@@ -0,0 +1,577 @@
+package provision
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"github.com/txn2/es/v2"
+)
+
+// OpenApiVersion of the generated document
+const OpenApiVersion = "3.0.3"
+
+const OpenApiAuthToken = "token"
+const OpenApiAuthScim = "scim"
+const OpenApiAuthBasic = "basic"
+
+// OpenApiOperation documents a route. Request and Response are
+// values of the request body and ack payload types, nil for
+// none.
+type OpenApiOperation struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+
+	// OpenApiAuthToken, OpenApiAuthScim, OpenApiAuthBasic or
+	// empty for none
+	Auth string
+
+	// query parameters and their descriptions
+	Query map[string]string
+
+	Request  interface{}
+	Response interface{}
+
+	// the response is not an ack, e.g. SCIM
+	Raw bool
+
+	// success status, defaults to 200
+	Status int
+}
+
+// openApiPageQuery documents the parameters of list routes
+var openApiPageQuery = map[string]string{
+	"size":   "page size, at most 100",
+	"cursor": "next_cursor of the previous page",
+}
+
+// openApiSearchQuery documents the parameters of searches
+// taking a SearchQuery
+var openApiSearchQuery = map[string]string{
+	"dsl":    "true posts Elasticsearch DSL, sysops only with raw search enabled",
+	"cursor": "with dsl=true, next_cursor of the previous page",
+}
+
+// openApiGroupAccount documents the account of adm group routes
+var openApiGroupAccount = map[string]string{
+	"account": "account of the group, :parentAccount if empty",
+}
+
+// OpenApiOperations documents every route served by
+// cmd/provision.go. NewOpenApiSpec fails for a route
+// without an entry.
+var OpenApiOperations = []OpenApiOperation{
+	{Method: "GET", Path: "/prefix", Tag: "util", Summary: "Get the prefix used for Elasticsearch indexes.",
+		Response: ""},
+	{Method: "GET", Path: "/openapi.json", Tag: "util", Summary: "Get this OpenAPI document.",
+		Raw: true, Response: map[string]interface{}{}},
+	{Method: "GET", Path: "/healthz", Tag: "util", Summary: "Liveness check.",
+		Auth: OpenApiAuthBasic, Raw: true, Response: map[string]interface{}{}},
+
+	{Method: "POST", Path: "/account", Tag: "account", Summary: "Upsert an Account.",
+		Request: Account{}, Response: es.Result{}},
+	{Method: "GET", Path: "/account/:id", Tag: "account", Summary: "Get an Account by id.",
+		Response: AccountResult{}},
+	{Method: "POST", Path: "/keyCheck/:id", Tag: "account", Summary: "Check an AccessKey of an Account.",
+		Request: AccessKey{}, Response: true},
+	{Method: "POST", Path: "/searchAccounts", Tag: "account", Summary: "Search Accounts with a SearchQuery.",
+		Query:   openApiSearchQuery,
+		Request: SearchQuery{}, Response: AccountSearchResults{}},
+
+	{Method: "POST", Path: "/user", Tag: "user", Summary: "Upsert a User.",
+		Request: User{}, Response: es.Result{}},
+	{Method: "GET", Path: "/user/:id", Tag: "user", Summary: "Get a User by id.",
+		Response: UserResult{}},
+	{Method: "POST", Path: "/searchUsers", Tag: "user", Summary: "Search Users with a SearchQuery.",
+		Query:   openApiSearchQuery,
+		Request: SearchQuery{}, Response: UserSearchResults{}},
+	{Method: "POST", Path: "/authUser", Tag: "user", Summary: "Authenticate a User and receive a token.",
+		Query:   map[string]string{"raw": "true responds with the token as text"},
+		Request: Auth{}, Response: UserTokenResult{}},
+	{Method: "POST", Path: "/invite/accept", Tag: "user", Summary: "Accept an invite, creating or linking a User.",
+		Request: InviteAccept{}, Response: User{}},
+	{Method: "GET", Path: "/oidc/login", Tag: "user", Summary: "Start an OpenID Connect login, redirects to the provider.",
+		Status: http.StatusFound},
+	{Method: "GET", Path: "/oidc/callback", Tag: "user", Summary: "Complete an OpenID Connect login and receive a token.",
+		Query: map[string]string{
+			"code":              "authorization code",
+			"state":             "state of the login",
+			"error":             "provider error",
+			"error_description": "provider error description",
+		},
+		Response: UserTokenResult{}},
+	{Method: "POST", Path: "/token/refresh", Tag: "user", Summary: "Exchange a refresh token for a new token and refresh token.",
+		Request: TokenRefresh{}, Response: UserTokenResult{}},
+	{Method: "POST", Path: "/token/revoke", Tag: "user", Summary: "Revoke the token and optionally a refresh token.",
+		Auth: OpenApiAuthToken, Request: TokenRefresh{}, Response: true},
+	{Method: "GET", Path: "/apiTokens", Tag: "user", Summary: "List the api tokens of the token user.",
+		Auth: OpenApiAuthToken, Response: []ApiToken{}},
+	{Method: "POST", Path: "/apiTokens", Tag: "user", Summary: "Create an api token for the token user.",
+		Auth: OpenApiAuthToken, Request: ApiToken{}, Response: ApiTokenResult{}},
+	{Method: "DELETE", Path: "/apiTokens/:name", Tag: "user", Summary: "Revoke an api token of the token user.",
+		Auth: OpenApiAuthToken, Response: true},
+	{Method: "GET", Path: "/whoami", Tag: "user", Summary: "Get the effective permissions of the token user.",
+		Auth: OpenApiAuthToken, Response: EffectivePermissions{}},
+
+	{Method: "POST", Path: "/userHasAccess", Tag: "access", Summary: "Check the access of the token user.",
+		Auth: OpenApiAuthToken, Query: map[string]string{"explain": "true includes the policy trace"},
+		Request: AccessCheck{}, Response: AccessCheckResult{}},
+	{Method: "POST", Path: "/userHasAdminAccess", Tag: "access", Summary: "Check the token user is an admin of the accounts.",
+		Auth: OpenApiAuthToken, Query: map[string]string{"explain": "true includes the policy trace"},
+		Request: AccessCheck{}, Response: AccessCheckResult{}},
+	{Method: "POST", Path: "/userHasAccessBatch", Tag: "access", Summary: "Check a list of access and admin checks of the token user.",
+		Auth: OpenApiAuthToken, Query: map[string]string{"explain": "true includes the policy trace"},
+		Request: BatchAccessCheck{}, Response: BatchAccessCheckResult{}},
+
+	{Method: "POST", Path: "/role", Tag: "role", Summary: "Upsert a Role, global or for an account.",
+		Request: Role{}, Response: es.Result{}},
+	{Method: "GET", Path: "/role/:id", Tag: "role", Summary: "Get a Role by id.",
+		Query:    map[string]string{"account": "account of the role, global if empty"},
+		Response: RoleResult{}},
+	{Method: "POST", Path: "/searchRoles", Tag: "role", Summary: "Search Roles with a SearchQuery.",
+		Query:   openApiSearchQuery,
+		Request: SearchQuery{}, Response: RoleSearchResults{}},
+
+	{Method: "POST", Path: "/group", Tag: "group", Summary: "Upsert a Group.",
+		Request: Group{}, Response: es.Result{}},
+	{Method: "GET", Path: "/group/:id", Tag: "group", Summary: "Get a Group by id.",
+		Query:    map[string]string{"account": "account of the group"},
+		Response: GroupResult{}},
+	{Method: "POST", Path: "/searchGroups", Tag: "group", Summary: "Search Groups with a SearchQuery.",
+		Query:   openApiSearchQuery,
+		Request: SearchQuery{}, Response: GroupSearchResults{}},
+
+	{Method: "POST", Path: "/asset", Tag: "asset", Summary: "Upsert an Asset.",
+		Request: Asset{}, Response: es.Result{}},
+	{Method: "GET", Path: "/asset/:id", Tag: "asset", Summary: "Get an Asset by id.",
+		Response: AssetResult{}},
+	{Method: "POST", Path: "/searchAssets", Tag: "asset", Summary: "Search Assets with a SearchQuery.",
+		Query:   openApiSearchQuery,
+		Request: SearchQuery{}, Response: AssetSearchResults{}},
+
+	{Method: "GET", Path: "/scim/v2/Users", Tag: "scim", Summary: "List the Users of the SCIM token account.",
+		Auth: OpenApiAuthScim, Raw: true, Response: ScimListResponse{},
+		Query: map[string]string{"filter": "SCIM filter", "startIndex": "1-based index", "count": "page size"}},
+	{Method: "POST", Path: "/scim/v2/Users", Tag: "scim", Summary: "Create a User in the SCIM token account.",
+		Auth: OpenApiAuthScim, Raw: true, Request: ScimUser{}, Response: ScimUser{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/scim/v2/Users/:id", Tag: "scim", Summary: "Get a User of the SCIM token account.",
+		Auth: OpenApiAuthScim, Raw: true, Response: ScimUser{}},
+	{Method: "PUT", Path: "/scim/v2/Users/:id", Tag: "scim", Summary: "Replace a User of the SCIM token account.",
+		Auth: OpenApiAuthScim, Raw: true, Request: ScimUser{}, Response: ScimUser{}},
+	{Method: "PATCH", Path: "/scim/v2/Users/:id", Tag: "scim", Summary: "Patch a User of the SCIM token account.",
+		Auth: OpenApiAuthScim, Raw: true, Request: ScimPatchOp{}, Response: ScimUser{}},
+	{Method: "DELETE", Path: "/scim/v2/Users/:id", Tag: "scim", Summary: "Remove a User from the SCIM token account.",
+		Auth: OpenApiAuthScim, Raw: true, Status: http.StatusNoContent},
+	{Method: "GET", Path: "/scim/v2/Groups", Tag: "scim", Summary: "List the Groups of the SCIM token account.",
+		Auth: OpenApiAuthScim, Raw: true, Response: ScimListResponse{},
+		Query: map[string]string{"filter": "SCIM filter"}},
+	{Method: "GET", Path: "/scim/v2/Groups/:id", Tag: "scim", Summary: "Get a Group of the SCIM token account.",
+		Auth: OpenApiAuthScim, Raw: true, Response: ScimGroup{}},
+	{Method: "PATCH", Path: "/scim/v2/Groups/:id", Tag: "scim", Summary: "Add or remove Group members.",
+		Auth: OpenApiAuthScim, Raw: true, Request: ScimPatchOp{}, Status: http.StatusNoContent},
+
+	{Method: "GET", Path: "/adm/:parentAccount/account/:account", Tag: "adm", Summary: "Get the parent or a child account.",
+		Response: AccountResult{}},
+	{Method: "POST", Path: "/adm/:parentAccount/account", Tag: "adm", Summary: "Upsert a child account.",
+		Request: Account{}, Response: es.Result{}},
+	{Method: "GET", Path: "/adm/:parentAccount/children", Tag: "adm", Summary: "Get the children of the parent account.",
+		Query: openApiPageQuery, Response: AccountSummaryResults{}},
+	{Method: "GET", Path: "/adm/:parentAccount/assets/:account", Tag: "adm", Summary: "Get assets with associations to an account.",
+		Query: openApiPageQuery, Response: AssetSummaryResults{}},
+	{Method: "GET", Path: "/adm/:parentAccount/assetAssoc/:asset/:accountFrom/:accountTo", Tag: "adm",
+		Summary: "Re-associate the routes of an asset from one account to another.", Response: es.Result{}},
+	{Method: "POST", Path: "/adm/:parentAccount/searchAssets", Tag: "adm", Summary: "Search Assets of the parent and descendant accounts.",
+		Request: SearchQuery{}, Response: AssetSearchResults{}},
+	{Method: "POST", Path: "/adm/:parentAccount/user", Tag: "adm", Summary: "Upsert a user of the parent or child accounts.",
+		Request: User{}, Response: es.Result{}},
+	{Method: "POST", Path: "/adm/:parentAccount/searchUsers", Tag: "adm", Summary: "Search Users of the parent and descendant accounts.",
+		Request: SearchQuery{}, Response: UserSearchResults{}},
+	{Method: "POST", Path: "/adm/:parentAccount/role", Tag: "adm", Summary: "Upsert a Role for the parent or a child account.",
+		Request: Role{}, Response: es.Result{}},
+	{Method: "POST", Path: "/adm/:parentAccount/group", Tag: "adm", Summary: "Upsert a Group owned by the parent or a child account.",
+		Request: Group{}, Response: es.Result{}},
+	{Method: "GET", Path: "/adm/:parentAccount/groups", Tag: "adm", Summary: "List Groups owned by the parent and child accounts.",
+		Query: openApiPageQuery, Response: GroupSearchResults{}},
+	{Method: "GET", Path: "/adm/:parentAccount/group/:group", Tag: "adm", Summary: "Get a Group.",
+		Query: openApiGroupAccount, Response: Group{}},
+	{Method: "DELETE", Path: "/adm/:parentAccount/group/:group", Tag: "adm", Summary: "Remove a Group.",
+		Query: openApiGroupAccount, Response: true},
+	{Method: "PUT", Path: "/adm/:parentAccount/group/:group/member/:user", Tag: "adm", Summary: "Add a User of the group account to a Group.",
+		Query: openApiGroupAccount, Response: es.Result{}},
+	{Method: "DELETE", Path: "/adm/:parentAccount/group/:group/member/:user", Tag: "adm", Summary: "Remove a User from a Group.",
+		Query: openApiGroupAccount, Response: es.Result{}},
+	{Method: "POST", Path: "/adm/:parentAccount/invite", Tag: "adm", Summary: "Invite an email to the parent or a child account.",
+		Request: Invite{}, Response: InviteTokenResult{}},
+	{Method: "GET", Path: "/adm/:parentAccount/invites", Tag: "adm", Summary: "List pending invites for the parent and child accounts.",
+		Query: openApiPageQuery, Response: InviteSearchResults{}},
+	{Method: "DELETE", Path: "/adm/:parentAccount/invite/:invite", Tag: "adm", Summary: "Revoke a pending invite.",
+		Response: true},
+}
+
+// OpenApiDoc is an OpenAPI document
+type OpenApiDoc map[string]interface{}
+
+// NewOpenApiSpec documents the routes with OpenApiOperations,
+// returning an error naming every route without an entry.
+func NewOpenApiSpec(routes gin.RoutesInfo, version string) (OpenApiDoc, error) {
+	ops := make(map[string]OpenApiOperation, len(OpenApiOperations))
+	for _, op := range OpenApiOperations {
+		ops[op.Method+" "+op.Path] = op
+	}
+
+	b := &openApiBuilder{schemas: make(map[string]interface{})}
+	paths := make(map[string]interface{})
+	missing := make([]string, 0)
+
+	for _, route := range routes {
+		op, ok := ops[route.Method+" "+route.Path]
+		if !ok {
+			missing = append(missing, route.Method+" "+route.Path)
+			continue
+		}
+
+		pth, params := openApiPath(route.Path)
+
+		item, ok := paths[pth].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[pth] = item
+		}
+
+		item[strings.ToLower(op.Method)] = b.operation(op, params)
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("routes without an OpenApiOperations entry: %s", strings.Join(missing, ", "))
+	}
+
+	b.schemas["ErrorAck"] = b.ack(nil)
+
+	return OpenApiDoc{
+		"openapi": OpenApiVersion,
+		"info": map[string]interface{}{
+			"title":       "provision",
+			"description": "User, account and asset provisioning.",
+			"version":     version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]interface{}{
+				OpenApiAuthToken: map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "User token from /authUser or a personal api token.",
+				},
+				OpenApiAuthScim: map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "<account>:<scim access key>",
+				},
+				OpenApiAuthBasic: map[string]interface{}{
+					"type":   "http",
+					"scheme": "basic",
+				},
+			},
+		},
+	}, nil
+}
+
+// OpenApiHandler serves the OpenAPI document of the routes of
+// the engine, built on the first request.
+func OpenApiHandler(engine *gin.Engine, version string) gin.HandlerFunc {
+	var once sync.Once
+	var spec OpenApiDoc
+	var specErr error
+
+	return func(c *gin.Context) {
+		once.Do(func() {
+			spec, specErr = NewOpenApiSpec(engine.Routes(), version)
+		})
+
+		if specErr != nil {
+			ak := ack.Gin(c)
+			ak.GinErrorAbort(500, "OpenApiError", specErr.Error())
+			return
+		}
+
+		c.JSON(200, spec)
+	}
+}
+
+var ginParam = regexp.MustCompile(`[:*]([^/]+)`)
+
+// openApiPath converts a gin path to an OpenAPI path returning
+// the path parameters
+func openApiPath(ginPath string) (string, []string) {
+	params := make([]string, 0)
+	for _, m := range ginParam.FindAllStringSubmatch(ginPath, -1) {
+		params = append(params, m[1])
+	}
+
+	return ginParam.ReplaceAllString(ginPath, "{$1}"), params
+}
+
+// openApiBuilder collects the component schemas of the types
+// referenced by operations
+type openApiBuilder struct {
+	schemas map[string]interface{}
+}
+
+// operation
+func (b *openApiBuilder) operation(op OpenApiOperation, pathParams []string) map[string]interface{} {
+	params := make([]interface{}, 0)
+	for _, p := range pathParams {
+		params = append(params, map[string]interface{}{
+			"name":     p,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+
+	query := make([]string, 0, len(op.Query))
+	for q := range op.Query {
+		query = append(query, q)
+	}
+	sort.Strings(query)
+
+	for _, q := range query {
+		params = append(params, map[string]interface{}{
+			"name":        q,
+			"in":          "query",
+			"description": op.Query[q],
+			"schema":      map[string]interface{}{"type": "string"},
+		})
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = 200
+	}
+
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if op.Response != nil {
+		schema := b.schema(reflect.TypeOf(op.Response))
+		if !op.Raw {
+			schema = b.ack(schema)
+		}
+		success["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		}
+	}
+
+	responses := map[string]interface{}{
+		fmt.Sprintf("%d", status): success,
+	}
+
+	if !op.Raw {
+		responses["default"] = map[string]interface{}{
+			"description": "Unsuccessful ack, error_code and error_message describe the failure.",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"$ref": "#/components/schemas/ErrorAck"},
+				},
+			},
+		}
+	}
+
+	operation := map[string]interface{}{
+		"operationId": openApiOperationId(op),
+		"tags":        []string{op.Tag},
+		"summary":     op.Summary,
+		"responses":   responses,
+	}
+
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+
+	if op.Request != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": b.schema(reflect.TypeOf(op.Request)),
+				},
+			},
+		}
+	}
+
+	if op.Auth != "" {
+		operation["security"] = []interface{}{
+			map[string]interface{}{op.Auth: []string{}},
+		}
+	}
+
+	return operation
+}
+
+// ack wraps a payload schema in the ack envelope
+func (b *openApiBuilder) ack(payload map[string]interface{}) map[string]interface{} {
+	ackRef := b.schema(reflect.TypeOf(ack.Ack{}))
+	if payload == nil {
+		return ackRef
+	}
+
+	return map[string]interface{}{
+		"allOf": []interface{}{
+			ackRef,
+			map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"payload": payload},
+			},
+		},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// schema of a type, named structs are added to the components
+// and referenced
+func (b *openApiBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+
+		name := openApiSchemaName(t)
+		if _, ok := b.schemas[name]; !ok {
+			// placeholder for recursive types
+			b.schemas[name] = map[string]interface{}{}
+			b.schemas[name] = b.object(t)
+		}
+
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+
+	// interface{}
+	return map[string]interface{}{}
+}
+
+// object schema of a struct with the json names of its fields,
+// including the fields of embedded structs
+func (b *openApiBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	b.properties(t, properties)
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+// properties adds the fields of t not already in properties,
+// outer fields shadow the fields of embedded structs
+func (b *openApiBuilder) properties(t reflect.Type, properties map[string]interface{}) {
+	embedded := make([]reflect.Type, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		if _, ok := properties[name]; !ok {
+			properties[name] = b.schema(f.Type)
+		}
+	}
+
+	for _, et := range embedded {
+		b.properties(et, properties)
+	}
+}
+
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// openApiSchemaName is the type name, prefixed with the package
+// for types of other packages (EsResult)
+func openApiSchemaName(t reflect.Type) string {
+	pkg := strings.Split(t.PkgPath(), "/")
+	base := pkg[len(pkg)-1]
+
+	// skip a major version suffix, es/v2
+	if len(pkg) > 1 && majorVersion.MatchString(base) {
+		base = pkg[len(pkg)-2]
+	}
+
+	if base == "provision" || strings.HasPrefix(strings.ToLower(t.Name()), base) {
+		return t.Name()
+	}
+
+	return strings.ToUpper(base[:1]) + base[1:] + t.Name()
+}
+
+// openApiOperationId, e.g. getAdmParentAccountGroupsGroup
+func openApiOperationId(op OpenApiOperation) string {
+	id := strings.ToLower(op.Method)
+	for _, part := range strings.FieldsFunc(op.Path, func(r rune) bool {
+		return r == '/' || r == ':' || r == '*' || r == '.' || r == '_'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+
+	return id
+}
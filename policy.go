@@ -0,0 +1,325 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+const PolicyCheckAccess = "access"
+const PolicyCheckAdmin = "admin"
+const PolicyAllow = "allow"
+const PolicyDeny = "deny"
+
+// Policy is an ordered list of rules. The first rule for the
+// check whose expression is true decides, otherwise Default
+// (deny unless set to allow) applies.
+type Policy struct {
+	Name    string       `json:"name" yaml:"name"`
+	Default string       `json:"default" yaml:"default"`
+	Rules   []PolicyRule `json:"rules" yaml:"rules"`
+}
+
+// PolicyRule
+type PolicyRule struct {
+	Name string `json:"name" yaml:"name"`
+
+	// access, admin or empty for both
+	Check string `json:"check" yaml:"check"`
+
+	// allow or deny
+	Effect string `json:"effect" yaml:"effect"`
+
+	// expression, see policy_expr.go
+	When string `json:"when" yaml:"when"`
+}
+
+// PolicyInput is evaluated by a policy. Accounts holds
+// attributes of the requested accounts when the policy
+// uses the account() builtin. Ancestors holds the parent
+// chain of the requested accounts when admin is inherited.
+type PolicyInput struct {
+	User      *User
+	Request   *AccessCheck
+	Accounts  map[string]Account
+	Ancestors map[string][]string
+}
+
+// PolicyDecision
+type PolicyDecision struct {
+	Allowed bool   `json:"allowed"`
+	Rule    string `json:"rule"`
+}
+
+// PolicyExplanation traces how a decision was reached
+type PolicyExplanation struct {
+	Policy   string            `json:"policy"`
+	Check    string            `json:"check"`
+	Decision PolicyDecision    `json:"decision"`
+	Rules    []PolicyRuleTrace `json:"rules"`
+}
+
+// PolicyRuleTrace is the evaluation of a single rule. Rules
+// after the deciding rule are not evaluated and not listed.
+type PolicyRuleTrace struct {
+	Rule    string              `json:"rule"`
+	Effect  string              `json:"effect"`
+	When    string              `json:"when"`
+	Matched bool                `json:"matched"`
+	Error   string              `json:"error,omitempty"`
+	Details []PolicyTraceDetail `json:"details,omitempty"`
+}
+
+// PolicyTraceDetail records the item that decided an all/any,
+// e.g. the account a user is not a member of or the section
+// that was not granted.
+type PolicyTraceDetail struct {
+	Quantifier string `json:"quantifier"`
+	Var        string `json:"var"`
+	Value      string `json:"value"`
+	Result     bool   `json:"result"`
+}
+
+// DefaultPolicy implements the built in access rules: sysops
+// have access, admin checks require at least one account,
+// admins of the requested accounts have access, otherwise the
+// user must belong to the requested accounts and be granted
+// every requested section. Mode all requires this for every
+// account, mode any for at least one.
+var DefaultPolicy = Policy{
+	Name:    "default",
+	Default: PolicyDeny,
+	Rules: []PolicyRule{
+		{Name: "inactive", Effect: PolicyDeny, When: "!user.active"},
+		{Name: "sysop", Effect: PolicyAllow, When: "user.sysop"},
+		{Name: "invalid_mode", Effect: PolicyDeny,
+			When: `!(request.mode in ["all", "any"])`},
+		{Name: "no_accounts", Check: PolicyCheckAdmin, Effect: PolicyDeny,
+			When: "size(request.accounts) == 0"},
+		{Name: "denied_section", Check: PolicyCheckAccess, Effect: PolicyDeny,
+			When: `(request.mode == "all" || size(request.accounts) == 0) && any(request.sections, s, denied(s, request.accounts))`},
+		{Name: "account_admin", Effect: PolicyAllow,
+			When: `request.mode == "all" && size(request.accounts) > 0 && all(request.accounts, a, is_admin(a))`},
+		{Name: "any_account_admin", Check: PolicyCheckAdmin, Effect: PolicyAllow,
+			When: `request.mode == "any" && any(request.accounts, a, is_admin(a))`},
+		{Name: "any_account_access", Check: PolicyCheckAccess, Effect: PolicyAllow,
+			When: `request.mode == "any" && any(request.accounts, a,
+				!any(request.sections, s, denied(s, [a])) &&
+				(is_admin(a) || (is_member(a) && (user.sections_all || all(request.sections, s, granted(s, [a]))))))`},
+		{Name: "no_account_access", Check: PolicyCheckAccess, Effect: PolicyDeny,
+			When: `request.mode == "any" && size(request.accounts) > 0`},
+		{Name: "not_member", Check: PolicyCheckAccess, Effect: PolicyDeny,
+			When: "!all(request.accounts, a, is_member(a))"},
+		{Name: "sections_all", Check: PolicyCheckAccess, Effect: PolicyAllow, When: "user.sections_all"},
+		{Name: "sections_granted", Check: PolicyCheckAccess, Effect: PolicyAllow,
+			When: "all(request.sections, s, granted(s, request.accounts))"},
+	},
+}
+
+// PolicyEvaluator is a compiled policy
+type PolicyEvaluator struct {
+	Policy       Policy
+	UsesAccounts bool
+	rules        []policyNode
+}
+
+// NewPolicyEvaluator compiles a policy
+func NewPolicyEvaluator(policy Policy) (*PolicyEvaluator, error) {
+	if policy.Default == "" {
+		policy.Default = PolicyDeny
+	}
+
+	if policy.Default != PolicyAllow && policy.Default != PolicyDeny {
+		return nil, errors.New("policy default must be allow or deny")
+	}
+
+	pe := &PolicyEvaluator{Policy: policy}
+
+	for i, rule := range policy.Rules {
+		if rule.Effect != PolicyAllow && rule.Effect != PolicyDeny {
+			return nil, fmt.Errorf("policy rule %d (%s): effect must be allow or deny", i, rule.Name)
+		}
+
+		if rule.Check != "" && rule.Check != PolicyCheckAccess && rule.Check != PolicyCheckAdmin {
+			return nil, fmt.Errorf("policy rule %d (%s): check must be access, admin or empty", i, rule.Name)
+		}
+
+		node, usesAccounts, err := compilePolicyExpr(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("policy rule %d (%s): %s", i, rule.Name, err.Error())
+		}
+
+		pe.UsesAccounts = pe.UsesAccounts || usesAccounts
+		pe.rules = append(pe.rules, node)
+	}
+
+	return pe, nil
+}
+
+// Evaluate a check (access or admin) for the input. Expression
+// errors deny and are returned.
+func (pe *PolicyEvaluator) Evaluate(check string, in PolicyInput) (PolicyDecision, error) {
+	decision, _, err := pe.evaluate(check, in, false)
+	return decision, err
+}
+
+// Explain evaluates a check returning a trace of every rule
+// evaluated.
+func (pe *PolicyEvaluator) Explain(check string, in PolicyInput) (PolicyDecision, *PolicyExplanation, error) {
+	return pe.evaluate(check, in, true)
+}
+
+// evaluate
+func (pe *PolicyEvaluator) evaluate(check string, in PolicyInput, explain bool) (PolicyDecision, *PolicyExplanation, error) {
+	var exp *PolicyExplanation
+	if explain {
+		exp = &PolicyExplanation{
+			Policy: pe.Policy.Name,
+			Check:  check,
+			Rules:  make([]PolicyRuleTrace, 0),
+		}
+	}
+
+	decide := func(decision PolicyDecision, err error) (PolicyDecision, *PolicyExplanation, error) {
+		if exp != nil {
+			exp.Decision = decision
+		}
+		return decision, exp, err
+	}
+
+	if in.User == nil || in.Request == nil {
+		return decide(PolicyDecision{Rule: "invalid_input"}, errors.New("policy input requires a user and request"))
+	}
+
+	ctx := &policyCtx{
+		input: in,
+		vars: map[string]policyVal{
+			"user":    userPolicyVal(in.User),
+			"request": requestPolicyVal(in.Request),
+		},
+	}
+
+	for i, rule := range pe.Policy.Rules {
+		if rule.Check != "" && rule.Check != check {
+			continue
+		}
+
+		if explain {
+			ctx.trace = make([]PolicyTraceDetail, 0)
+		}
+
+		match, err := policyEvalBool(pe.rules[i], ctx)
+
+		if explain {
+			rt := PolicyRuleTrace{
+				Rule:    rule.Name,
+				Effect:  rule.Effect,
+				When:    rule.When,
+				Matched: match,
+				Details: ctx.trace,
+			}
+			if err != nil {
+				rt.Error = err.Error()
+			}
+			exp.Rules = append(exp.Rules, rt)
+		}
+
+		if err != nil {
+			return decide(PolicyDecision{Rule: rule.Name}, fmt.Errorf("policy rule %s: %s", rule.Name, err.Error()))
+		}
+
+		if match {
+			return decide(PolicyDecision{Allowed: rule.Effect == PolicyAllow, Rule: rule.Name}, nil)
+		}
+	}
+
+	return decide(PolicyDecision{Allowed: pe.Policy.Default == PolicyAllow, Rule: "default"}, nil)
+}
+
+// Decide evaluates the policy of the Api for a user
+func (a *Api) Decide(user *User, check string, ac *AccessCheck) (PolicyDecision, error) {
+	in, err := a.PolicyInput(user, ac)
+	if err != nil {
+		return PolicyDecision{}, err
+	}
+
+	return a.PolicyEvaluator().Evaluate(check, in)
+}
+
+// PolicyInput for a user and access check, loading the requested
+// accounts when the policy of the Api uses account attributes and
+// their ancestors when InheritAdmin is set.
+func (a *Api) PolicyInput(user *User, ac *AccessCheck) (PolicyInput, error) {
+	in := PolicyInput{User: user, Request: ac}
+
+	if a.InheritAdmin {
+		in.Ancestors = make(map[string][]string)
+		for _, acc := range ac.Accounts {
+			ancestors, err := a.AccountAncestors(acc)
+			if err != nil {
+				return in, err
+			}
+			in.Ancestors[acc] = ancestors
+		}
+	}
+
+	if !a.PolicyEvaluator().UsesAccounts {
+		return in, nil
+	}
+
+	in.Accounts = make(map[string]Account)
+	for _, acc := range ac.Accounts {
+		code, accountResult, err := a.GetAccount(acc)
+		if code == 404 {
+			continue
+		}
+		if err != nil {
+			return in, err
+		}
+		in.Accounts[acc] = accountResult.Source
+	}
+
+	return in, nil
+}
+
+// LoadPolicy reads a YAML (or JSON) policy file
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{}
+	err = yaml.Unmarshal(data, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// defaultPolicyEvaluator is DefaultPolicy compiled, used by the
+// package level access helpers
+var defaultPolicyEvaluator = mustPolicyEvaluator(DefaultPolicy)
+
+// PolicyEvaluator returns the compiled Config.Policy of the Api,
+// DefaultPolicy if none is configured
+func (a *Api) PolicyEvaluator() *PolicyEvaluator {
+	if a.policy == nil {
+		return defaultPolicyEvaluator
+	}
+
+	return a.policy
+}
+
+// mustPolicyEvaluator
+func mustPolicyEvaluator(policy Policy) *PolicyEvaluator {
+	pe, err := NewPolicyEvaluator(policy)
+	if err != nil {
+		panic(err)
+	}
+
+	return pe
+}
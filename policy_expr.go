@@ -0,0 +1,800 @@
+package provision
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Policy expressions are a small boolean language evaluated
+// against the user and the access request:
+//
+//   user.sysop || (user.active && "api" in request.sections)
+//   all(request.accounts, a, is_admin(a))
+//   any(request.sections, s, match(s, "billing:*"))
+//
+// Operators: ! && || == != < <= > >= in, list literals
+// ["a", "b"], member access with . and the builtins in
+// policyBuiltins. all/any bind a variable over a list.
+
+// policyVal is a string, float64, bool, []string,
+// map[string]interface{} or nil
+type policyVal interface{}
+
+// policyNode is a compiled expression
+type policyNode interface {
+	eval(ctx *policyCtx) (policyVal, error)
+}
+
+// policyCtx holds the input and bound variables of an evaluation,
+// trace collects all/any deciding items when explaining
+type policyCtx struct {
+	input PolicyInput
+	vars  map[string]policyVal
+	trace []PolicyTraceDetail
+}
+
+// policyBuiltin
+type policyBuiltin struct {
+	arity int
+	fn    func(ctx *policyCtx, args []policyVal) (policyVal, error)
+}
+
+// policyBuiltins available to expressions
+var policyBuiltins = map[string]policyBuiltin{
+	// size of a list or string
+	"size": {1, func(ctx *policyCtx, args []policyVal) (policyVal, error) {
+		switch v := args[0].(type) {
+		case []string:
+			return float64(len(v)), nil
+		case string:
+			return float64(len(v)), nil
+		case nil:
+			return float64(0), nil
+		}
+		return nil, fmt.Errorf("size of %T", args[0])
+	}},
+	// user belongs to the account
+	"is_member": {1, func(ctx *policyCtx, args []policyVal) (policyVal, error) {
+		acc, err := policyString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		u := ctx.input.User
+		return stringInSlice(acc, u.Accounts) || len(u.memberships(acc)) > 0, nil
+	}},
+	// user is an admin of the account or, when inherited,
+	// of one of its ancestors
+	"is_admin": {1, func(ctx *policyCtx, args []policyVal) (policyVal, error) {
+		acc, err := policyString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if ctx.input.User.isAccountAdmin(acc) {
+			return true, nil
+		}
+		for _, ancestor := range ctx.input.Ancestors[acc] {
+			if ctx.input.User.isAccountAdmin(ancestor) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}},
+	// parent chain of a requested account when admin is
+	// inherited, otherwise empty
+	"ancestors": {1, func(ctx *policyCtx, args []policyVal) (policyVal, error) {
+		acc, err := policyString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return nonNil(ctx.input.Ancestors[acc]), nil
+	}},
+	// section is granted in every one of the accounts
+	"granted": {2, func(ctx *policyCtx, args []policyVal) (policyVal, error) {
+		sec, err := policyString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		accounts, err := policyList(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return ctx.input.User.hasSection(sec, accounts), nil
+	}},
+	// section is denied in one of the accounts
+	"denied": {2, func(ctx *policyCtx, args []policyVal) (policyVal, error) {
+		sec, err := policyString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		accounts, err := policyList(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return ctx.input.User.sectionDenied(sec, accounts), nil
+	}},
+	// glob match
+	"match": {2, func(ctx *policyCtx, args []policyVal) (policyVal, error) {
+		s, err := policyString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		p, err := policyString(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return sectionMatch(s, []string{p}), nil
+	}},
+	// account attributes or null if not available
+	"account": {1, func(ctx *policyCtx, args []policyVal) (policyVal, error) {
+		id, err := policyString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		acc, ok := ctx.input.Accounts[id]
+		if !ok {
+			return nil, nil
+		}
+		return accountPolicyVal(acc), nil
+	}},
+}
+
+// compilePolicyExpr parses an expression, validating identifiers
+// and builtin arity.
+func compilePolicyExpr(src string) (policyNode, bool, error) {
+	toks, err := lexPolicy(src)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p := &policyParser{toks: toks, scope: []string{"user", "request"}}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if p.peek().kind != tokEOF {
+		return nil, false, fmt.Errorf("unexpected %q at %d", p.peek().text, p.peek().pos)
+	}
+
+	return node, p.usesAccounts, nil
+}
+
+// lexer
+
+const (
+	tokEOF = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type policyTok struct {
+	kind int
+	text string
+	pos  int
+}
+
+// policyOps longest first
+var policyOps = []string{"&&", "||", "==", "!=", "<=", ">=", "!", "<", ">", "(", ")", "[", "]", ",", "."}
+
+// lexPolicy
+func lexPolicy(src string) ([]policyTok, error) {
+	toks := make([]policyTok, 0)
+	rs := []rune(src)
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(rs) && rs[j] != r; j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+				sb.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			toks = append(toks, policyTok{tokString, sb.String(), i})
+			i = j + 1
+
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			toks = append(toks, policyTok{tokNumber, string(rs[i:j]), i})
+			i = j
+
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			toks = append(toks, policyTok{tokIdent, string(rs[i:j]), i})
+			i = j
+
+		default:
+			matched := false
+			for _, op := range policyOps {
+				if strings.HasPrefix(string(rs[i:]), op) {
+					toks = append(toks, policyTok{tokOp, op, i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at %d", r, i)
+			}
+		}
+	}
+
+	return append(toks, policyTok{tokEOF, "", len(rs)}), nil
+}
+
+// parser
+
+type policyParser struct {
+	toks         []policyTok
+	i            int
+	scope        []string
+	usesAccounts bool
+}
+
+func (p *policyParser) peek() policyTok {
+	return p.toks[p.i]
+}
+
+func (p *policyParser) next() policyTok {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *policyParser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (p *policyParser) expect(op string) error {
+	if !p.isOp(op) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at %d, found %q", op, t.pos, t.text)
+	}
+	p.next()
+	return nil
+}
+
+func (p *policyParser) parseOr() (policyNode, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &policyBinNode{op: "||", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *policyParser) parseAnd() (policyNode, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &policyBinNode{op: "&&", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *policyParser) parseUnary() (policyNode, error) {
+	if p.isOp("!") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &policyNotNode{x: x}, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *policyParser) parseCmp() (policyNode, error) {
+	l, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	isCmp := t.kind == tokOp && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">=")
+	if isCmp || (t.kind == tokIdent && t.text == "in") {
+		p.next()
+		r, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return &policyBinNode{op: t.text, l: l, r: r}, nil
+	}
+
+	return l, nil
+}
+
+func (p *policyParser) parsePostfix() (policyNode, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(".") {
+		p.next()
+		t := p.next()
+		if t.kind != tokIdent {
+			return nil, fmt.Errorf("expected field name at %d", t.pos)
+		}
+		x = &policyMemberNode{x: x, field: t.text}
+	}
+	return x, nil
+}
+
+func (p *policyParser) parsePrimary() (policyNode, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokString:
+		return &policyLitNode{v: t.text}, nil
+
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q at %d", t.text, t.pos)
+		}
+		return &policyLitNode{v: f}, nil
+
+	case tokOp:
+		if t.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+		if t.text == "[" {
+			items := make([]policyNode, 0)
+			for !p.isOp("]") {
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+				if !p.isOp(",") {
+					break
+				}
+				p.next()
+			}
+			return &policyListNode{items: items}, p.expect("]")
+		}
+
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &policyLitNode{v: true}, nil
+		case "false":
+			return &policyLitNode{v: false}, nil
+		case "null":
+			return &policyLitNode{v: nil}, nil
+		}
+
+		if p.isOp("(") {
+			return p.parseCall(t)
+		}
+
+		for _, name := range p.scope {
+			if name == t.text {
+				return &policyIdentNode{name: t.text}, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown identifier %q at %d", t.text, t.pos)
+	}
+
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// parseCall parses a builtin call or an all/any quantifier
+func (p *policyParser) parseCall(name policyTok) (policyNode, error) {
+	p.next()
+
+	if name.text == "all" || name.text == "any" {
+		list, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		v := p.next()
+		if v.kind != tokIdent {
+			return nil, fmt.Errorf("expected variable name at %d", v.pos)
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+
+		p.scope = append(p.scope, v.text)
+		body, err := p.parseOr()
+		p.scope = p.scope[:len(p.scope)-1]
+		if err != nil {
+			return nil, err
+		}
+
+		return &policyQuantNode{all: name.text == "all", list: list, name: v.text, body: body}, p.expect(")")
+	}
+
+	builtin, ok := policyBuiltins[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at %d", name.text, name.pos)
+	}
+
+	if name.text == "account" {
+		p.usesAccounts = true
+	}
+
+	args := make([]policyNode, 0)
+	for !p.isOp(")") {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if len(args) != builtin.arity {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", name.text, builtin.arity, len(args))
+	}
+
+	return &policyCallNode{name: name.text, fn: builtin.fn, args: args}, nil
+}
+
+// nodes
+
+type policyLitNode struct {
+	v policyVal
+}
+
+func (n *policyLitNode) eval(ctx *policyCtx) (policyVal, error) {
+	return n.v, nil
+}
+
+type policyIdentNode struct {
+	name string
+}
+
+func (n *policyIdentNode) eval(ctx *policyCtx) (policyVal, error) {
+	return ctx.vars[n.name], nil
+}
+
+type policyMemberNode struct {
+	x     policyNode
+	field string
+}
+
+func (n *policyMemberNode) eval(ctx *policyCtx) (policyVal, error) {
+	v, err := n.x.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m[n.field], nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("no field %s on %T", n.field, v)
+}
+
+type policyListNode struct {
+	items []policyNode
+}
+
+func (n *policyListNode) eval(ctx *policyCtx) (policyVal, error) {
+	list := make([]string, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s, err := policyString(v)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+type policyNotNode struct {
+	x policyNode
+}
+
+func (n *policyNotNode) eval(ctx *policyCtx) (policyVal, error) {
+	b, err := policyEvalBool(n.x, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return !b, nil
+}
+
+type policyBinNode struct {
+	op   string
+	l, r policyNode
+}
+
+func (n *policyBinNode) eval(ctx *policyCtx) (policyVal, error) {
+	if n.op == "&&" || n.op == "||" {
+		l, err := policyEvalBool(n.l, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		return policyEvalBool(n.r, ctx)
+	}
+
+	l, err := n.l.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.r.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return policyEqual(l, r), nil
+	case "!=":
+		return !policyEqual(l, r), nil
+	case "in":
+		s, err := policyString(l)
+		if err != nil {
+			return nil, err
+		}
+		list, err := policyList(r)
+		if err != nil {
+			return nil, err
+		}
+		return stringInSlice(s, list), nil
+	}
+
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%s requires numbers", n.op)
+	}
+
+	switch n.op {
+	case "<":
+		return lf < rf, nil
+	case "<=":
+		return lf <= rf, nil
+	case ">":
+		return lf > rf, nil
+	}
+	return lf >= rf, nil
+}
+
+type policyCallNode struct {
+	name string
+	fn   func(ctx *policyCtx, args []policyVal) (policyVal, error)
+	args []policyNode
+}
+
+func (n *policyCallNode) eval(ctx *policyCtx) (policyVal, error) {
+	args := make([]policyVal, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	v, err := n.fn(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", n.name, err.Error())
+	}
+	return v, nil
+}
+
+type policyQuantNode struct {
+	all  bool
+	list policyNode
+	name string
+	body policyNode
+}
+
+func (n *policyQuantNode) eval(ctx *policyCtx) (policyVal, error) {
+	lv, err := n.list.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list, err := policyList(lv)
+	if err != nil {
+		return nil, err
+	}
+
+	prev, shadowed := ctx.vars[n.name]
+	defer func() {
+		if shadowed {
+			ctx.vars[n.name] = prev
+		} else {
+			delete(ctx.vars, n.name)
+		}
+	}()
+
+	for _, item := range list {
+		ctx.vars[n.name] = item
+		b, err := policyEvalBool(n.body, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if b != n.all {
+			if ctx.trace != nil {
+				quantifier := "any"
+				if n.all {
+					quantifier = "all"
+				}
+				ctx.trace = append(ctx.trace, PolicyTraceDetail{
+					Quantifier: quantifier,
+					Var:        n.name,
+					Value:      item,
+					Result:     b,
+				})
+			}
+			return b, nil
+		}
+	}
+
+	return n.all, nil
+}
+
+// helpers
+
+// policyEvalBool
+func policyEvalBool(n policyNode, ctx *policyCtx) (bool, error) {
+	v, err := n.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("expected boolean, got %T", v)
+}
+
+// policyString
+func policyString(v policyVal) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected string, got %T", v)
+	}
+	return s, nil
+}
+
+// policyList
+func policyList(v policyVal) ([]string, error) {
+	switch l := v.(type) {
+	case []string:
+		return l, nil
+	case nil:
+		return []string{}, nil
+	}
+	return nil, fmt.Errorf("expected list, got %T", v)
+}
+
+// policyEqual
+func policyEqual(l policyVal, r policyVal) bool {
+	switch lv := l.(type) {
+	case []string:
+		rv, ok := r.([]string)
+		if !ok || len(lv) != len(rv) {
+			return false
+		}
+		for i := range lv {
+			if lv[i] != rv[i] {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		return false
+	}
+	if _, ok := r.([]string); ok {
+		return false
+	}
+	if _, ok := r.(map[string]interface{}); ok {
+		return false
+	}
+	return l == r
+}
+
+// userPolicyVal exposes user attributes to expressions
+func userPolicyVal(u *User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             u.Id,
+		"email":          u.Email,
+		"active":         u.Active,
+		"sysop":          u.Sysop,
+		"sections":       nonNil(u.Sections),
+		"sections_all":   u.SectionsAll,
+		"deny_sections":  nonNil(u.DenySections),
+		"accounts":       nonNil(u.Accounts),
+		"admin_accounts": nonNil(u.AdminAccounts),
+	}
+}
+
+// requestPolicyVal exposes the access request to expressions
+func requestPolicyVal(ac *AccessCheck) map[string]interface{} {
+	mode := ac.Mode
+	if mode == "" {
+		mode = AccessModeAll
+	}
+
+	return map[string]interface{}{
+		"accounts": nonNil(ac.Accounts),
+		"sections": nonNil(ac.Sections),
+		"mode":     mode,
+	}
+}
+
+// accountPolicyVal exposes account attributes to expressions
+func accountPolicyVal(acc Account) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           acc.Id,
+		"parent":       acc.Parent,
+		"display_name": acc.DisplayName,
+		"active":       acc.Active,
+		"modules":      nonNil(acc.Modules),
+		"org_id":       float64(acc.OrgId),
+	}
+}
+
+// nonNil
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,136 @@
+package provision
+
+import (
+	"testing"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	users := map[string]*User{
+		"sysop": {Id: "sysop", Active: true, Sysop: true},
+		"admin": {Id: "admin", Active: true, AdminAccounts: []string{"acme"}},
+		"member": {Id: "member", Active: true, Accounts: []string{"acme"},
+			Memberships: []Membership{{Account: "acme", Sections: []string{"billing"}}},
+			Sections:    []string{"reports"}},
+		"inactive": {Id: "inactive", Active: false, Sysop: true, AdminAccounts: []string{"acme"}},
+	}
+
+	none := []string{}
+	acme := []string{"acme"}
+	both := []string{"acme", "other"}
+
+	tt := []struct {
+		user     string
+		check    string
+		mode     string
+		accounts []string
+		section  string
+		allowed  bool
+		rule     string
+	}{
+		// sysop
+		{"sysop", PolicyCheckAccess, AccessModeAll, none, "reports", true, "sysop"},
+		{"sysop", PolicyCheckAccess, AccessModeAll, acme, "reports", true, "sysop"},
+		{"sysop", PolicyCheckAccess, AccessModeAny, none, "reports", true, "sysop"},
+		{"sysop", PolicyCheckAccess, AccessModeAny, both, "reports", true, "sysop"},
+		{"sysop", PolicyCheckAdmin, AccessModeAll, none, "", true, "sysop"},
+		{"sysop", PolicyCheckAdmin, AccessModeAny, acme, "", true, "sysop"},
+
+		// admin
+		{"admin", PolicyCheckAccess, AccessModeAll, none, "reports", false, "default"},
+		{"admin", PolicyCheckAccess, AccessModeAll, acme, "reports", true, "account_admin"},
+		{"admin", PolicyCheckAccess, AccessModeAll, both, "reports", false, "not_member"},
+		{"admin", PolicyCheckAccess, AccessModeAny, none, "reports", false, "default"},
+		{"admin", PolicyCheckAccess, AccessModeAny, acme, "reports", true, "any_account_access"},
+		{"admin", PolicyCheckAccess, AccessModeAny, both, "reports", true, "any_account_access"},
+		{"admin", PolicyCheckAdmin, AccessModeAll, none, "", false, "no_accounts"},
+		{"admin", PolicyCheckAdmin, AccessModeAll, acme, "", true, "account_admin"},
+		{"admin", PolicyCheckAdmin, AccessModeAll, both, "", false, "default"},
+		{"admin", PolicyCheckAdmin, AccessModeAny, none, "", false, "no_accounts"},
+		{"admin", PolicyCheckAdmin, AccessModeAny, both, "", true, "any_account_admin"},
+
+		// member
+		{"member", PolicyCheckAccess, AccessModeAll, none, "reports", true, "sections_granted"},
+		{"member", PolicyCheckAccess, AccessModeAll, none, "billing", false, "default"},
+		{"member", PolicyCheckAccess, AccessModeAll, acme, "billing", true, "sections_granted"},
+		{"member", PolicyCheckAccess, AccessModeAll, acme, "admin", false, "default"},
+		{"member", PolicyCheckAccess, AccessModeAll, both, "reports", false, "not_member"},
+		{"member", PolicyCheckAccess, AccessModeAny, none, "reports", true, "sections_granted"},
+		{"member", PolicyCheckAccess, AccessModeAny, both, "billing", true, "any_account_access"},
+		{"member", PolicyCheckAccess, AccessModeAny, both, "admin", false, "no_account_access"},
+		{"member", PolicyCheckAdmin, AccessModeAll, none, "", false, "no_accounts"},
+		{"member", PolicyCheckAdmin, AccessModeAll, acme, "", false, "default"},
+		{"member", PolicyCheckAdmin, AccessModeAny, acme, "", false, "default"},
+
+		// inactive is denied before sysop and admin
+		{"inactive", PolicyCheckAccess, AccessModeAll, none, "reports", false, "inactive"},
+		{"inactive", PolicyCheckAccess, AccessModeAll, acme, "reports", false, "inactive"},
+		{"inactive", PolicyCheckAccess, AccessModeAny, acme, "reports", false, "inactive"},
+		{"inactive", PolicyCheckAdmin, AccessModeAll, acme, "", false, "inactive"},
+		{"inactive", PolicyCheckAdmin, AccessModeAny, acme, "", false, "inactive"},
+
+		// an empty mode is all
+		{"admin", PolicyCheckAccess, "", acme, "reports", true, "account_admin"},
+		{"member", PolicyCheckAccess, "", both, "reports", false, "not_member"},
+
+		// modes are case sensitive, only sysop passes an unknown mode
+		{"sysop", PolicyCheckAccess, "ALL", acme, "reports", true, "sysop"},
+		{"admin", PolicyCheckAccess, "ALL", acme, "reports", false, "invalid_mode"},
+		{"admin", PolicyCheckAdmin, "ALL", acme, "", false, "invalid_mode"},
+		{"member", PolicyCheckAccess, "Any", acme, "billing", false, "invalid_mode"},
+	}
+
+	for _, tc := range tt {
+		name := tc.user + "/" + tc.check + "/" + tc.mode + "/" + tc.section
+		for _, acc := range tc.accounts {
+			name += "/" + acc
+		}
+
+		t.Run(name, func(t *testing.T) {
+			ac := &AccessCheck{Accounts: tc.accounts, Mode: tc.mode}
+			if tc.section != "" {
+				ac.Sections = []string{tc.section}
+			}
+
+			decision, err := defaultPolicyEvaluator.Evaluate(tc.check, PolicyInput{User: users[tc.user], Request: ac})
+			if err != nil {
+				t.Fatalf("Evaluate: %s", err.Error())
+			}
+
+			if decision.Allowed != tc.allowed || decision.Rule != tc.rule {
+				t.Errorf("got %v by %s, want %v by %s", decision.Allowed, decision.Rule, tc.allowed, tc.rule)
+			}
+
+			// the package level helpers agree with the policy
+			has := users[tc.user].HasAccess(ac)
+			if tc.check == PolicyCheckAdmin {
+				has = users[tc.user].HasAdminAccess(ac)
+			}
+			if has != tc.allowed {
+				t.Errorf("helper got %v, want %v", has, tc.allowed)
+			}
+		})
+	}
+}
+
+func TestApiPolicyEvaluator(t *testing.T) {
+	open, err := NewPolicyEvaluator(Policy{Name: "open", Default: PolicyAllow})
+	if err != nil {
+		t.Fatalf("NewPolicyEvaluator: %s", err.Error())
+	}
+
+	a := &Api{Config: &Config{}, policy: open}
+	b := &Api{Config: &Config{}}
+
+	if a.PolicyEvaluator().Policy.Name != "open" {
+		t.Errorf("Api policy = %s, want open", a.PolicyEvaluator().Policy.Name)
+	}
+	if b.PolicyEvaluator().Policy.Name != DefaultPolicy.Name {
+		t.Errorf("Api without policy = %s, want %s", b.PolicyEvaluator().Policy.Name, DefaultPolicy.Name)
+	}
+
+	// an Api policy never changes the package level helpers
+	user := &User{Id: "u1", Active: true}
+	if user.HasAccess(&AccessCheck{Accounts: []string{"acme"}}) {
+		t.Error("HasAccess used the Api policy")
+	}
+}
@@ -16,6 +16,8 @@ package provision
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -42,16 +44,69 @@ type Config struct {
 
 	// pre-configured from server (txn2/micro)
 	Token *token.Jwt
+
+	// refresh token expiration in minutes
+	// defaults to RefreshTokenExpDefault
+	RefreshTokenExp int
+
+	// when true tokens carry only the user id and epoch and
+	// the current user is resolved from storage on validation
+	TokenUserRef bool
+
+	// seconds to cache users resolved from storage
+	// defaults to UserCacheTTLDefault, negative disables
+	UserCacheTTL int
+
+	// OpenID Connect login federation
+	// if nil OIDC routes respond not found
+	Oidc *OidcCfg
+
+	// access policy for all access checks
+	// defaults to DefaultPolicy
+	Policy *Policy
+
+	// when true admins of an account are admins of all
+	// of its descendant accounts
+	InheritAdmin bool
+
+	// seconds to cache the account hierarchy
+	// defaults to AccountCacheTTLDefault, negative disables
+	AccountCacheTTL int
+
+	// when true sysops may search with Elasticsearch DSL
+	// (dsl=true), otherwise searches take a SearchQuery
+	RawSearch bool
 }
 
 // Api
 type Api struct {
+	// unix time expired revocations were last removed, first
+	// for 64-bit alignment of atomic access
+	revokedPurged int64
+
 	*Config
+	userCache *userCache
+	ancestors *ancestorCache
+	oidc      oidcProvider
+	scimKeys  scimKeyCache
+	policy    *PolicyEvaluator
 }
 
 // NewApi
 func NewApi(cfg *Config) (*Api, error) {
-	a := &Api{Config: cfg}
+	if cfg.UserCacheTTL == 0 {
+		cfg.UserCacheTTL = UserCacheTTLDefault
+	}
+
+	if cfg.AccountCacheTTL == 0 {
+		cfg.AccountCacheTTL = AccountCacheTTLDefault
+	}
+
+	a := &Api{
+		Config:    cfg,
+		userCache: newUserCache(cfg.UserCacheTTL),
+		ancestors: newAncestorCache(cfg.AccountCacheTTL),
+	}
 
 	if a.Elastic == nil {
 		// Configure an elastic client
@@ -66,6 +121,18 @@ func NewApi(cfg *Config) (*Api, error) {
 		cfg.IdxPrefix = "system_"
 	}
 
+	if cfg.RefreshTokenExp == 0 {
+		cfg.RefreshTokenExp = RefreshTokenExpDefault
+	}
+
+	if cfg.Policy != nil {
+		pe, err := NewPolicyEvaluator(*cfg.Policy)
+		if err != nil {
+			return nil, err
+		}
+		a.policy = pe
+	}
+
 	// check for elasticsearch a few times before failing
 	// this reduces a reliance on restarts when a full system is
 	// spinning up
@@ -101,6 +168,42 @@ func NewApi(cfg *Config) (*Api, error) {
 		return nil, err
 	}
 
+	// send index mappings for refresh tokens
+	err = a.SendEsMapping(GetRefreshTokenMapping(cfg.IdxPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	// send index mappings for revoked tokens
+	err = a.SendEsMapping(GetRevokedTokenMapping(cfg.IdxPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	// send index mappings for oidc login state
+	err = a.SendEsMapping(GetOidcStateMapping(cfg.IdxPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	// send index mappings for invites
+	err = a.SendEsMapping(GetInviteMapping(cfg.IdxPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	// send index mappings for roles
+	err = a.SendEsMapping(GetRoleMapping(cfg.IdxPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	// send index mappings for groups
+	err = a.SendEsMapping(GetGroupMapping(cfg.IdxPrefix))
+	if err != nil {
+		return nil, err
+	}
+
 	return a, nil
 }
 
@@ -124,12 +227,28 @@ func (a *Api) SendEsMapping(mapping es.IndexTemplate) error {
 		if errorResponse != nil {
 			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
 		}
-		return errors.New("Error setting up " + mapping.Name + " template, got code " + string(code))
+		return errors.New("Error setting up " + mapping.Name + " template, got code " + strconv.Itoa(code))
 	}
 
 	return err
 }
 
+// esDelete removes a document
+func (a *Api) esDelete(pth string) (int, error) {
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/%s", a.Elastic.ElasticServer, pth), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := a.Elastic.HttpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
 // PrefixHandler
 func (a *Api) PrefixHandler(c *gin.Context) {
 	ak := ack.Gin(c)
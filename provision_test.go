@@ -0,0 +1,191 @@
+package provision
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/txn2/es/v2"
+	"go.uber.org/zap"
+)
+
+// newTestApi returns an Api backed by an Elasticsearch stub, the
+// stub must be closed
+func newTestApi(handler http.HandlerFunc) (*Api, *httptest.Server) {
+	srv := httptest.NewServer(handler)
+
+	cfg := &Config{Logger: zap.NewNop()}
+	cfg.Elastic = es.CreateClient(es.Config{Log: cfg.Logger, HttpClient: http.DefaultClient, ElasticServer: srv.URL})
+
+	return &Api{Config: cfg, userCache: newUserCache(1), ancestors: newAncestorCache(1)}, srv
+}
+
+// memEs is an in memory Elasticsearch stub of the document
+// and term search calls made by the Api
+type memEs struct {
+	mu       sync.Mutex
+	docs     map[string]json.RawMessage
+	versions map[string]int
+}
+
+func newMemEs() *memEs {
+	return &memEs{docs: make(map[string]json.RawMessage), versions: make(map[string]int)}
+}
+
+// put stores a document as index/id
+func (m *memEs) put(key string, doc interface{}) {
+	js, _ := json.Marshal(doc)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.docs[key] = js
+	m.versions[key]++
+}
+
+// get unmarshals the document index/id into doc
+func (m *memEs) get(key string, doc interface{}) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	js, ok := m.docs[key]
+	if ok {
+		json.Unmarshal(js, doc)
+	}
+
+	return ok
+}
+
+func (m *memEs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	body, _ := ioutil.ReadAll(r.Body)
+
+	if len(parts) == 2 && parts[1] == "_search" {
+		m.search(w, parts[0], body)
+		return
+	}
+
+	if len(parts) < 3 || parts[1] != "_doc" {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"error":"unsupported"}`))
+		return
+	}
+
+	key := parts[0] + "/" + parts[2]
+	_, exists := m.docs[key]
+
+	switch {
+	case r.Method == http.MethodGet:
+		if !exists {
+			w.WriteHeader(404)
+			w.Write([]byte(`{"found":false}`))
+			return
+		}
+		w.Write([]byte(`{"found":true,"_version":` + strconv.Itoa(m.versions[key]) + `,"_source":` + string(m.docs[key]) + `}`))
+
+	case r.Method == http.MethodDelete:
+		if !exists {
+			w.WriteHeader(404)
+			w.Write([]byte(`{"result":"not_found"}`))
+			return
+		}
+		if v := r.URL.Query().Get("version"); v != "" && v != strconv.Itoa(m.versions[key]) {
+			w.WriteHeader(409)
+			w.Write([]byte(`{"error":"version_conflict_engine_exception"}`))
+			return
+		}
+		delete(m.docs, key)
+		w.Write([]byte(`{"result":"deleted"}`))
+
+	default:
+		if len(parts) == 4 && parts[3] == "_create" && exists {
+			w.WriteHeader(409)
+			w.Write([]byte(`{"error":"version_conflict_engine_exception"}`))
+			return
+		}
+		m.docs[key] = body
+		m.versions[key]++
+		w.WriteHeader(201)
+		w.Write([]byte(`{"result":"created","_version":` + strconv.Itoa(m.versions[key]) + `}`))
+	}
+}
+
+// search matches a term or terms query, optionally of a
+// constant_score filter, against the documents of idx. Other
+// queries match every document.
+func (m *memEs) search(w http.ResponseWriter, idx string, body []byte) {
+	type terms struct {
+		Term  map[string]interface{}   `json:"term"`
+		Terms map[string][]interface{} `json:"terms"`
+	}
+
+	q := struct {
+		Query struct {
+			terms
+			ConstantScore struct {
+				Filter terms `json:"filter"`
+			} `json:"constant_score"`
+		} `json:"query"`
+	}{}
+	json.Unmarshal(body, &q)
+
+	filter := q.Query.terms
+	if q.Query.Term == nil && q.Query.Terms == nil {
+		filter = q.Query.ConstantScore.Filter
+	}
+
+	keys := make([]string, 0)
+	for key := range m.docs {
+		if strings.HasPrefix(key, idx+"/") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	hits := make([]string, 0)
+	for _, key := range keys {
+		doc := make(map[string]interface{})
+		json.Unmarshal(m.docs[key], &doc)
+
+		match := true
+		for field, value := range filter.Term {
+			match = match && termMatch(doc[field], value)
+		}
+		for field, values := range filter.Terms {
+			any := false
+			for _, value := range values {
+				any = any || termMatch(doc[field], value)
+			}
+			match = match && any
+		}
+
+		if match {
+			id := strings.TrimPrefix(key, idx+"/")
+			hits = append(hits, `{"_id":"`+id+`","_source":`+string(m.docs[key])+`,"sort":["`+id+`"]}`)
+		}
+	}
+
+	w.Write([]byte(`{"hits":{"total":` + strconv.Itoa(len(hits)) + `,"hits":[` + strings.Join(hits, ",") + `]}}`))
+}
+
+// termMatch compares a field value or any value of a list
+func termMatch(field interface{}, value interface{}) bool {
+	if list, ok := field.([]interface{}); ok {
+		for _, v := range list {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	return field == value
+}
@@ -0,0 +1,2882 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: provisionpb/provision.proto
+
+package provisionpb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type GetRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetRequest) Reset()         { *m = GetRequest{} }
+func (m *GetRequest) String() string { return proto.CompactTextString(m) }
+func (*GetRequest) ProtoMessage()    {}
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{0}
+}
+
+func (m *GetRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetRequest.Unmarshal(m, b)
+}
+func (m *GetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetRequest.Marshal(b, m, deterministic)
+}
+func (m *GetRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRequest.Merge(m, src)
+}
+func (m *GetRequest) XXX_Size() int {
+	return xxx_messageInfo_GetRequest.Size(m)
+}
+func (m *GetRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetRequest proto.InternalMessageInfo
+
+func (m *GetRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// SearchRequest mirrors provision.SearchQuery
+type SearchRequest struct {
+	// Elasticsearch DSL as JSON in place of the fields below,
+	// requires a sysop token and raw search enabled
+	Dsl     []byte                   `protobuf:"bytes,1,opt,name=dsl,proto3" json:"dsl,omitempty"`
+	Text    string                   `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Ids     []string                 `protobuf:"bytes,3,rep,name=ids,proto3" json:"ids,omitempty"`
+	Filters map[string]*FilterValues `protobuf:"bytes,4,rep,name=filters,proto3" json:"filters,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Sort    []*SearchSort            `protobuf:"bytes,5,rep,name=sort,proto3" json:"sort,omitempty"`
+	From    int32                    `protobuf:"varint,6,opt,name=from,proto3" json:"from,omitempty"`
+	Size    int32                    `protobuf:"varint,7,opt,name=size,proto3" json:"size,omitempty"`
+	// next_cursor of the previous page, in place of from
+	Cursor               string   `protobuf:"bytes,8,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SearchRequest) Reset()         { *m = SearchRequest{} }
+func (m *SearchRequest) String() string { return proto.CompactTextString(m) }
+func (*SearchRequest) ProtoMessage()    {}
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{1}
+}
+
+func (m *SearchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SearchRequest.Unmarshal(m, b)
+}
+func (m *SearchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SearchRequest.Marshal(b, m, deterministic)
+}
+func (m *SearchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchRequest.Merge(m, src)
+}
+func (m *SearchRequest) XXX_Size() int {
+	return xxx_messageInfo_SearchRequest.Size(m)
+}
+func (m *SearchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SearchRequest proto.InternalMessageInfo
+
+func (m *SearchRequest) GetDsl() []byte {
+	if m != nil {
+		return m.Dsl
+	}
+	return nil
+}
+
+func (m *SearchRequest) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+func (m *SearchRequest) GetIds() []string {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+func (m *SearchRequest) GetFilters() map[string]*FilterValues {
+	if m != nil {
+		return m.Filters
+	}
+	return nil
+}
+
+func (m *SearchRequest) GetSort() []*SearchSort {
+	if m != nil {
+		return m.Sort
+	}
+	return nil
+}
+
+func (m *SearchRequest) GetFrom() int32 {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *SearchRequest) GetSize() int32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *SearchRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+// FilterValues matches any of the values
+type FilterValues struct {
+	Values               []string `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FilterValues) Reset()         { *m = FilterValues{} }
+func (m *FilterValues) String() string { return proto.CompactTextString(m) }
+func (*FilterValues) ProtoMessage()    {}
+func (*FilterValues) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{2}
+}
+
+func (m *FilterValues) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FilterValues.Unmarshal(m, b)
+}
+func (m *FilterValues) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FilterValues.Marshal(b, m, deterministic)
+}
+func (m *FilterValues) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FilterValues.Merge(m, src)
+}
+func (m *FilterValues) XXX_Size() int {
+	return xxx_messageInfo_FilterValues.Size(m)
+}
+func (m *FilterValues) XXX_DiscardUnknown() {
+	xxx_messageInfo_FilterValues.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FilterValues proto.InternalMessageInfo
+
+func (m *FilterValues) GetValues() []string {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+type SearchSort struct {
+	Field                string   `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Desc                 bool     `protobuf:"varint,2,opt,name=desc,proto3" json:"desc,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SearchSort) Reset()         { *m = SearchSort{} }
+func (m *SearchSort) String() string { return proto.CompactTextString(m) }
+func (*SearchSort) ProtoMessage()    {}
+func (*SearchSort) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{3}
+}
+
+func (m *SearchSort) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SearchSort.Unmarshal(m, b)
+}
+func (m *SearchSort) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SearchSort.Marshal(b, m, deterministic)
+}
+func (m *SearchSort) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchSort.Merge(m, src)
+}
+func (m *SearchSort) XXX_Size() int {
+	return xxx_messageInfo_SearchSort.Size(m)
+}
+func (m *SearchSort) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchSort.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SearchSort proto.InternalMessageInfo
+
+func (m *SearchSort) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *SearchSort) GetDesc() bool {
+	if m != nil {
+		return m.Desc
+	}
+	return false
+}
+
+// UpsertResult is the Elasticsearch result of an upsert
+type UpsertResult struct {
+	Index                string   `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	Id                   string   `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Version              int64    `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	Result               string   `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpsertResult) Reset()         { *m = UpsertResult{} }
+func (m *UpsertResult) String() string { return proto.CompactTextString(m) }
+func (*UpsertResult) ProtoMessage()    {}
+func (*UpsertResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{4}
+}
+
+func (m *UpsertResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpsertResult.Unmarshal(m, b)
+}
+func (m *UpsertResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpsertResult.Marshal(b, m, deterministic)
+}
+func (m *UpsertResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpsertResult.Merge(m, src)
+}
+func (m *UpsertResult) XXX_Size() int {
+	return xxx_messageInfo_UpsertResult.Size(m)
+}
+func (m *UpsertResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpsertResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpsertResult proto.InternalMessageInfo
+
+func (m *UpsertResult) GetIndex() string {
+	if m != nil {
+		return m.Index
+	}
+	return ""
+}
+
+func (m *UpsertResult) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *UpsertResult) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *UpsertResult) GetResult() string {
+	if m != nil {
+		return m.Result
+	}
+	return ""
+}
+
+type AccessKey struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description          string   `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Key                  string   `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Active               bool     `protobuf:"varint,4,opt,name=active,proto3" json:"active,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AccessKey) Reset()         { *m = AccessKey{} }
+func (m *AccessKey) String() string { return proto.CompactTextString(m) }
+func (*AccessKey) ProtoMessage()    {}
+func (*AccessKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{5}
+}
+
+func (m *AccessKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccessKey.Unmarshal(m, b)
+}
+func (m *AccessKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccessKey.Marshal(b, m, deterministic)
+}
+func (m *AccessKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccessKey.Merge(m, src)
+}
+func (m *AccessKey) XXX_Size() int {
+	return xxx_messageInfo_AccessKey.Size(m)
+}
+func (m *AccessKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccessKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AccessKey proto.InternalMessageInfo
+
+func (m *AccessKey) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *AccessKey) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *AccessKey) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *AccessKey) GetActive() bool {
+	if m != nil {
+		return m.Active
+	}
+	return false
+}
+
+type LdapGroupMapping struct {
+	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Sections             []string `protobuf:"bytes,2,rep,name=sections,proto3" json:"sections,omitempty"`
+	SectionsAll          bool     `protobuf:"varint,3,opt,name=sections_all,json=sectionsAll,proto3" json:"sections_all,omitempty"`
+	Admin                bool     `protobuf:"varint,4,opt,name=admin,proto3" json:"admin,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LdapGroupMapping) Reset()         { *m = LdapGroupMapping{} }
+func (m *LdapGroupMapping) String() string { return proto.CompactTextString(m) }
+func (*LdapGroupMapping) ProtoMessage()    {}
+func (*LdapGroupMapping) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{6}
+}
+
+func (m *LdapGroupMapping) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LdapGroupMapping.Unmarshal(m, b)
+}
+func (m *LdapGroupMapping) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LdapGroupMapping.Marshal(b, m, deterministic)
+}
+func (m *LdapGroupMapping) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LdapGroupMapping.Merge(m, src)
+}
+func (m *LdapGroupMapping) XXX_Size() int {
+	return xxx_messageInfo_LdapGroupMapping.Size(m)
+}
+func (m *LdapGroupMapping) XXX_DiscardUnknown() {
+	xxx_messageInfo_LdapGroupMapping.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LdapGroupMapping proto.InternalMessageInfo
+
+func (m *LdapGroupMapping) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *LdapGroupMapping) GetSections() []string {
+	if m != nil {
+		return m.Sections
+	}
+	return nil
+}
+
+func (m *LdapGroupMapping) GetSectionsAll() bool {
+	if m != nil {
+		return m.SectionsAll
+	}
+	return false
+}
+
+func (m *LdapGroupMapping) GetAdmin() bool {
+	if m != nil {
+		return m.Admin
+	}
+	return false
+}
+
+type LdapCfg struct {
+	Url                  string              `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	StartTls             bool                `protobuf:"varint,2,opt,name=start_tls,json=startTls,proto3" json:"start_tls,omitempty"`
+	InsecureSkipVerify   bool                `protobuf:"varint,3,opt,name=insecure_skip_verify,json=insecureSkipVerify,proto3" json:"insecure_skip_verify,omitempty"`
+	Timeout              int32               `protobuf:"varint,4,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	BindDn               string              `protobuf:"bytes,5,opt,name=bind_dn,json=bindDn,proto3" json:"bind_dn,omitempty"`
+	GroupBaseDn          string              `protobuf:"bytes,6,opt,name=group_base_dn,json=groupBaseDn,proto3" json:"group_base_dn,omitempty"`
+	GroupFilter          string              `protobuf:"bytes,7,opt,name=group_filter,json=groupFilter,proto3" json:"group_filter,omitempty"`
+	GroupAttr            string              `protobuf:"bytes,8,opt,name=group_attr,json=groupAttr,proto3" json:"group_attr,omitempty"`
+	GroupMappings        []*LdapGroupMapping `protobuf:"bytes,9,rep,name=group_mappings,json=groupMappings,proto3" json:"group_mappings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *LdapCfg) Reset()         { *m = LdapCfg{} }
+func (m *LdapCfg) String() string { return proto.CompactTextString(m) }
+func (*LdapCfg) ProtoMessage()    {}
+func (*LdapCfg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{7}
+}
+
+func (m *LdapCfg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LdapCfg.Unmarshal(m, b)
+}
+func (m *LdapCfg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LdapCfg.Marshal(b, m, deterministic)
+}
+func (m *LdapCfg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LdapCfg.Merge(m, src)
+}
+func (m *LdapCfg) XXX_Size() int {
+	return xxx_messageInfo_LdapCfg.Size(m)
+}
+func (m *LdapCfg) XXX_DiscardUnknown() {
+	xxx_messageInfo_LdapCfg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LdapCfg proto.InternalMessageInfo
+
+func (m *LdapCfg) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *LdapCfg) GetStartTls() bool {
+	if m != nil {
+		return m.StartTls
+	}
+	return false
+}
+
+func (m *LdapCfg) GetInsecureSkipVerify() bool {
+	if m != nil {
+		return m.InsecureSkipVerify
+	}
+	return false
+}
+
+func (m *LdapCfg) GetTimeout() int32 {
+	if m != nil {
+		return m.Timeout
+	}
+	return 0
+}
+
+func (m *LdapCfg) GetBindDn() string {
+	if m != nil {
+		return m.BindDn
+	}
+	return ""
+}
+
+func (m *LdapCfg) GetGroupBaseDn() string {
+	if m != nil {
+		return m.GroupBaseDn
+	}
+	return ""
+}
+
+func (m *LdapCfg) GetGroupFilter() string {
+	if m != nil {
+		return m.GroupFilter
+	}
+	return ""
+}
+
+func (m *LdapCfg) GetGroupAttr() string {
+	if m != nil {
+		return m.GroupAttr
+	}
+	return ""
+}
+
+func (m *LdapCfg) GetGroupMappings() []*LdapGroupMapping {
+	if m != nil {
+		return m.GroupMappings
+	}
+	return nil
+}
+
+type AuthCfg struct {
+	Type                 string   `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Ldap                 *LdapCfg `protobuf:"bytes,2,opt,name=ldap,proto3" json:"ldap,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuthCfg) Reset()         { *m = AuthCfg{} }
+func (m *AuthCfg) String() string { return proto.CompactTextString(m) }
+func (*AuthCfg) ProtoMessage()    {}
+func (*AuthCfg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{8}
+}
+
+func (m *AuthCfg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthCfg.Unmarshal(m, b)
+}
+func (m *AuthCfg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthCfg.Marshal(b, m, deterministic)
+}
+func (m *AuthCfg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthCfg.Merge(m, src)
+}
+func (m *AuthCfg) XXX_Size() int {
+	return xxx_messageInfo_AuthCfg.Size(m)
+}
+func (m *AuthCfg) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthCfg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuthCfg proto.InternalMessageInfo
+
+func (m *AuthCfg) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *AuthCfg) GetLdap() *LdapCfg {
+	if m != nil {
+		return m.Ldap
+	}
+	return nil
+}
+
+type Account struct {
+	Id                   string       `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Parent               string       `protobuf:"bytes,2,opt,name=parent,proto3" json:"parent,omitempty"`
+	Description          string       `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	DisplayName          string       `protobuf:"bytes,4,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Active               bool         `protobuf:"varint,5,opt,name=active,proto3" json:"active,omitempty"`
+	Modules              []string     `protobuf:"bytes,6,rep,name=modules,proto3" json:"modules,omitempty"`
+	OrgId                int32        `protobuf:"varint,7,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	AccessKeys           []*AccessKey `protobuf:"bytes,8,rep,name=access_keys,json=accessKeys,proto3" json:"access_keys,omitempty"`
+	Auth                 *AuthCfg     `protobuf:"bytes,9,opt,name=auth,proto3" json:"auth,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Account) Reset()         { *m = Account{} }
+func (m *Account) String() string { return proto.CompactTextString(m) }
+func (*Account) ProtoMessage()    {}
+func (*Account) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{9}
+}
+
+func (m *Account) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Account.Unmarshal(m, b)
+}
+func (m *Account) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Account.Marshal(b, m, deterministic)
+}
+func (m *Account) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Account.Merge(m, src)
+}
+func (m *Account) XXX_Size() int {
+	return xxx_messageInfo_Account.Size(m)
+}
+func (m *Account) XXX_DiscardUnknown() {
+	xxx_messageInfo_Account.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Account proto.InternalMessageInfo
+
+func (m *Account) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Account) GetParent() string {
+	if m != nil {
+		return m.Parent
+	}
+	return ""
+}
+
+func (m *Account) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *Account) GetDisplayName() string {
+	if m != nil {
+		return m.DisplayName
+	}
+	return ""
+}
+
+func (m *Account) GetActive() bool {
+	if m != nil {
+		return m.Active
+	}
+	return false
+}
+
+func (m *Account) GetModules() []string {
+	if m != nil {
+		return m.Modules
+	}
+	return nil
+}
+
+func (m *Account) GetOrgId() int32 {
+	if m != nil {
+		return m.OrgId
+	}
+	return 0
+}
+
+func (m *Account) GetAccessKeys() []*AccessKey {
+	if m != nil {
+		return m.AccessKeys
+	}
+	return nil
+}
+
+func (m *Account) GetAuth() *AuthCfg {
+	if m != nil {
+		return m.Auth
+	}
+	return nil
+}
+
+type AccountResult struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version              int64    `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Found                bool     `protobuf:"varint,3,opt,name=found,proto3" json:"found,omitempty"`
+	Source               *Account `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AccountResult) Reset()         { *m = AccountResult{} }
+func (m *AccountResult) String() string { return proto.CompactTextString(m) }
+func (*AccountResult) ProtoMessage()    {}
+func (*AccountResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{10}
+}
+
+func (m *AccountResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccountResult.Unmarshal(m, b)
+}
+func (m *AccountResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccountResult.Marshal(b, m, deterministic)
+}
+func (m *AccountResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccountResult.Merge(m, src)
+}
+func (m *AccountResult) XXX_Size() int {
+	return xxx_messageInfo_AccountResult.Size(m)
+}
+func (m *AccountResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccountResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AccountResult proto.InternalMessageInfo
+
+func (m *AccountResult) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *AccountResult) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *AccountResult) GetFound() bool {
+	if m != nil {
+		return m.Found
+	}
+	return false
+}
+
+func (m *AccountResult) GetSource() *Account {
+	if m != nil {
+		return m.Source
+	}
+	return nil
+}
+
+type AccountSearchResults struct {
+	Total    int64            `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	MaxScore float64          `protobuf:"fixed64,2,opt,name=max_score,json=maxScore,proto3" json:"max_score,omitempty"`
+	Hits     []*AccountResult `protobuf:"bytes,3,rep,name=hits,proto3" json:"hits,omitempty"`
+	// empty on the last page
+	NextCursor           string   `protobuf:"bytes,4,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AccountSearchResults) Reset()         { *m = AccountSearchResults{} }
+func (m *AccountSearchResults) String() string { return proto.CompactTextString(m) }
+func (*AccountSearchResults) ProtoMessage()    {}
+func (*AccountSearchResults) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{11}
+}
+
+func (m *AccountSearchResults) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccountSearchResults.Unmarshal(m, b)
+}
+func (m *AccountSearchResults) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccountSearchResults.Marshal(b, m, deterministic)
+}
+func (m *AccountSearchResults) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccountSearchResults.Merge(m, src)
+}
+func (m *AccountSearchResults) XXX_Size() int {
+	return xxx_messageInfo_AccountSearchResults.Size(m)
+}
+func (m *AccountSearchResults) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccountSearchResults.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AccountSearchResults proto.InternalMessageInfo
+
+func (m *AccountSearchResults) GetTotal() int64 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+func (m *AccountSearchResults) GetMaxScore() float64 {
+	if m != nil {
+		return m.MaxScore
+	}
+	return 0
+}
+
+func (m *AccountSearchResults) GetHits() []*AccountResult {
+	if m != nil {
+		return m.Hits
+	}
+	return nil
+}
+
+func (m *AccountSearchResults) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+type CheckKeyRequest struct {
+	Account              string     `protobuf:"bytes,1,opt,name=account,proto3" json:"account,omitempty"`
+	Key                  *AccessKey `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *CheckKeyRequest) Reset()         { *m = CheckKeyRequest{} }
+func (m *CheckKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*CheckKeyRequest) ProtoMessage()    {}
+func (*CheckKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{12}
+}
+
+func (m *CheckKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckKeyRequest.Unmarshal(m, b)
+}
+func (m *CheckKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckKeyRequest.Marshal(b, m, deterministic)
+}
+func (m *CheckKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckKeyRequest.Merge(m, src)
+}
+func (m *CheckKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_CheckKeyRequest.Size(m)
+}
+func (m *CheckKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckKeyRequest proto.InternalMessageInfo
+
+func (m *CheckKeyRequest) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *CheckKeyRequest) GetKey() *AccessKey {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+type CheckKeyResult struct {
+	Valid                bool     `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CheckKeyResult) Reset()         { *m = CheckKeyResult{} }
+func (m *CheckKeyResult) String() string { return proto.CompactTextString(m) }
+func (*CheckKeyResult) ProtoMessage()    {}
+func (*CheckKeyResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{13}
+}
+
+func (m *CheckKeyResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckKeyResult.Unmarshal(m, b)
+}
+func (m *CheckKeyResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckKeyResult.Marshal(b, m, deterministic)
+}
+func (m *CheckKeyResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckKeyResult.Merge(m, src)
+}
+func (m *CheckKeyResult) XXX_Size() int {
+	return xxx_messageInfo_CheckKeyResult.Size(m)
+}
+func (m *CheckKeyResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckKeyResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckKeyResult proto.InternalMessageInfo
+
+func (m *CheckKeyResult) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
+type ApiToken struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Hash                 string   `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Created              int64    `protobuf:"varint,3,opt,name=created,proto3" json:"created,omitempty"`
+	Expires              int64    `protobuf:"varint,4,opt,name=expires,proto3" json:"expires,omitempty"`
+	Sections             []string `protobuf:"bytes,5,rep,name=sections,proto3" json:"sections,omitempty"`
+	Accounts             []string `protobuf:"bytes,6,rep,name=accounts,proto3" json:"accounts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ApiToken) Reset()         { *m = ApiToken{} }
+func (m *ApiToken) String() string { return proto.CompactTextString(m) }
+func (*ApiToken) ProtoMessage()    {}
+func (*ApiToken) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{14}
+}
+
+func (m *ApiToken) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ApiToken.Unmarshal(m, b)
+}
+func (m *ApiToken) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ApiToken.Marshal(b, m, deterministic)
+}
+func (m *ApiToken) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ApiToken.Merge(m, src)
+}
+func (m *ApiToken) XXX_Size() int {
+	return xxx_messageInfo_ApiToken.Size(m)
+}
+func (m *ApiToken) XXX_DiscardUnknown() {
+	xxx_messageInfo_ApiToken.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ApiToken proto.InternalMessageInfo
+
+func (m *ApiToken) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ApiToken) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+func (m *ApiToken) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *ApiToken) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+func (m *ApiToken) GetSections() []string {
+	if m != nil {
+		return m.Sections
+	}
+	return nil
+}
+
+func (m *ApiToken) GetAccounts() []string {
+	if m != nil {
+		return m.Accounts
+	}
+	return nil
+}
+
+type AccountRoles struct {
+	Account              string   `protobuf:"bytes,1,opt,name=account,proto3" json:"account,omitempty"`
+	Roles                []string `protobuf:"bytes,2,rep,name=roles,proto3" json:"roles,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AccountRoles) Reset()         { *m = AccountRoles{} }
+func (m *AccountRoles) String() string { return proto.CompactTextString(m) }
+func (*AccountRoles) ProtoMessage()    {}
+func (*AccountRoles) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{15}
+}
+
+func (m *AccountRoles) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccountRoles.Unmarshal(m, b)
+}
+func (m *AccountRoles) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccountRoles.Marshal(b, m, deterministic)
+}
+func (m *AccountRoles) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccountRoles.Merge(m, src)
+}
+func (m *AccountRoles) XXX_Size() int {
+	return xxx_messageInfo_AccountRoles.Size(m)
+}
+func (m *AccountRoles) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccountRoles.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AccountRoles proto.InternalMessageInfo
+
+func (m *AccountRoles) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *AccountRoles) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+type Membership struct {
+	Account              string   `protobuf:"bytes,1,opt,name=account,proto3" json:"account,omitempty"`
+	Sections             []string `protobuf:"bytes,2,rep,name=sections,proto3" json:"sections,omitempty"`
+	SectionsAll          bool     `protobuf:"varint,3,opt,name=sections_all,json=sectionsAll,proto3" json:"sections_all,omitempty"`
+	DenySections         []string `protobuf:"bytes,4,rep,name=deny_sections,json=denySections,proto3" json:"deny_sections,omitempty"`
+	Roles                []string `protobuf:"bytes,5,rep,name=roles,proto3" json:"roles,omitempty"`
+	Admin                bool     `protobuf:"varint,6,opt,name=admin,proto3" json:"admin,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Membership) Reset()         { *m = Membership{} }
+func (m *Membership) String() string { return proto.CompactTextString(m) }
+func (*Membership) ProtoMessage()    {}
+func (*Membership) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{16}
+}
+
+func (m *Membership) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Membership.Unmarshal(m, b)
+}
+func (m *Membership) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Membership.Marshal(b, m, deterministic)
+}
+func (m *Membership) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Membership.Merge(m, src)
+}
+func (m *Membership) XXX_Size() int {
+	return xxx_messageInfo_Membership.Size(m)
+}
+func (m *Membership) XXX_DiscardUnknown() {
+	xxx_messageInfo_Membership.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Membership proto.InternalMessageInfo
+
+func (m *Membership) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *Membership) GetSections() []string {
+	if m != nil {
+		return m.Sections
+	}
+	return nil
+}
+
+func (m *Membership) GetSectionsAll() bool {
+	if m != nil {
+		return m.SectionsAll
+	}
+	return false
+}
+
+func (m *Membership) GetDenySections() []string {
+	if m != nil {
+		return m.DenySections
+	}
+	return nil
+}
+
+func (m *Membership) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+func (m *Membership) GetAdmin() bool {
+	if m != nil {
+		return m.Admin
+	}
+	return false
+}
+
+type RoleGrant struct {
+	Account              string   `protobuf:"bytes,1,opt,name=account,proto3" json:"account,omitempty"`
+	Sections             []string `protobuf:"bytes,2,rep,name=sections,proto3" json:"sections,omitempty"`
+	SectionsAll          bool     `protobuf:"varint,3,opt,name=sections_all,json=sectionsAll,proto3" json:"sections_all,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RoleGrant) Reset()         { *m = RoleGrant{} }
+func (m *RoleGrant) String() string { return proto.CompactTextString(m) }
+func (*RoleGrant) ProtoMessage()    {}
+func (*RoleGrant) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{17}
+}
+
+func (m *RoleGrant) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RoleGrant.Unmarshal(m, b)
+}
+func (m *RoleGrant) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RoleGrant.Marshal(b, m, deterministic)
+}
+func (m *RoleGrant) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RoleGrant.Merge(m, src)
+}
+func (m *RoleGrant) XXX_Size() int {
+	return xxx_messageInfo_RoleGrant.Size(m)
+}
+func (m *RoleGrant) XXX_DiscardUnknown() {
+	xxx_messageInfo_RoleGrant.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RoleGrant proto.InternalMessageInfo
+
+func (m *RoleGrant) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *RoleGrant) GetSections() []string {
+	if m != nil {
+		return m.Sections
+	}
+	return nil
+}
+
+func (m *RoleGrant) GetSectionsAll() bool {
+	if m != nil {
+		return m.SectionsAll
+	}
+	return false
+}
+
+type User struct {
+	Id                   string          `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Description          string          `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	DisplayName          string          `protobuf:"bytes,3,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Name                 string          `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	Email                string          `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
+	EmailVerified        bool            `protobuf:"varint,6,opt,name=email_verified,json=emailVerified,proto3" json:"email_verified,omitempty"`
+	Picture              string          `protobuf:"bytes,7,opt,name=picture,proto3" json:"picture,omitempty"`
+	Active               bool            `protobuf:"varint,8,opt,name=active,proto3" json:"active,omitempty"`
+	Sysop                bool            `protobuf:"varint,9,opt,name=sysop,proto3" json:"sysop,omitempty"`
+	Password             string          `protobuf:"bytes,10,opt,name=password,proto3" json:"password,omitempty"`
+	Sections             []string        `protobuf:"bytes,11,rep,name=sections,proto3" json:"sections,omitempty"`
+	SectionsAll          bool            `protobuf:"varint,12,opt,name=sections_all,json=sectionsAll,proto3" json:"sections_all,omitempty"`
+	DenySections         []string        `protobuf:"bytes,13,rep,name=deny_sections,json=denySections,proto3" json:"deny_sections,omitempty"`
+	Accounts             []string        `protobuf:"bytes,14,rep,name=accounts,proto3" json:"accounts,omitempty"`
+	AdminAccounts        []string        `protobuf:"bytes,15,rep,name=admin_accounts,json=adminAccounts,proto3" json:"admin_accounts,omitempty"`
+	Epoch                int64           `protobuf:"varint,16,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	ApiTokens            []*ApiToken     `protobuf:"bytes,17,rep,name=api_tokens,json=apiTokens,proto3" json:"api_tokens,omitempty"`
+	Identities           []string        `protobuf:"bytes,18,rep,name=identities,proto3" json:"identities,omitempty"`
+	Roles                []*AccountRoles `protobuf:"bytes,19,rep,name=roles,proto3" json:"roles,omitempty"`
+	Memberships          []*Membership   `protobuf:"bytes,20,rep,name=memberships,proto3" json:"memberships,omitempty"`
+	RoleGrants           []*RoleGrant    `protobuf:"bytes,21,rep,name=role_grants,json=roleGrants,proto3" json:"role_grants,omitempty"`
+	GroupGrants          []*Membership   `protobuf:"bytes,22,rep,name=group_grants,json=groupGrants,proto3" json:"group_grants,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *User) Reset()         { *m = User{} }
+func (m *User) String() string { return proto.CompactTextString(m) }
+func (*User) ProtoMessage()    {}
+func (*User) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{18}
+}
+
+func (m *User) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_User.Unmarshal(m, b)
+}
+func (m *User) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_User.Marshal(b, m, deterministic)
+}
+func (m *User) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_User.Merge(m, src)
+}
+func (m *User) XXX_Size() int {
+	return xxx_messageInfo_User.Size(m)
+}
+func (m *User) XXX_DiscardUnknown() {
+	xxx_messageInfo_User.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_User proto.InternalMessageInfo
+
+func (m *User) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *User) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *User) GetDisplayName() string {
+	if m != nil {
+		return m.DisplayName
+	}
+	return ""
+}
+
+func (m *User) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *User) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *User) GetEmailVerified() bool {
+	if m != nil {
+		return m.EmailVerified
+	}
+	return false
+}
+
+func (m *User) GetPicture() string {
+	if m != nil {
+		return m.Picture
+	}
+	return ""
+}
+
+func (m *User) GetActive() bool {
+	if m != nil {
+		return m.Active
+	}
+	return false
+}
+
+func (m *User) GetSysop() bool {
+	if m != nil {
+		return m.Sysop
+	}
+	return false
+}
+
+func (m *User) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func (m *User) GetSections() []string {
+	if m != nil {
+		return m.Sections
+	}
+	return nil
+}
+
+func (m *User) GetSectionsAll() bool {
+	if m != nil {
+		return m.SectionsAll
+	}
+	return false
+}
+
+func (m *User) GetDenySections() []string {
+	if m != nil {
+		return m.DenySections
+	}
+	return nil
+}
+
+func (m *User) GetAccounts() []string {
+	if m != nil {
+		return m.Accounts
+	}
+	return nil
+}
+
+func (m *User) GetAdminAccounts() []string {
+	if m != nil {
+		return m.AdminAccounts
+	}
+	return nil
+}
+
+func (m *User) GetEpoch() int64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *User) GetApiTokens() []*ApiToken {
+	if m != nil {
+		return m.ApiTokens
+	}
+	return nil
+}
+
+func (m *User) GetIdentities() []string {
+	if m != nil {
+		return m.Identities
+	}
+	return nil
+}
+
+func (m *User) GetRoles() []*AccountRoles {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+func (m *User) GetMemberships() []*Membership {
+	if m != nil {
+		return m.Memberships
+	}
+	return nil
+}
+
+func (m *User) GetRoleGrants() []*RoleGrant {
+	if m != nil {
+		return m.RoleGrants
+	}
+	return nil
+}
+
+func (m *User) GetGroupGrants() []*Membership {
+	if m != nil {
+		return m.GroupGrants
+	}
+	return nil
+}
+
+type UserResult struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version              int64    `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Found                bool     `protobuf:"varint,3,opt,name=found,proto3" json:"found,omitempty"`
+	Source               *User    `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserResult) Reset()         { *m = UserResult{} }
+func (m *UserResult) String() string { return proto.CompactTextString(m) }
+func (*UserResult) ProtoMessage()    {}
+func (*UserResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{19}
+}
+
+func (m *UserResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserResult.Unmarshal(m, b)
+}
+func (m *UserResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserResult.Marshal(b, m, deterministic)
+}
+func (m *UserResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserResult.Merge(m, src)
+}
+func (m *UserResult) XXX_Size() int {
+	return xxx_messageInfo_UserResult.Size(m)
+}
+func (m *UserResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserResult proto.InternalMessageInfo
+
+func (m *UserResult) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *UserResult) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *UserResult) GetFound() bool {
+	if m != nil {
+		return m.Found
+	}
+	return false
+}
+
+func (m *UserResult) GetSource() *User {
+	if m != nil {
+		return m.Source
+	}
+	return nil
+}
+
+type UserSearchResults struct {
+	Total    int64         `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	MaxScore float64       `protobuf:"fixed64,2,opt,name=max_score,json=maxScore,proto3" json:"max_score,omitempty"`
+	Hits     []*UserResult `protobuf:"bytes,3,rep,name=hits,proto3" json:"hits,omitempty"`
+	// empty on the last page
+	NextCursor           string   `protobuf:"bytes,4,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserSearchResults) Reset()         { *m = UserSearchResults{} }
+func (m *UserSearchResults) String() string { return proto.CompactTextString(m) }
+func (*UserSearchResults) ProtoMessage()    {}
+func (*UserSearchResults) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{20}
+}
+
+func (m *UserSearchResults) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserSearchResults.Unmarshal(m, b)
+}
+func (m *UserSearchResults) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserSearchResults.Marshal(b, m, deterministic)
+}
+func (m *UserSearchResults) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserSearchResults.Merge(m, src)
+}
+func (m *UserSearchResults) XXX_Size() int {
+	return xxx_messageInfo_UserSearchResults.Size(m)
+}
+func (m *UserSearchResults) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserSearchResults.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserSearchResults proto.InternalMessageInfo
+
+func (m *UserSearchResults) GetTotal() int64 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+func (m *UserSearchResults) GetMaxScore() float64 {
+	if m != nil {
+		return m.MaxScore
+	}
+	return 0
+}
+
+func (m *UserSearchResults) GetHits() []*UserResult {
+	if m != nil {
+		return m.Hits
+	}
+	return nil
+}
+
+func (m *UserSearchResults) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+type Condition struct {
+	Parser               string   `protobuf:"bytes,1,opt,name=parser,proto3" json:"parser,omitempty"`
+	Condition            string   `protobuf:"bytes,2,opt,name=condition,proto3" json:"condition,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Condition) Reset()         { *m = Condition{} }
+func (m *Condition) String() string { return proto.CompactTextString(m) }
+func (*Condition) ProtoMessage()    {}
+func (*Condition) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{21}
+}
+
+func (m *Condition) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Condition.Unmarshal(m, b)
+}
+func (m *Condition) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Condition.Marshal(b, m, deterministic)
+}
+func (m *Condition) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Condition.Merge(m, src)
+}
+func (m *Condition) XXX_Size() int {
+	return xxx_messageInfo_Condition.Size(m)
+}
+func (m *Condition) XXX_DiscardUnknown() {
+	xxx_messageInfo_Condition.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Condition proto.InternalMessageInfo
+
+func (m *Condition) GetParser() string {
+	if m != nil {
+		return m.Parser
+	}
+	return ""
+}
+
+func (m *Condition) GetCondition() string {
+	if m != nil {
+		return m.Condition
+	}
+	return ""
+}
+
+type Route struct {
+	AccountId            string       `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	ModelId              string       `protobuf:"bytes,2,opt,name=model_id,json=modelId,proto3" json:"model_id,omitempty"`
+	Type                 string       `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Conditions           []*Condition `protobuf:"bytes,4,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Route) Reset()         { *m = Route{} }
+func (m *Route) String() string { return proto.CompactTextString(m) }
+func (*Route) ProtoMessage()    {}
+func (*Route) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{22}
+}
+
+func (m *Route) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Route.Unmarshal(m, b)
+}
+func (m *Route) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Route.Marshal(b, m, deterministic)
+}
+func (m *Route) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Route.Merge(m, src)
+}
+func (m *Route) XXX_Size() int {
+	return xxx_messageInfo_Route.Size(m)
+}
+func (m *Route) XXX_DiscardUnknown() {
+	xxx_messageInfo_Route.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Route proto.InternalMessageInfo
+
+func (m *Route) GetAccountId() string {
+	if m != nil {
+		return m.AccountId
+	}
+	return ""
+}
+
+func (m *Route) GetModelId() string {
+	if m != nil {
+		return m.ModelId
+	}
+	return ""
+}
+
+func (m *Route) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Route) GetConditions() []*Condition {
+	if m != nil {
+		return m.Conditions
+	}
+	return nil
+}
+
+type Asset struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	AccountId            string   `protobuf:"bytes,2,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Description          string   `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	DisplayName          string   `protobuf:"bytes,4,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	AssetClass           string   `protobuf:"bytes,5,opt,name=asset_class,json=assetClass,proto3" json:"asset_class,omitempty"`
+	AssetCfg             string   `protobuf:"bytes,6,opt,name=asset_cfg,json=assetCfg,proto3" json:"asset_cfg,omitempty"`
+	Active               bool     `protobuf:"varint,7,opt,name=active,proto3" json:"active,omitempty"`
+	Routes               []*Route `protobuf:"bytes,8,rep,name=routes,proto3" json:"routes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Asset) Reset()         { *m = Asset{} }
+func (m *Asset) String() string { return proto.CompactTextString(m) }
+func (*Asset) ProtoMessage()    {}
+func (*Asset) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{23}
+}
+
+func (m *Asset) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Asset.Unmarshal(m, b)
+}
+func (m *Asset) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Asset.Marshal(b, m, deterministic)
+}
+func (m *Asset) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Asset.Merge(m, src)
+}
+func (m *Asset) XXX_Size() int {
+	return xxx_messageInfo_Asset.Size(m)
+}
+func (m *Asset) XXX_DiscardUnknown() {
+	xxx_messageInfo_Asset.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Asset proto.InternalMessageInfo
+
+func (m *Asset) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Asset) GetAccountId() string {
+	if m != nil {
+		return m.AccountId
+	}
+	return ""
+}
+
+func (m *Asset) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *Asset) GetDisplayName() string {
+	if m != nil {
+		return m.DisplayName
+	}
+	return ""
+}
+
+func (m *Asset) GetAssetClass() string {
+	if m != nil {
+		return m.AssetClass
+	}
+	return ""
+}
+
+func (m *Asset) GetAssetCfg() string {
+	if m != nil {
+		return m.AssetCfg
+	}
+	return ""
+}
+
+func (m *Asset) GetActive() bool {
+	if m != nil {
+		return m.Active
+	}
+	return false
+}
+
+func (m *Asset) GetRoutes() []*Route {
+	if m != nil {
+		return m.Routes
+	}
+	return nil
+}
+
+type AssetResult struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version              int64    `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Found                bool     `protobuf:"varint,3,opt,name=found,proto3" json:"found,omitempty"`
+	Source               *Asset   `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AssetResult) Reset()         { *m = AssetResult{} }
+func (m *AssetResult) String() string { return proto.CompactTextString(m) }
+func (*AssetResult) ProtoMessage()    {}
+func (*AssetResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{24}
+}
+
+func (m *AssetResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AssetResult.Unmarshal(m, b)
+}
+func (m *AssetResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AssetResult.Marshal(b, m, deterministic)
+}
+func (m *AssetResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AssetResult.Merge(m, src)
+}
+func (m *AssetResult) XXX_Size() int {
+	return xxx_messageInfo_AssetResult.Size(m)
+}
+func (m *AssetResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_AssetResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AssetResult proto.InternalMessageInfo
+
+func (m *AssetResult) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *AssetResult) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *AssetResult) GetFound() bool {
+	if m != nil {
+		return m.Found
+	}
+	return false
+}
+
+func (m *AssetResult) GetSource() *Asset {
+	if m != nil {
+		return m.Source
+	}
+	return nil
+}
+
+type AssetSearchResults struct {
+	Total    int64          `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	MaxScore float64        `protobuf:"fixed64,2,opt,name=max_score,json=maxScore,proto3" json:"max_score,omitempty"`
+	Hits     []*AssetResult `protobuf:"bytes,3,rep,name=hits,proto3" json:"hits,omitempty"`
+	// empty on the last page
+	NextCursor           string   `protobuf:"bytes,4,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AssetSearchResults) Reset()         { *m = AssetSearchResults{} }
+func (m *AssetSearchResults) String() string { return proto.CompactTextString(m) }
+func (*AssetSearchResults) ProtoMessage()    {}
+func (*AssetSearchResults) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{25}
+}
+
+func (m *AssetSearchResults) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AssetSearchResults.Unmarshal(m, b)
+}
+func (m *AssetSearchResults) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AssetSearchResults.Marshal(b, m, deterministic)
+}
+func (m *AssetSearchResults) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AssetSearchResults.Merge(m, src)
+}
+func (m *AssetSearchResults) XXX_Size() int {
+	return xxx_messageInfo_AssetSearchResults.Size(m)
+}
+func (m *AssetSearchResults) XXX_DiscardUnknown() {
+	xxx_messageInfo_AssetSearchResults.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AssetSearchResults proto.InternalMessageInfo
+
+func (m *AssetSearchResults) GetTotal() int64 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+func (m *AssetSearchResults) GetMaxScore() float64 {
+	if m != nil {
+		return m.MaxScore
+	}
+	return 0
+}
+
+func (m *AssetSearchResults) GetHits() []*AssetResult {
+	if m != nil {
+		return m.Hits
+	}
+	return nil
+}
+
+func (m *AssetSearchResults) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+type AccessCheck struct {
+	Sections             []string `protobuf:"bytes,1,rep,name=sections,proto3" json:"sections,omitempty"`
+	Accounts             []string `protobuf:"bytes,2,rep,name=accounts,proto3" json:"accounts,omitempty"`
+	Mode                 string   `protobuf:"bytes,3,opt,name=mode,proto3" json:"mode,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AccessCheck) Reset()         { *m = AccessCheck{} }
+func (m *AccessCheck) String() string { return proto.CompactTextString(m) }
+func (*AccessCheck) ProtoMessage()    {}
+func (*AccessCheck) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{26}
+}
+
+func (m *AccessCheck) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccessCheck.Unmarshal(m, b)
+}
+func (m *AccessCheck) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccessCheck.Marshal(b, m, deterministic)
+}
+func (m *AccessCheck) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccessCheck.Merge(m, src)
+}
+func (m *AccessCheck) XXX_Size() int {
+	return xxx_messageInfo_AccessCheck.Size(m)
+}
+func (m *AccessCheck) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccessCheck.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AccessCheck proto.InternalMessageInfo
+
+func (m *AccessCheck) GetSections() []string {
+	if m != nil {
+		return m.Sections
+	}
+	return nil
+}
+
+func (m *AccessCheck) GetAccounts() []string {
+	if m != nil {
+		return m.Accounts
+	}
+	return nil
+}
+
+func (m *AccessCheck) GetMode() string {
+	if m != nil {
+		return m.Mode
+	}
+	return ""
+}
+
+type AccessCheckRequest struct {
+	Check *AccessCheck `protobuf:"bytes,1,opt,name=check,proto3" json:"check,omitempty"`
+	// include the policy trace
+	Explain              bool     `protobuf:"varint,2,opt,name=explain,proto3" json:"explain,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AccessCheckRequest) Reset()         { *m = AccessCheckRequest{} }
+func (m *AccessCheckRequest) String() string { return proto.CompactTextString(m) }
+func (*AccessCheckRequest) ProtoMessage()    {}
+func (*AccessCheckRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{27}
+}
+
+func (m *AccessCheckRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccessCheckRequest.Unmarshal(m, b)
+}
+func (m *AccessCheckRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccessCheckRequest.Marshal(b, m, deterministic)
+}
+func (m *AccessCheckRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccessCheckRequest.Merge(m, src)
+}
+func (m *AccessCheckRequest) XXX_Size() int {
+	return xxx_messageInfo_AccessCheckRequest.Size(m)
+}
+func (m *AccessCheckRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccessCheckRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AccessCheckRequest proto.InternalMessageInfo
+
+func (m *AccessCheckRequest) GetCheck() *AccessCheck {
+	if m != nil {
+		return m.Check
+	}
+	return nil
+}
+
+func (m *AccessCheckRequest) GetExplain() bool {
+	if m != nil {
+		return m.Explain
+	}
+	return false
+}
+
+type PolicyDecision struct {
+	Allowed              bool     `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Rule                 string   `protobuf:"bytes,2,opt,name=rule,proto3" json:"rule,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PolicyDecision) Reset()         { *m = PolicyDecision{} }
+func (m *PolicyDecision) String() string { return proto.CompactTextString(m) }
+func (*PolicyDecision) ProtoMessage()    {}
+func (*PolicyDecision) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{28}
+}
+
+func (m *PolicyDecision) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PolicyDecision.Unmarshal(m, b)
+}
+func (m *PolicyDecision) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PolicyDecision.Marshal(b, m, deterministic)
+}
+func (m *PolicyDecision) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PolicyDecision.Merge(m, src)
+}
+func (m *PolicyDecision) XXX_Size() int {
+	return xxx_messageInfo_PolicyDecision.Size(m)
+}
+func (m *PolicyDecision) XXX_DiscardUnknown() {
+	xxx_messageInfo_PolicyDecision.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PolicyDecision proto.InternalMessageInfo
+
+func (m *PolicyDecision) GetAllowed() bool {
+	if m != nil {
+		return m.Allowed
+	}
+	return false
+}
+
+func (m *PolicyDecision) GetRule() string {
+	if m != nil {
+		return m.Rule
+	}
+	return ""
+}
+
+type PolicyTraceDetail struct {
+	Quantifier           string   `protobuf:"bytes,1,opt,name=quantifier,proto3" json:"quantifier,omitempty"`
+	Var                  string   `protobuf:"bytes,2,opt,name=var,proto3" json:"var,omitempty"`
+	Value                string   `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Result               bool     `protobuf:"varint,4,opt,name=result,proto3" json:"result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PolicyTraceDetail) Reset()         { *m = PolicyTraceDetail{} }
+func (m *PolicyTraceDetail) String() string { return proto.CompactTextString(m) }
+func (*PolicyTraceDetail) ProtoMessage()    {}
+func (*PolicyTraceDetail) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{29}
+}
+
+func (m *PolicyTraceDetail) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PolicyTraceDetail.Unmarshal(m, b)
+}
+func (m *PolicyTraceDetail) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PolicyTraceDetail.Marshal(b, m, deterministic)
+}
+func (m *PolicyTraceDetail) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PolicyTraceDetail.Merge(m, src)
+}
+func (m *PolicyTraceDetail) XXX_Size() int {
+	return xxx_messageInfo_PolicyTraceDetail.Size(m)
+}
+func (m *PolicyTraceDetail) XXX_DiscardUnknown() {
+	xxx_messageInfo_PolicyTraceDetail.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PolicyTraceDetail proto.InternalMessageInfo
+
+func (m *PolicyTraceDetail) GetQuantifier() string {
+	if m != nil {
+		return m.Quantifier
+	}
+	return ""
+}
+
+func (m *PolicyTraceDetail) GetVar() string {
+	if m != nil {
+		return m.Var
+	}
+	return ""
+}
+
+func (m *PolicyTraceDetail) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *PolicyTraceDetail) GetResult() bool {
+	if m != nil {
+		return m.Result
+	}
+	return false
+}
+
+type PolicyRuleTrace struct {
+	Rule                 string               `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	Effect               string               `protobuf:"bytes,2,opt,name=effect,proto3" json:"effect,omitempty"`
+	When                 string               `protobuf:"bytes,3,opt,name=when,proto3" json:"when,omitempty"`
+	Matched              bool                 `protobuf:"varint,4,opt,name=matched,proto3" json:"matched,omitempty"`
+	Error                string               `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Details              []*PolicyTraceDetail `protobuf:"bytes,6,rep,name=details,proto3" json:"details,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *PolicyRuleTrace) Reset()         { *m = PolicyRuleTrace{} }
+func (m *PolicyRuleTrace) String() string { return proto.CompactTextString(m) }
+func (*PolicyRuleTrace) ProtoMessage()    {}
+func (*PolicyRuleTrace) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{30}
+}
+
+func (m *PolicyRuleTrace) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PolicyRuleTrace.Unmarshal(m, b)
+}
+func (m *PolicyRuleTrace) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PolicyRuleTrace.Marshal(b, m, deterministic)
+}
+func (m *PolicyRuleTrace) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PolicyRuleTrace.Merge(m, src)
+}
+func (m *PolicyRuleTrace) XXX_Size() int {
+	return xxx_messageInfo_PolicyRuleTrace.Size(m)
+}
+func (m *PolicyRuleTrace) XXX_DiscardUnknown() {
+	xxx_messageInfo_PolicyRuleTrace.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PolicyRuleTrace proto.InternalMessageInfo
+
+func (m *PolicyRuleTrace) GetRule() string {
+	if m != nil {
+		return m.Rule
+	}
+	return ""
+}
+
+func (m *PolicyRuleTrace) GetEffect() string {
+	if m != nil {
+		return m.Effect
+	}
+	return ""
+}
+
+func (m *PolicyRuleTrace) GetWhen() string {
+	if m != nil {
+		return m.When
+	}
+	return ""
+}
+
+func (m *PolicyRuleTrace) GetMatched() bool {
+	if m != nil {
+		return m.Matched
+	}
+	return false
+}
+
+func (m *PolicyRuleTrace) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *PolicyRuleTrace) GetDetails() []*PolicyTraceDetail {
+	if m != nil {
+		return m.Details
+	}
+	return nil
+}
+
+type PolicyExplanation struct {
+	Policy               string             `protobuf:"bytes,1,opt,name=policy,proto3" json:"policy,omitempty"`
+	Check                string             `protobuf:"bytes,2,opt,name=check,proto3" json:"check,omitempty"`
+	Decision             *PolicyDecision    `protobuf:"bytes,3,opt,name=decision,proto3" json:"decision,omitempty"`
+	Rules                []*PolicyRuleTrace `protobuf:"bytes,4,rep,name=rules,proto3" json:"rules,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *PolicyExplanation) Reset()         { *m = PolicyExplanation{} }
+func (m *PolicyExplanation) String() string { return proto.CompactTextString(m) }
+func (*PolicyExplanation) ProtoMessage()    {}
+func (*PolicyExplanation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{31}
+}
+
+func (m *PolicyExplanation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PolicyExplanation.Unmarshal(m, b)
+}
+func (m *PolicyExplanation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PolicyExplanation.Marshal(b, m, deterministic)
+}
+func (m *PolicyExplanation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PolicyExplanation.Merge(m, src)
+}
+func (m *PolicyExplanation) XXX_Size() int {
+	return xxx_messageInfo_PolicyExplanation.Size(m)
+}
+func (m *PolicyExplanation) XXX_DiscardUnknown() {
+	xxx_messageInfo_PolicyExplanation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PolicyExplanation proto.InternalMessageInfo
+
+func (m *PolicyExplanation) GetPolicy() string {
+	if m != nil {
+		return m.Policy
+	}
+	return ""
+}
+
+func (m *PolicyExplanation) GetCheck() string {
+	if m != nil {
+		return m.Check
+	}
+	return ""
+}
+
+func (m *PolicyExplanation) GetDecision() *PolicyDecision {
+	if m != nil {
+		return m.Decision
+	}
+	return nil
+}
+
+func (m *PolicyExplanation) GetRules() []*PolicyRuleTrace {
+	if m != nil {
+		return m.Rules
+	}
+	return nil
+}
+
+// AccessCheckResult, a denied check is a result with status
+// false rather than an error
+type AccessCheckResult struct {
+	AccessChecked        *AccessCheck       `protobuf:"bytes,1,opt,name=access_checked,json=accessChecked,proto3" json:"access_checked,omitempty"`
+	Status               bool               `protobuf:"varint,2,opt,name=status,proto3" json:"status,omitempty"`
+	Message              string             `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Explanation          *PolicyExplanation `protobuf:"bytes,4,opt,name=explanation,proto3" json:"explanation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *AccessCheckResult) Reset()         { *m = AccessCheckResult{} }
+func (m *AccessCheckResult) String() string { return proto.CompactTextString(m) }
+func (*AccessCheckResult) ProtoMessage()    {}
+func (*AccessCheckResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33252b3ed4033f30, []int{32}
+}
+
+func (m *AccessCheckResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccessCheckResult.Unmarshal(m, b)
+}
+func (m *AccessCheckResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccessCheckResult.Marshal(b, m, deterministic)
+}
+func (m *AccessCheckResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccessCheckResult.Merge(m, src)
+}
+func (m *AccessCheckResult) XXX_Size() int {
+	return xxx_messageInfo_AccessCheckResult.Size(m)
+}
+func (m *AccessCheckResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccessCheckResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AccessCheckResult proto.InternalMessageInfo
+
+func (m *AccessCheckResult) GetAccessChecked() *AccessCheck {
+	if m != nil {
+		return m.AccessChecked
+	}
+	return nil
+}
+
+func (m *AccessCheckResult) GetStatus() bool {
+	if m != nil {
+		return m.Status
+	}
+	return false
+}
+
+func (m *AccessCheckResult) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *AccessCheckResult) GetExplanation() *PolicyExplanation {
+	if m != nil {
+		return m.Explanation
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*GetRequest)(nil), "provision.GetRequest")
+	proto.RegisterType((*SearchRequest)(nil), "provision.SearchRequest")
+	proto.RegisterMapType((map[string]*FilterValues)(nil), "provision.SearchRequest.FiltersEntry")
+	proto.RegisterType((*FilterValues)(nil), "provision.FilterValues")
+	proto.RegisterType((*SearchSort)(nil), "provision.SearchSort")
+	proto.RegisterType((*UpsertResult)(nil), "provision.UpsertResult")
+	proto.RegisterType((*AccessKey)(nil), "provision.AccessKey")
+	proto.RegisterType((*LdapGroupMapping)(nil), "provision.LdapGroupMapping")
+	proto.RegisterType((*LdapCfg)(nil), "provision.LdapCfg")
+	proto.RegisterType((*AuthCfg)(nil), "provision.AuthCfg")
+	proto.RegisterType((*Account)(nil), "provision.Account")
+	proto.RegisterType((*AccountResult)(nil), "provision.AccountResult")
+	proto.RegisterType((*AccountSearchResults)(nil), "provision.AccountSearchResults")
+	proto.RegisterType((*CheckKeyRequest)(nil), "provision.CheckKeyRequest")
+	proto.RegisterType((*CheckKeyResult)(nil), "provision.CheckKeyResult")
+	proto.RegisterType((*ApiToken)(nil), "provision.ApiToken")
+	proto.RegisterType((*AccountRoles)(nil), "provision.AccountRoles")
+	proto.RegisterType((*Membership)(nil), "provision.Membership")
+	proto.RegisterType((*RoleGrant)(nil), "provision.RoleGrant")
+	proto.RegisterType((*User)(nil), "provision.User")
+	proto.RegisterType((*UserResult)(nil), "provision.UserResult")
+	proto.RegisterType((*UserSearchResults)(nil), "provision.UserSearchResults")
+	proto.RegisterType((*Condition)(nil), "provision.Condition")
+	proto.RegisterType((*Route)(nil), "provision.Route")
+	proto.RegisterType((*Asset)(nil), "provision.Asset")
+	proto.RegisterType((*AssetResult)(nil), "provision.AssetResult")
+	proto.RegisterType((*AssetSearchResults)(nil), "provision.AssetSearchResults")
+	proto.RegisterType((*AccessCheck)(nil), "provision.AccessCheck")
+	proto.RegisterType((*AccessCheckRequest)(nil), "provision.AccessCheckRequest")
+	proto.RegisterType((*PolicyDecision)(nil), "provision.PolicyDecision")
+	proto.RegisterType((*PolicyTraceDetail)(nil), "provision.PolicyTraceDetail")
+	proto.RegisterType((*PolicyRuleTrace)(nil), "provision.PolicyRuleTrace")
+	proto.RegisterType((*PolicyExplanation)(nil), "provision.PolicyExplanation")
+	proto.RegisterType((*AccessCheckResult)(nil), "provision.AccessCheckResult")
+}
+
+func init() { proto.RegisterFile("provisionpb/provision.proto", fileDescriptor_33252b3ed4033f30) }
+
+var fileDescriptor_33252b3ed4033f30 = []byte{
+	// 2076 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0x4f, 0x8f, 0x1c, 0x47,
+	0x15, 0x57, 0xcf, 0xdf, 0x9e, 0x37, 0x33, 0x6b, 0xbb, 0xb2, 0x76, 0xda, 0x6b, 0x3b, 0xde, 0x34,
+	0xb2, 0xb3, 0x89, 0x82, 0x1d, 0x2d, 0xc4, 0x31, 0x88, 0x18, 0x8d, 0xd7, 0x66, 0xb1, 0x92, 0xa0,
+	0xd0, 0xeb, 0x58, 0x02, 0x21, 0x8d, 0x6a, 0xbb, 0x6b, 0x66, 0x5a, 0xdb, 0xd3, 0xdd, 0xa9, 0xaa,
+	0x5e, 0xef, 0x80, 0xf8, 0x04, 0xdc, 0x10, 0x07, 0x4e, 0xf9, 0x00, 0x5c, 0xb8, 0x21, 0x71, 0x47,
+	0x82, 0xcf, 0x04, 0x17, 0xf4, 0xea, 0x4f, 0x4f, 0x4f, 0xcf, 0xce, 0x1a, 0xcb, 0xe6, 0x56, 0xbf,
+	0xd7, 0xaf, 0xea, 0xfd, 0x7f, 0xf5, 0xaa, 0xe1, 0x46, 0xce, 0xb3, 0xd3, 0x58, 0xc4, 0x59, 0x9a,
+	0x1f, 0xdf, 0x2f, 0xd7, 0xf7, 0x72, 0x9e, 0xc9, 0x8c, 0xf4, 0x4a, 0x82, 0x7f, 0x13, 0xe0, 0x90,
+	0xc9, 0x80, 0x7d, 0x5b, 0x30, 0x21, 0xc9, 0x16, 0x34, 0xe2, 0xc8, 0x73, 0x76, 0x9d, 0xbd, 0x5e,
+	0xd0, 0x88, 0x23, 0xff, 0x5f, 0x0d, 0x18, 0x1e, 0x31, 0xca, 0xc3, 0x99, 0xe5, 0xb8, 0x0c, 0xcd,
+	0x48, 0x24, 0x8a, 0x65, 0x10, 0xe0, 0x92, 0x10, 0x68, 0x49, 0x76, 0x26, 0xbd, 0x86, 0xda, 0xa5,
+	0xd6, 0xc8, 0x15, 0x47, 0xc2, 0x6b, 0xee, 0x36, 0xf7, 0x7a, 0x01, 0x2e, 0xc9, 0x4f, 0xa1, 0x3b,
+	0x89, 0x13, 0xc9, 0xb8, 0xf0, 0x5a, 0xbb, 0xcd, 0xbd, 0xfe, 0xfe, 0x9d, 0x7b, 0x4b, 0xad, 0x56,
+	0x44, 0xdc, 0xfb, 0x99, 0xe6, 0x7b, 0x9a, 0x4a, 0xbe, 0x08, 0xec, 0x2e, 0xf2, 0x21, 0xb4, 0x44,
+	0xc6, 0xa5, 0xd7, 0x56, 0xbb, 0xaf, 0xae, 0xed, 0x3e, 0xca, 0xb8, 0x0c, 0x14, 0x0b, 0x6a, 0x34,
+	0xe1, 0xd9, 0xdc, 0xeb, 0xec, 0x3a, 0x7b, 0xed, 0x40, 0xad, 0x91, 0x26, 0xe2, 0xdf, 0x32, 0xaf,
+	0xab, 0x69, 0xb8, 0x26, 0xd7, 0xa0, 0x13, 0x16, 0x5c, 0x64, 0xdc, 0x73, 0x95, 0xee, 0x06, 0xed,
+	0x1c, 0xc1, 0xa0, 0xaa, 0x03, 0x5a, 0x73, 0xc2, 0x16, 0xc6, 0x2d, 0xb8, 0x24, 0xdf, 0x87, 0xf6,
+	0x29, 0x4d, 0x0a, 0xa6, 0x8c, 0xee, 0xef, 0xbf, 0x5b, 0xd1, 0x46, 0xef, 0x7c, 0x81, 0x5f, 0x45,
+	0xa0, 0xb9, 0x7e, 0xdc, 0x78, 0xe8, 0xf8, 0x77, 0x61, 0x50, 0xfd, 0x84, 0xc2, 0xd5, 0x47, 0xe1,
+	0x39, 0xca, 0x4b, 0x06, 0xf9, 0x0f, 0x00, 0x96, 0x06, 0x91, 0x6d, 0x68, 0x4f, 0x62, 0x96, 0xd8,
+	0x98, 0x68, 0x80, 0xc6, 0x44, 0x4c, 0x84, 0x4a, 0xba, 0x1b, 0xa8, 0xb5, 0x3f, 0x81, 0xc1, 0x37,
+	0xb9, 0x60, 0x5c, 0x06, 0x4c, 0x14, 0x89, 0xda, 0x19, 0xa7, 0x11, 0x3b, 0xb3, 0x3b, 0x15, 0x30,
+	0x01, 0x6e, 0xd8, 0x00, 0x13, 0x0f, 0xba, 0xa7, 0x8c, 0xa3, 0xe2, 0x5e, 0x73, 0xd7, 0xd9, 0x6b,
+	0x06, 0x16, 0xa2, 0x7e, 0x5c, 0x9d, 0xe4, 0xb5, 0xb4, 0x73, 0x34, 0xf2, 0x4f, 0xa0, 0x37, 0x0a,
+	0x43, 0x26, 0xc4, 0x17, 0x6c, 0x81, 0x8a, 0xa4, 0x74, 0xce, 0x8c, 0x0c, 0xb5, 0x26, 0xbb, 0xd0,
+	0x47, 0x85, 0x78, 0x9c, 0x4b, 0x3c, 0x56, 0xcb, 0xaa, 0x92, 0xac, 0x3f, 0x9b, 0x4b, 0x7f, 0x5e,
+	0x83, 0x0e, 0x0d, 0x65, 0x7c, 0xca, 0x94, 0x30, 0x37, 0x30, 0xc8, 0xff, 0x3d, 0x5c, 0xfe, 0x32,
+	0xa2, 0xf9, 0x21, 0xcf, 0x8a, 0xfc, 0x2b, 0x9a, 0xe7, 0x71, 0x3a, 0x45, 0xc3, 0xa6, 0x88, 0xad,
+	0x61, 0x0a, 0x90, 0x1d, 0x70, 0x05, 0x0b, 0xf1, 0x78, 0xe1, 0x35, 0x94, 0x43, 0x4b, 0x4c, 0xde,
+	0x87, 0x81, 0x5d, 0x8f, 0x69, 0x92, 0x28, 0xc1, 0x6e, 0xd0, 0xb7, 0xb4, 0x51, 0x92, 0xe0, 0xa1,
+	0x34, 0x9a, 0xc7, 0xa9, 0x91, 0xaf, 0x81, 0xff, 0xcf, 0x06, 0x74, 0x51, 0xfe, 0xc1, 0x64, 0x8a,
+	0x4a, 0x17, 0x3c, 0xb1, 0x49, 0x50, 0xf0, 0x84, 0xdc, 0x80, 0x9e, 0x90, 0x94, 0xcb, 0xb1, 0x4c,
+	0x84, 0x09, 0x85, 0xab, 0x08, 0xcf, 0x13, 0x41, 0x3e, 0x81, 0xed, 0x38, 0x15, 0x2c, 0x2c, 0x38,
+	0x1b, 0x8b, 0x93, 0x38, 0x1f, 0x9f, 0x32, 0x1e, 0x4f, 0x16, 0x46, 0x36, 0xb1, 0xdf, 0x8e, 0x4e,
+	0xe2, 0xfc, 0x85, 0xfa, 0x82, 0xa1, 0x90, 0xf1, 0x9c, 0x65, 0x85, 0xf6, 0x78, 0x3b, 0xb0, 0x90,
+	0xbc, 0x0b, 0xdd, 0xe3, 0x38, 0x8d, 0xc6, 0x51, 0xea, 0xb5, 0x75, 0x2c, 0x10, 0x3e, 0x49, 0x89,
+	0x0f, 0x43, 0x65, 0xfd, 0xf8, 0x98, 0x0a, 0x86, 0x9f, 0x3b, 0xda, 0xd9, 0x8a, 0xf8, 0x98, 0x0a,
+	0xf6, 0x24, 0x45, 0xe3, 0x35, 0x8f, 0x2e, 0x24, 0xaf, 0x5b, 0x61, 0xd1, 0x09, 0x49, 0x6e, 0x01,
+	0x68, 0x16, 0x2a, 0xa5, 0xad, 0x85, 0x9e, 0xa2, 0x8c, 0xa4, 0xe4, 0xe4, 0x31, 0x6c, 0xe9, 0xcf,
+	0x73, 0x1d, 0x01, 0xe1, 0xf5, 0x54, 0x0d, 0xde, 0xa8, 0x64, 0x7d, 0x3d, 0x4a, 0xc1, 0x70, 0x5a,
+	0x41, 0xc2, 0x7f, 0x0a, 0xdd, 0x51, 0x21, 0x67, 0xe8, 0x48, 0xec, 0x17, 0x8b, 0xbc, 0xcc, 0x19,
+	0x5c, 0x93, 0xbb, 0xd0, 0x4a, 0x22, 0x9a, 0x9b, 0x72, 0x22, 0xb5, 0x83, 0x0f, 0x26, 0xd3, 0x40,
+	0x7d, 0xf7, 0xbf, 0x6b, 0x40, 0x77, 0x14, 0x86, 0x59, 0x91, 0xae, 0xf5, 0x2a, 0xcc, 0xa1, 0x9c,
+	0x72, 0x96, 0xda, 0x4e, 0x64, 0x50, 0x3d, 0x1f, 0x9b, 0xeb, 0xf9, 0xf8, 0x3e, 0x0c, 0xa2, 0x58,
+	0xe4, 0x09, 0x5d, 0x8c, 0x55, 0x36, 0xb7, 0x0c, 0x8b, 0xa6, 0xfd, 0x02, 0x93, 0x7a, 0x99, 0xa0,
+	0xed, 0x6a, 0x82, 0x62, 0xd0, 0xe6, 0x59, 0x54, 0x24, 0x4c, 0x78, 0x1d, 0x95, 0x75, 0x16, 0x92,
+	0xab, 0xd0, 0xc9, 0xf8, 0x74, 0x1c, 0x47, 0xa6, 0xe5, 0xb4, 0x33, 0x3e, 0x7d, 0x16, 0x91, 0x4f,
+	0xa1, 0x4f, 0x55, 0xf9, 0x8c, 0x4f, 0xd8, 0x42, 0x78, 0xae, 0xf2, 0xe4, 0x76, 0xc5, 0xe0, 0xb2,
+	0xb8, 0x02, 0xa0, 0x76, 0x29, 0xd0, 0x41, 0xb4, 0x90, 0x33, 0xaf, 0xb7, 0xe6, 0x20, 0xe3, 0xd6,
+	0x40, 0x7d, 0xf7, 0x7f, 0x07, 0x43, 0xe3, 0x1f, 0xd3, 0x06, 0xea, 0x5e, 0xaa, 0x14, 0x7c, 0x63,
+	0xb5, 0xe0, 0xb1, 0xd5, 0x64, 0x45, 0x1a, 0x99, 0x14, 0xd5, 0x80, 0x7c, 0x04, 0x1d, 0x91, 0x15,
+	0x3c, 0xd4, 0x5e, 0xa9, 0x89, 0x36, 0x92, 0x0c, 0x87, 0xff, 0x67, 0x07, 0xb6, 0x0d, 0xcd, 0x76,
+	0x74, 0xd4, 0x41, 0xe0, 0xd1, 0x32, 0x93, 0x54, 0x57, 0x4f, 0x33, 0xd0, 0x00, 0xeb, 0x67, 0x4e,
+	0xcf, 0xc6, 0x22, 0xcc, 0xb8, 0x6e, 0xa4, 0x4e, 0xe0, 0xce, 0xe9, 0xd9, 0x11, 0x62, 0xf2, 0x31,
+	0xb4, 0x66, 0xb1, 0xd4, 0x57, 0x48, 0x7f, 0xdf, 0x3b, 0x47, 0xaa, 0x3a, 0x3b, 0x50, 0x5c, 0xe4,
+	0x36, 0xf4, 0x53, 0x76, 0x26, 0xc7, 0xa6, 0x9d, 0xeb, 0x00, 0x02, 0x92, 0x0e, 0x14, 0xc5, 0x3f,
+	0x82, 0x4b, 0x07, 0x33, 0x16, 0x9e, 0xa0, 0x5f, 0xcd, 0x4d, 0xe6, 0x41, 0x97, 0xea, 0xa3, 0x8c,
+	0x7b, 0x2c, 0x24, 0x77, 0x75, 0x7f, 0xd2, 0xc9, 0x78, 0x7e, 0x6c, 0x90, 0xc1, 0xbf, 0x0b, 0x5b,
+	0xcb, 0x43, 0x6d, 0xd3, 0x3d, 0xa5, 0x89, 0x71, 0xb8, 0x1b, 0x68, 0xe0, 0x7f, 0xe7, 0x80, 0x3b,
+	0xca, 0xe3, 0xe7, 0xd9, 0x09, 0x4b, 0xcf, 0x6d, 0x99, 0x04, 0x5a, 0x33, 0x2a, 0x66, 0xf6, 0x0a,
+	0xc5, 0x35, 0xaa, 0x17, 0x72, 0x46, 0x25, 0x8b, 0x6c, 0x67, 0x36, 0x10, 0xbf, 0xb0, 0xb3, 0x3c,
+	0xe6, 0x4c, 0x28, 0x43, 0x9b, 0x81, 0x85, 0x2b, 0x4d, 0xb0, 0x5d, 0x6b, 0x82, 0x3b, 0xe0, 0x1a,
+	0xfb, 0x6c, 0xaa, 0x96, 0xd8, 0x7f, 0x04, 0x03, 0xeb, 0xd5, 0x0c, 0x73, 0x77, 0xb3, 0x6b, 0xb6,
+	0xa1, 0xcd, 0x91, 0xc5, 0xf4, 0x58, 0x0d, 0xfc, 0xbf, 0x39, 0x00, 0x5f, 0xb1, 0xf9, 0x31, 0xe3,
+	0x62, 0x16, 0xe7, 0x17, 0x6c, 0x7f, 0xc3, 0x2e, 0xfd, 0x3d, 0x18, 0x46, 0x2c, 0x5d, 0x8c, 0xcb,
+	0x33, 0x5a, 0xea, 0x8c, 0x01, 0x12, 0x8f, 0xec, 0x39, 0xa5, 0x8a, 0xed, 0x8a, 0x8a, 0xcb, 0x06,
+	0xdf, 0xa9, 0x36, 0xf8, 0x08, 0x7a, 0x68, 0xf1, 0x21, 0xa7, 0xa9, 0xfc, 0xbf, 0xa9, 0xed, 0xff,
+	0xbb, 0x0d, 0xad, 0x6f, 0x04, 0xe3, 0x6b, 0xc5, 0xf8, 0xea, 0xab, 0xb2, 0xde, 0x9a, 0x9a, 0xeb,
+	0xad, 0xc9, 0x26, 0x54, 0xab, 0x92, 0x50, 0xdb, 0xd0, 0x66, 0x73, 0x1a, 0x27, 0xe6, 0xbe, 0xd0,
+	0x80, 0xdc, 0x81, 0x2d, 0xb5, 0xd0, 0x77, 0x51, 0xcc, 0x22, 0xe3, 0x8c, 0xa1, 0xa2, 0xbe, 0x30,
+	0x44, 0xf4, 0x43, 0x1e, 0x87, 0xb2, 0xe0, 0xcc, 0x5c, 0x16, 0x16, 0x56, 0xba, 0xa0, 0xbb, 0xd2,
+	0x05, 0xb7, 0xa1, 0x2d, 0x16, 0x22, 0xcb, 0x55, 0x7b, 0x72, 0x03, 0x0d, 0xd0, 0x6b, 0x39, 0x15,
+	0xe2, 0x65, 0xc6, 0x23, 0x0f, 0xd4, 0x41, 0x25, 0x5e, 0xf1, 0x68, 0xff, 0x15, 0x1e, 0x1d, 0xfc,
+	0x0f, 0x89, 0x30, 0x3c, 0x27, 0x11, 0xaa, 0x19, 0xbf, 0xb5, 0x9a, 0xf1, 0xe8, 0x0a, 0x95, 0x01,
+	0xe3, 0x92, 0xe3, 0x92, 0xe2, 0x18, 0x2a, 0xea, 0xc8, 0xb2, 0xa1, 0x1f, 0xf3, 0x2c, 0x9c, 0x79,
+	0x97, 0x75, 0xe3, 0x52, 0x80, 0xec, 0x03, 0xd0, 0x3c, 0x1e, 0x4b, 0xac, 0x67, 0xe1, 0x5d, 0x51,
+	0x1d, 0xea, 0x9d, 0x6a, 0x9b, 0x30, 0xb5, 0x1e, 0xf4, 0xa8, 0x59, 0x09, 0xf2, 0x1e, 0x40, 0x1c,
+	0xb1, 0x54, 0xc6, 0x32, 0x66, 0xc2, 0x23, 0x4a, 0x58, 0x85, 0x82, 0x13, 0xa5, 0xce, 0xda, 0x77,
+	0x76, 0x9b, 0xb5, 0x89, 0xb2, 0x5a, 0x9a, 0x36, 0x9d, 0x3f, 0x83, 0xfe, 0xbc, 0x2c, 0x38, 0xe1,
+	0x6d, 0xaf, 0x0d, 0xc5, 0xcb, 0x72, 0x0c, 0xaa, 0x9c, 0x78, 0xff, 0xe0, 0x09, 0xe3, 0x29, 0xa6,
+	0xbc, 0xf0, 0xae, 0xae, 0xdd, 0x3f, 0x65, 0x3d, 0x04, 0xc0, 0xed, 0x52, 0x90, 0x87, 0x76, 0x8a,
+	0x30, 0xfb, 0xae, 0x5d, 0x28, 0x50, 0xb1, 0xea, 0x9d, 0x7e, 0x01, 0x80, 0xb9, 0xff, 0x96, 0xae,
+	0xa3, 0x0f, 0x6a, 0xd7, 0xd1, 0xa5, 0x8a, 0x06, 0x4a, 0x8c, 0xbd, 0x8b, 0xfe, 0xe8, 0xc0, 0x15,
+	0x24, 0xbc, 0xf1, 0x45, 0xf4, 0xe1, 0xca, 0x45, 0x74, 0xb5, 0x2e, 0xef, 0xb5, 0x6e, 0xa1, 0x11,
+	0xf4, 0x0e, 0xb2, 0x34, 0x8a, 0xa5, 0x19, 0xb0, 0x73, 0xca, 0x05, 0xe3, 0xc6, 0x1d, 0x06, 0x91,
+	0x9b, 0xd0, 0x0b, 0x2d, 0x93, 0x69, 0x09, 0x4b, 0x82, 0xff, 0x07, 0x07, 0xda, 0x41, 0x56, 0x48,
+	0x86, 0x53, 0x9b, 0x49, 0xde, 0x71, 0xe9, 0xd2, 0x9e, 0xa1, 0x3c, 0x8b, 0xc8, 0x75, 0x70, 0xe7,
+	0x59, 0xc4, 0x92, 0x71, 0x39, 0xef, 0x77, 0x15, 0x7e, 0x16, 0x95, 0x13, 0x58, 0xb3, 0x32, 0x81,
+	0xfd, 0x10, 0xa0, 0x14, 0x62, 0x9f, 0x68, 0xd5, 0xb4, 0x28, 0xf5, 0x0e, 0x2a, 0x7c, 0xfe, 0x7f,
+	0x1c, 0x68, 0x8f, 0x84, 0x60, 0xeb, 0x81, 0x5d, 0xd5, 0xae, 0x51, 0xd7, 0xee, 0xad, 0x0c, 0x65,
+	0xb7, 0xa1, 0x4f, 0x51, 0xf8, 0x38, 0x4c, 0xa8, 0x10, 0xa6, 0xd7, 0x81, 0x22, 0x1d, 0x20, 0x05,
+	0x03, 0x6b, 0x18, 0x26, 0x53, 0x33, 0x1b, 0xbb, 0xfa, 0xf3, 0x64, 0x5a, 0x69, 0x66, 0xdd, 0x95,
+	0x66, 0xb6, 0x07, 0x1d, 0x8e, 0x0e, 0xb6, 0xc3, 0xd9, 0xe5, 0x95, 0xe2, 0x28, 0x24, 0x0b, 0xcc,
+	0x77, 0xff, 0x25, 0xf4, 0x95, 0xf1, 0x6f, 0x29, 0xb7, 0xf7, 0x6a, 0xb9, 0x5d, 0x15, 0xac, 0xe5,
+	0xd8, 0xe4, 0xfe, 0x93, 0x03, 0x44, 0x51, 0xde, 0x38, 0xbb, 0x3f, 0x5a, 0xc9, 0xee, 0x6b, 0x6b,
+	0x12, 0x5f, 0x2b, 0xbd, 0x7f, 0x05, 0x7d, 0x3d, 0x21, 0xa9, 0xa9, 0x68, 0xa5, 0xc7, 0x3b, 0x17,
+	0x4c, 0x23, 0x8d, 0x5a, 0x6f, 0x26, 0xd0, 0xc2, 0x4c, 0xb5, 0xe9, 0x89, 0x6b, 0xff, 0x37, 0x40,
+	0x2a, 0x47, 0xdb, 0x11, 0xee, 0x63, 0x68, 0x87, 0x88, 0x95, 0xc1, 0x35, 0xf5, 0x2b, 0xdc, 0x9a,
+	0xc9, 0xcc, 0x4d, 0x09, 0x8d, 0x53, 0xf3, 0x5a, 0xb3, 0xd0, 0x7f, 0x04, 0x5b, 0x5f, 0x67, 0x49,
+	0x1c, 0x2e, 0x9e, 0xb0, 0x50, 0x6d, 0x47, 0x5e, 0x9a, 0x24, 0xd9, 0x4b, 0x66, 0x47, 0x39, 0x0b,
+	0x51, 0x3b, 0x5e, 0x24, 0xcc, 0xce, 0x6a, 0xb8, 0xf6, 0x05, 0x5c, 0xd1, 0xfb, 0x9f, 0x73, 0x1a,
+	0xb2, 0x27, 0x4c, 0xe2, 0x6d, 0xfb, 0x1e, 0xc0, 0xb7, 0x05, 0x4d, 0x25, 0x5e, 0xaa, 0xb6, 0xc6,
+	0x2b, 0x14, 0x7c, 0x50, 0x9e, 0x52, 0x6e, 0xce, 0xc1, 0xa5, 0x99, 0x1e, 0x0b, 0x6b, 0xb9, 0x06,
+	0xb5, 0x87, 0xb8, 0x5b, 0x3e, 0xc4, 0xff, 0xee, 0xc0, 0x25, 0x2d, 0x35, 0x28, 0x12, 0xa6, 0x24,
+	0x97, 0xca, 0x39, 0x4b, 0xe5, 0x70, 0x3f, 0x9b, 0x4c, 0x58, 0x58, 0xbe, 0x8b, 0x34, 0x42, 0xde,
+	0x97, 0x33, 0x66, 0x6b, 0x4f, 0xad, 0xd5, 0x73, 0x86, 0xca, 0x70, 0xc6, 0x22, 0x23, 0xcc, 0x42,
+	0x75, 0x13, 0x72, 0x9e, 0xf1, 0x72, 0xa2, 0x40, 0x40, 0x1e, 0x40, 0x37, 0x52, 0xd6, 0xea, 0x99,
+	0xb2, 0xbf, 0x7f, 0xb3, 0x12, 0x82, 0x35, 0x97, 0x04, 0x96, 0xd9, 0xff, 0x8b, 0x63, 0x3d, 0xf6,
+	0x14, 0x43, 0x90, 0xd2, 0xb2, 0x23, 0x2a, 0x62, 0xd9, 0x11, 0x15, 0x42, 0xd9, 0x3a, 0xcc, 0xda,
+	0x00, 0x0d, 0xc8, 0xa7, 0xe0, 0x46, 0x26, 0x5c, 0xca, 0x86, 0xfe, 0xfe, 0xf5, 0x35, 0xe1, 0x36,
+	0x9e, 0x41, 0xc9, 0x4a, 0x3e, 0x81, 0x36, 0x57, 0xef, 0x35, 0xdd, 0xe3, 0x76, 0xd6, 0xf6, 0x94,
+	0xde, 0x0c, 0x34, 0xa3, 0xff, 0x0f, 0x07, 0xae, 0xac, 0x24, 0x9f, 0xaa, 0xf6, 0xcf, 0x61, 0xcb,
+	0x3c, 0xe4, 0x94, 0x3a, 0x2c, 0x7a, 0x45, 0x12, 0x0e, 0xe9, 0x12, 0x30, 0xf5, 0x5a, 0x15, 0x92,
+	0xca, 0xc2, 0xfe, 0x39, 0x30, 0x48, 0x45, 0x80, 0x09, 0x41, 0xa7, 0x36, 0x0b, 0x2c, 0x24, 0x8f,
+	0xa0, 0xcf, 0x96, 0xce, 0x32, 0x3d, 0x62, 0xdd, 0xdf, 0x15, 0x87, 0x06, 0xd5, 0x0d, 0xfb, 0x7f,
+	0xed, 0x40, 0xef, 0x6b, 0xcb, 0x4c, 0x7e, 0x02, 0x43, 0xfd, 0xbb, 0xc8, 0x3e, 0xa7, 0xcf, 0x79,
+	0xd8, 0xed, 0x54, 0xa7, 0x90, 0x95, 0x9f, 0x4b, 0x9f, 0xab, 0xbf, 0x86, 0x76, 0x6b, 0xf5, 0x52,
+	0x5c, 0xfe, 0x4c, 0xdc, 0xd9, 0xf8, 0x68, 0x23, 0x5f, 0xc0, 0x96, 0xee, 0x5c, 0xe5, 0xa0, 0xe5,
+	0x6d, 0xfa, 0x1b, 0xb8, 0x73, 0x7b, 0xfd, 0x94, 0xd5, 0xae, 0x37, 0x02, 0xd7, 0xbe, 0xc2, 0x48,
+	0x35, 0x9a, 0xb5, 0xf7, 0xde, 0xce, 0xf5, 0x73, 0xbf, 0x29, 0x7d, 0x1e, 0x00, 0x68, 0xf3, 0xd4,
+	0x94, 0x5e, 0x9f, 0x29, 0x36, 0xbb, 0xe1, 0x33, 0xe8, 0x1e, 0x32, 0xbd, 0x69, 0x83, 0x0f, 0xce,
+	0x9f, 0x17, 0xc8, 0x53, 0xe8, 0x6b, 0x23, 0x90, 0x76, 0x91, 0xf5, 0x37, 0x6b, 0xfb, 0x57, 0x4d,
+	0x7f, 0x08, 0x7d, 0x13, 0x44, 0x75, 0x07, 0xaf, 0x5d, 0x18, 0x9b, 0x35, 0xff, 0x11, 0xb8, 0x18,
+	0x40, 0xb5, 0x6d, 0x83, 0xea, 0x1b, 0x2e, 0x03, 0x72, 0x08, 0x03, 0x13, 0x3c, 0x24, 0x5e, 0xa4,
+	0xfc, 0xad, 0xfa, 0x09, 0xab, 0xda, 0x7f, 0x09, 0x43, 0x34, 0xe9, 0xe7, 0x54, 0xe8, 0x3a, 0x21,
+	0xb7, 0x36, 0x94, 0xce, 0x39, 0xbe, 0x58, 0xaf, 0xc7, 0x5f, 0x02, 0xb1, 0xa7, 0x99, 0x11, 0xfe,
+	0x4d, 0x8f, 0x7c, 0xfc, 0xc1, 0xaf, 0xef, 0x4c, 0x63, 0x39, 0x2b, 0x8e, 0xef, 0x85, 0xd9, 0xfc,
+	0xbe, 0x3c, 0x4b, 0xf7, 0x97, 0x7f, 0xd2, 0xef, 0x57, 0xfe, 0xaf, 0x1f, 0x77, 0xd4, 0x6f, 0xf5,
+	0x1f, 0xfc, 0x77, 0x00, 0xc5, 0x13, 0xd1, 0x49, 0x75, 0x17, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// ProvisionClient is the client API for Provision service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ProvisionClient interface {
+	// POST /account
+	UpsertAccount(ctx context.Context, in *Account, opts ...grpc.CallOption) (*UpsertResult, error)
+	// GET /account/:id
+	GetAccount(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*AccountResult, error)
+	// POST /searchAccounts
+	SearchAccounts(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*AccountSearchResults, error)
+	// POST /keyCheck/:id
+	CheckKey(ctx context.Context, in *CheckKeyRequest, opts ...grpc.CallOption) (*CheckKeyResult, error)
+	// POST /user
+	UpsertUser(ctx context.Context, in *User, opts ...grpc.CallOption) (*UpsertResult, error)
+	// GET /user/:id
+	GetUser(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*UserResult, error)
+	// POST /searchUsers
+	SearchUsers(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*UserSearchResults, error)
+	// POST /asset
+	UpsertAsset(ctx context.Context, in *Asset, opts ...grpc.CallOption) (*UpsertResult, error)
+	// GET /asset/:id
+	GetAsset(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*AssetResult, error)
+	// POST /searchAssets
+	SearchAssets(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*AssetSearchResults, error)
+	// POST /userHasAccess, requires a token
+	UserHasAccess(ctx context.Context, in *AccessCheckRequest, opts ...grpc.CallOption) (*AccessCheckResult, error)
+	// POST /userHasAdminAccess, requires a token
+	UserHasAdminAccess(ctx context.Context, in *AccessCheckRequest, opts ...grpc.CallOption) (*AccessCheckResult, error)
+}
+
+type provisionClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewProvisionClient(cc *grpc.ClientConn) ProvisionClient {
+	return &provisionClient{cc}
+}
+
+func (c *provisionClient) UpsertAccount(ctx context.Context, in *Account, opts ...grpc.CallOption) (*UpsertResult, error) {
+	out := new(UpsertResult)
+	err := c.cc.Invoke(ctx, "/provision.Provision/UpsertAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) GetAccount(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*AccountResult, error) {
+	out := new(AccountResult)
+	err := c.cc.Invoke(ctx, "/provision.Provision/GetAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) SearchAccounts(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*AccountSearchResults, error) {
+	out := new(AccountSearchResults)
+	err := c.cc.Invoke(ctx, "/provision.Provision/SearchAccounts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) CheckKey(ctx context.Context, in *CheckKeyRequest, opts ...grpc.CallOption) (*CheckKeyResult, error) {
+	out := new(CheckKeyResult)
+	err := c.cc.Invoke(ctx, "/provision.Provision/CheckKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) UpsertUser(ctx context.Context, in *User, opts ...grpc.CallOption) (*UpsertResult, error) {
+	out := new(UpsertResult)
+	err := c.cc.Invoke(ctx, "/provision.Provision/UpsertUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) GetUser(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*UserResult, error) {
+	out := new(UserResult)
+	err := c.cc.Invoke(ctx, "/provision.Provision/GetUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) SearchUsers(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*UserSearchResults, error) {
+	out := new(UserSearchResults)
+	err := c.cc.Invoke(ctx, "/provision.Provision/SearchUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) UpsertAsset(ctx context.Context, in *Asset, opts ...grpc.CallOption) (*UpsertResult, error) {
+	out := new(UpsertResult)
+	err := c.cc.Invoke(ctx, "/provision.Provision/UpsertAsset", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) GetAsset(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*AssetResult, error) {
+	out := new(AssetResult)
+	err := c.cc.Invoke(ctx, "/provision.Provision/GetAsset", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) SearchAssets(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*AssetSearchResults, error) {
+	out := new(AssetSearchResults)
+	err := c.cc.Invoke(ctx, "/provision.Provision/SearchAssets", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) UserHasAccess(ctx context.Context, in *AccessCheckRequest, opts ...grpc.CallOption) (*AccessCheckResult, error) {
+	out := new(AccessCheckResult)
+	err := c.cc.Invoke(ctx, "/provision.Provision/UserHasAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *provisionClient) UserHasAdminAccess(ctx context.Context, in *AccessCheckRequest, opts ...grpc.CallOption) (*AccessCheckResult, error) {
+	out := new(AccessCheckResult)
+	err := c.cc.Invoke(ctx, "/provision.Provision/UserHasAdminAccess", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProvisionServer is the server API for Provision service.
+type ProvisionServer interface {
+	// POST /account
+	UpsertAccount(context.Context, *Account) (*UpsertResult, error)
+	// GET /account/:id
+	GetAccount(context.Context, *GetRequest) (*AccountResult, error)
+	// POST /searchAccounts
+	SearchAccounts(context.Context, *SearchRequest) (*AccountSearchResults, error)
+	// POST /keyCheck/:id
+	CheckKey(context.Context, *CheckKeyRequest) (*CheckKeyResult, error)
+	// POST /user
+	UpsertUser(context.Context, *User) (*UpsertResult, error)
+	// GET /user/:id
+	GetUser(context.Context, *GetRequest) (*UserResult, error)
+	// POST /searchUsers
+	SearchUsers(context.Context, *SearchRequest) (*UserSearchResults, error)
+	// POST /asset
+	UpsertAsset(context.Context, *Asset) (*UpsertResult, error)
+	// GET /asset/:id
+	GetAsset(context.Context, *GetRequest) (*AssetResult, error)
+	// POST /searchAssets
+	SearchAssets(context.Context, *SearchRequest) (*AssetSearchResults, error)
+	// POST /userHasAccess, requires a token
+	UserHasAccess(context.Context, *AccessCheckRequest) (*AccessCheckResult, error)
+	// POST /userHasAdminAccess, requires a token
+	UserHasAdminAccess(context.Context, *AccessCheckRequest) (*AccessCheckResult, error)
+}
+
+func RegisterProvisionServer(s *grpc.Server, srv ProvisionServer) {
+	s.RegisterService(&_Provision_serviceDesc, srv)
+}
+
+func _Provision_UpsertAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Account)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).UpsertAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/UpsertAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).UpsertAccount(ctx, req.(*Account))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_GetAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).GetAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/GetAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).GetAccount(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_SearchAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).SearchAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/SearchAccounts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).SearchAccounts(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_CheckKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).CheckKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/CheckKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).CheckKey(ctx, req.(*CheckKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_UpsertUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(User)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).UpsertUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/UpsertUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).UpsertUser(ctx, req.(*User))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/GetUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).GetUser(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_SearchUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).SearchUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/SearchUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).SearchUsers(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_UpsertAsset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Asset)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).UpsertAsset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/UpsertAsset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).UpsertAsset(ctx, req.(*Asset))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_GetAsset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).GetAsset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/GetAsset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).GetAsset(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_SearchAssets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).SearchAssets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/SearchAssets",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).SearchAssets(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_UserHasAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccessCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).UserHasAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/UserHasAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).UserHasAccess(ctx, req.(*AccessCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Provision_UserHasAdminAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccessCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProvisionServer).UserHasAdminAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/provision.Provision/UserHasAdminAccess",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProvisionServer).UserHasAdminAccess(ctx, req.(*AccessCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Provision_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "provision.Provision",
+	HandlerType: (*ProvisionServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "UpsertAccount",
+			Handler:    _Provision_UpsertAccount_Handler,
+		},
+		{
+			MethodName: "GetAccount",
+			Handler:    _Provision_GetAccount_Handler,
+		},
+		{
+			MethodName: "SearchAccounts",
+			Handler:    _Provision_SearchAccounts_Handler,
+		},
+		{
+			MethodName: "CheckKey",
+			Handler:    _Provision_CheckKey_Handler,
+		},
+		{
+			MethodName: "UpsertUser",
+			Handler:    _Provision_UpsertUser_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _Provision_GetUser_Handler,
+		},
+		{
+			MethodName: "SearchUsers",
+			Handler:    _Provision_SearchUsers_Handler,
+		},
+		{
+			MethodName: "UpsertAsset",
+			Handler:    _Provision_UpsertAsset_Handler,
+		},
+		{
+			MethodName: "GetAsset",
+			Handler:    _Provision_GetAsset_Handler,
+		},
+		{
+			MethodName: "SearchAssets",
+			Handler:    _Provision_SearchAssets_Handler,
+		},
+		{
+			MethodName: "UserHasAccess",
+			Handler:    _Provision_UserHasAccess_Handler,
+		},
+		{
+			MethodName: "UserHasAdminAccess",
+			Handler:    _Provision_UserHasAdminAccess_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "provisionpb/provision.proto",
+}
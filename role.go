@@ -0,0 +1,382 @@
+package provision
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"github.com/txn2/es/v2"
+	"go.uber.org/zap"
+)
+
+const IdxRole = "role"
+
+// Role bundles sections under a name. Roles with an empty
+// Account are global, account roles take precedence over a
+// global role of the same id.
+type Role struct {
+	Id          string   `json:"id" yaml:"id"`
+	Account     string   `json:"account" yaml:"account"`
+	DisplayName string   `json:"display_name" yaml:"displayName"`
+	Description string   `json:"description" yaml:"description"`
+	Sections    []string `json:"sections" yaml:"sections"`
+	SectionsAll bool     `json:"sections_all" yaml:"sectionsAll"`
+}
+
+// RoleResult returned from Elastic
+type RoleResult struct {
+	es.Result
+	Source Role `json:"_source"`
+}
+
+// RoleResultAck
+type RoleResultAck struct {
+	ack.Ack
+	Payload RoleResult `json:"payload"`
+}
+
+// RoleSearchResults
+type RoleSearchResults struct {
+	es.SearchResults
+	Hits struct {
+		Total    int          `json:"total"`
+		MaxScore float64      `json:"max_score"`
+		Hits     []RoleResult `json:"hits"`
+	} `json:"hits"`
+
+	// position after the last hit, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// AccountRoles assigns roles to a user in an account. An empty
+// Account assigns the roles in every account.
+type AccountRoles struct {
+	Account string   `json:"account" yaml:"account" mapstructure:"account"`
+	Roles   []string `json:"roles" yaml:"roles" mapstructure:"roles"`
+}
+
+// RoleGrant is the resolved sections of a user's roles in an
+// account. Populated by ResolveUserRoles, never stored.
+type RoleGrant struct {
+	Account     string   `json:"account" yaml:"account" mapstructure:"account"`
+	Sections    []string `json:"sections" yaml:"sections" mapstructure:"sections"`
+	SectionsAll bool     `json:"sections_all" yaml:"sectionsAll" mapstructure:"sections_all"`
+}
+
+// roleDocId
+func roleDocId(account string, id string) string {
+	return fmt.Sprintf("%s:%s", account, id)
+}
+
+// UpsertRole inserts or updates a role
+func (a *Api) UpsertRole(role *Role) (int, es.Result, *es.ErrorResponse, error) {
+	a.Logger.Info("Upsert role record", zap.String("id", role.Id), zap.String("account", role.Account))
+
+	if role.Id == "" {
+		return 400, es.Result{}, nil, errors.New("role requires an id")
+	}
+
+	code, esResult, errorResponse, err := a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxRole, roleDocId(role.Account, role.Id)), role)
+
+	// cached users carry resolved roles
+	a.userCache.reset()
+
+	return code, esResult, errorResponse, err
+}
+
+// UpsertRoleHandler
+func (a *Api) UpsertRoleHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	role := &Role{}
+	err := ak.UnmarshalPostAbort(role)
+	if err != nil {
+		a.Logger.Error("Upsert failure.", zap.Error(err))
+		return
+	}
+
+	a.upsertRoleSend(ak, role)
+}
+
+// UpsertAdmRoleHandler upserts a role for :parentAccount or
+// one of its children.
+func (a *Api) UpsertAdmRoleHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	role := &Role{}
+	err := ak.UnmarshalPostAbort(role)
+	if err != nil {
+		a.Logger.Error("Upsert failure.", zap.Error(err))
+		return
+	}
+
+	parentAccountId := c.Param("parentAccount")
+	if role.Account == "" {
+		role.Account = parentAccountId
+	}
+
+	ok, err := a.IsAdmAccount(parentAccountId, role.Account)
+	if err != nil {
+		ak.SetPayloadType("AccountLookupError")
+		ak.SetPayload("Unable to lookup account.")
+		ak.GinErrorAbort(500, "AccountLookupError", err.Error())
+		return
+	}
+
+	if !ok {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload("Role account is not the requester or a child of the requester.")
+		ak.GinErrorAbort(400, "ValidationError", "Role account does not belong to parent.")
+		return
+	}
+
+	a.upsertRoleSend(ak, role)
+}
+
+// upsertRoleSend
+func (a *Api) upsertRoleSend(ak ack.GinAck, role *Role) {
+	code, esResult, errorResponse, err := a.UpsertRole(role)
+	if err != nil {
+		a.Logger.Error("Upsert failure.", zap.Error(err))
+		ak.SetPayloadType("ErrorMessage")
+		ak.SetPayload("there was a problem upserting the role")
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+			ak.SetPayloadType("EsErrorResponse")
+			ak.SetPayload(errorResponse)
+		}
+		ak.GinErrorAbort(500, "UpsertError", err.Error())
+		return
+	}
+
+	if code < 200 || code >= 300 {
+		a.Logger.Error("Es returned a non 200")
+		ak.SetPayloadType("EsError")
+		ak.SetPayload(esResult)
+		ak.GinErrorAbort(500, "EsError", "Es returned a non 200")
+		return
+	}
+
+	ak.SetPayloadType("EsResult")
+	ak.GinSend(esResult)
+}
+
+// GetRole
+func (a *Api) GetRole(account string, id string) (int, *RoleResult, error) {
+	roleResult := &RoleResult{}
+
+	code, ret, err := a.Elastic.Get(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxRole, roleDocId(account, id)))
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		return code, roleResult, err
+	}
+
+	err = json.Unmarshal(ret, roleResult)
+	if err != nil {
+		return code, roleResult, err
+	}
+
+	return code, roleResult, nil
+}
+
+// GetRoleHandler gets a role by ID, global unless the
+// account query parameter is provided.
+func (a *Api) GetRoleHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	id := c.Param("id")
+	code, roleResult, err := a.GetRole(c.Query("account"), id)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	if code >= 400 && code < 500 {
+		ak.SetPayload("Role " + id + " not found.")
+		ak.GinErrorAbort(404, "RoleNotFound", "Role not found")
+		return
+	}
+
+	ak.SetPayloadType("RoleResult")
+	ak.GinSend(roleResult)
+}
+
+// SearchRoles
+func (a *Api) SearchRoles(searchObj *es.Obj) (int, RoleSearchResults, *es.ErrorResponse, error) {
+	rsResults := &RoleSearchResults{}
+
+	code, nextCursor, errorResponse, err := a.searchPage(IdxRole, searchObj, rsResults)
+	if err != nil {
+		return code, *rsResults, errorResponse, err
+	}
+
+	rsResults.NextCursor = nextCursor
+
+	return code, *rsResults, nil, nil
+}
+
+// SearchRolesHandler searches roles with a posted SearchQuery.
+// With Config.RawSearch sysops may post Elasticsearch DSL
+// with dsl=true.
+func (a *Api) SearchRolesHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	obj, ok := a.searchObj(c, ak, RoleSearchFields)
+	if !ok {
+		return
+	}
+
+	code, esResult, errorResponse, err := a.SearchRoles(obj)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+			ak.SetPayloadType("EsErrorResponse")
+			ak.SetPayload(errorResponse)
+		}
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	if code >= 400 && code < 500 {
+		ak.SetPayload(esResult)
+		ak.GinErrorAbort(500, "SearchError", "There was a problem searching")
+		return
+	}
+
+	ak.SetPayloadType("RoleSearchResults")
+	ak.GinSend(esResult)
+}
+
+// ResolveUserRoles populates the user's RoleGrants from the
+// roles assigned in Roles and Memberships.
+func (a *Api) ResolveUserRoles(user *User) error {
+	user.RoleGrants = nil
+
+	assignments := user.roleAssignments()
+	if len(assignments) < 1 {
+		return nil
+	}
+
+	// candidate account and global roles for every assignment
+	ids := make([]string, 0)
+	for _, ar := range assignments {
+		for _, r := range ar.Roles {
+			ids = appendUnique(ids, roleDocId(ar.Account, r))
+			ids = appendUnique(ids, roleDocId("", r))
+		}
+	}
+
+	query := &es.Obj{
+		"size": len(ids),
+		"query": es.Obj{
+			"ids": es.Obj{"values": ids},
+		},
+	}
+
+	code, rsResults, errorResponse, err := a.SearchRoles(query)
+	if err != nil {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return err
+	}
+
+	// no roles have been defined
+	if code == 404 {
+		return nil
+	}
+
+	roles := make(map[string]Role)
+	for _, hit := range rsResults.Hits.Hits {
+		roles[hit.Id] = hit.Source
+	}
+
+	for _, ar := range assignments {
+		grant := RoleGrant{Account: ar.Account, Sections: make([]string, 0)}
+
+		for _, r := range ar.Roles {
+			role, ok := roles[roleDocId(ar.Account, r)]
+			if !ok {
+				role, ok = roles[roleDocId("", r)]
+			}
+			if !ok {
+				continue
+			}
+
+			grant.SectionsAll = grant.SectionsAll || role.SectionsAll
+			for _, sec := range role.Sections {
+				grant.Sections = appendUnique(grant.Sections, sec)
+			}
+		}
+
+		user.RoleGrants = append(user.RoleGrants, grant)
+	}
+
+	return nil
+}
+
+// roleGrants returns true if a role assigned in account
+// grants the section.
+func (u *User) roleGrants(account string, sec string) bool {
+	for _, rg := range u.RoleGrants {
+		if rg.Account != account {
+			continue
+		}
+
+		if rg.SectionsAll || sectionMatch(sec, rg.Sections) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetRoleMapping
+func GetRoleMapping(prefix string) es.IndexTemplate {
+	template := es.Obj{
+		"index_patterns": []string{prefix + IdxRole},
+		"settings": es.Obj{
+			"number_of_shards": 2,
+		},
+		"mappings": es.Obj{
+			"_doc": es.Obj{
+				"_source": es.Obj{
+					"enabled": true,
+				},
+				"properties": es.Obj{
+					"id": es.Obj{
+						"type": "keyword",
+					},
+					"account": es.Obj{
+						"type": "keyword",
+					},
+					"display_name": es.Obj{
+						"type": "text",
+					},
+					"description": es.Obj{
+						"type": "text",
+					},
+					"sections": es.Obj{
+						"type": "keyword",
+					},
+					"sections_all": es.Obj{
+						"type": "boolean",
+					},
+				},
+			},
+		},
+	}
+
+	return es.IndexTemplate{
+		Name:     prefix + IdxRole,
+		Template: template,
+	}
+}
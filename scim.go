@@ -0,0 +1,920 @@
+package provision
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/es/v2"
+	"go.uber.org/zap"
+)
+
+// ScimKeyName is the name of the account AccessKey used as
+// the SCIM bearer token for the account.
+const ScimKeyName = "scim"
+
+// ScimGroupMembers and ScimGroupAdmins are the groups exposed
+// for an account, mapped to User Accounts and AdminAccounts.
+const ScimGroupMembers = "members"
+const ScimGroupAdmins = "admins"
+
+// ScimMaxCount is the largest page size returned
+const ScimMaxCount = 200
+
+const (
+	scimSchemaUser  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimSchemaGroup = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimSchemaList  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimSchemaError = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimContentType = "application/scim+json"
+)
+
+// ScimName
+type ScimName struct {
+	Formatted string `json:"formatted,omitempty"`
+}
+
+// ScimEmail
+type ScimEmail struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// ScimMember references a user or group
+type ScimMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// ScimMeta
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+// ScimUser is the SCIM representation of a User
+type ScimUser struct {
+	Schemas     []string     `json:"schemas"`
+	Id          string       `json:"id"`
+	UserName    string       `json:"userName"`
+	Name        *ScimName    `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []ScimEmail  `json:"emails,omitempty"`
+	Active      *bool        `json:"active,omitempty"`
+	Groups      []ScimMember `json:"groups,omitempty"`
+	Meta        *ScimMeta    `json:"meta,omitempty"`
+}
+
+// ScimGroup is the SCIM representation of an account group
+type ScimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	Id          string       `json:"id"`
+	DisplayName string       `json:"displayName"`
+	Members     []ScimMember `json:"members"`
+	Meta        *ScimMeta    `json:"meta,omitempty"`
+}
+
+// ScimListResponse
+type ScimListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// ScimPatchOp
+type ScimPatchOp struct {
+	Schemas    []string `json:"schemas"`
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+// ScimError
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// scimKeyCache holds recently verified SCIM tokens so bcrypt
+// is not run on every request of a sync.
+type scimKeyCache struct {
+	mu      sync.Mutex
+	entries map[string]scimKeyEntry
+}
+
+// scimKeyEntry is a verified token of an account
+type scimKeyEntry struct {
+	account string
+	expires time.Time
+}
+
+// evict drops the verified tokens of an account, its keys may
+// have been rotated or deactivated
+func (kc *scimKeyCache) evict(account string) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+
+	for k, entry := range kc.entries {
+		if entry.account == account {
+			delete(kc.entries, k)
+		}
+	}
+}
+
+// ScimTokenHandler authenticates a SCIM bearer token of the form
+// <account>:<key> against the account's scim AccessKey and sets
+// ScimAccount for the following handlers.
+func (a *Api) ScimTokenHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(authHeader) != 2 || authHeader[0] != "Bearer" {
+			scimError(c, 401, "", "Missing bearer token.")
+			return
+		}
+
+		parts := strings.SplitN(authHeader[1], ":", 2)
+		if len(parts) != 2 {
+			scimError(c, 401, "", "Malformed bearer token.")
+			return
+		}
+
+		account, key := parts[0], parts[1]
+		cacheKey := hashToken(authHeader[1])
+
+		a.scimKeys.mu.Lock()
+		verified, ok := a.scimKeys.entries[cacheKey]
+		a.scimKeys.mu.Unlock()
+
+		if !ok || time.Now().After(verified.expires) {
+			valid, err := a.CheckKey(account, AccessKey{Name: ScimKeyName, Key: key})
+			if err != nil || !valid {
+				scimError(c, 401, "", "Invalid bearer token.")
+				return
+			}
+
+			a.scimKeys.mu.Lock()
+			if a.scimKeys.entries == nil {
+				a.scimKeys.entries = make(map[string]scimKeyEntry)
+			}
+			a.scimKeys.entries[cacheKey] = scimKeyEntry{account: account, expires: time.Now().Add(time.Minute)}
+			a.scimKeys.mu.Unlock()
+		}
+
+		c.Set("ScimAccount", account)
+	}
+}
+
+// ScimListUsersHandler
+func (a *Api) ScimListUsersHandler(c *gin.Context) {
+	account := c.GetString("ScimAccount")
+
+	must, ok := scimFilter(c.Query("filter"))
+	if !ok {
+		scimError(c, 400, "invalidFilter", "Unsupported filter.")
+		return
+	}
+
+	must = append(must, es.Obj{"term": es.Obj{"accounts": account}})
+
+	startIndex, count := scimPage(c)
+
+	// sysops are not managed through SCIM
+	query := &es.Obj{
+		"from": startIndex - 1,
+		"size": count,
+		"query": es.Obj{
+			"bool": es.Obj{
+				"filter":   must,
+				"must_not": []es.Obj{{"term": es.Obj{"sysop": true}}},
+			},
+		},
+		"sort": []es.Obj{
+			{"_id": "asc"},
+		},
+	}
+
+	code, usResults, errorResponse, err := a.SearchUsers(query)
+	if err != nil || code != 200 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		scimError(c, 500, "", "Error communicating with database.")
+		return
+	}
+
+	res := ScimListResponse{
+		Schemas:      []string{scimSchemaList},
+		TotalResults: usResults.Hits.Total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(usResults.Hits.Hits),
+		Resources:    make([]interface{}, 0),
+	}
+
+	for _, hit := range usResults.Hits.Hits {
+		res.Resources = append(res.Resources, scimUser(&hit.Source, account))
+	}
+
+	scimSend(c, 200, res)
+}
+
+// ScimGetUserHandler
+func (a *Api) ScimGetUserHandler(c *gin.Context) {
+	account := c.GetString("ScimAccount")
+
+	user, ok := a.scimAccountUser(c, account, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	scimSend(c, 200, scimUser(user, account))
+}
+
+// ScimCreateUserHandler
+func (a *Api) ScimCreateUserHandler(c *gin.Context) {
+	account := c.GetString("ScimAccount")
+
+	su := &ScimUser{}
+	err := c.ShouldBindJSON(su)
+	if err != nil {
+		scimError(c, 400, "invalidSyntax", err.Error())
+		return
+	}
+
+	if su.UserName == "" {
+		scimError(c, 400, "invalidValue", "userName is required.")
+		return
+	}
+
+	code, userResult, err := a.GetUser(su.UserName)
+	if err != nil {
+		scimError(c, 500, "", "Error communicating with database.")
+		return
+	}
+
+	if code == 200 {
+		// users outside the account are answered like unknown users
+		// so their existence is not revealed
+		if userResult.Source.Sysop || !stringInSlice(account, userResult.Source.Accounts) {
+			scimError(c, 404, "", "User "+su.UserName+" not found.")
+			return
+		}
+
+		scimError(c, 409, "uniqueness", "User "+su.UserName+" already exists.")
+		return
+	}
+
+	// users provisioned through SCIM receive a random password and
+	// authenticate through the account's configured authenticator
+	pw, err := randomToken(32)
+	if err != nil {
+		scimError(c, 500, "", err.Error())
+		return
+	}
+
+	user := &User{
+		Id:       su.UserName,
+		Active:   true,
+		Password: pw,
+		Accounts: []string{account},
+	}
+
+	applyScimUser(user, su)
+
+	if !a.scimPutUser(c, user, true) {
+		return
+	}
+
+	scimSend(c, 201, scimUser(user, account))
+}
+
+// ScimReplaceUserHandler
+func (a *Api) ScimReplaceUserHandler(c *gin.Context) {
+	account := c.GetString("ScimAccount")
+
+	user, ok := a.scimAccountUser(c, account, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	su := &ScimUser{}
+	err := c.ShouldBindJSON(su)
+	if err != nil {
+		scimError(c, 400, "invalidSyntax", err.Error())
+		return
+	}
+
+	applyScimUser(user, su)
+
+	if !a.scimPutUser(c, user, false) {
+		return
+	}
+
+	scimSend(c, 200, scimUser(user, account))
+}
+
+// ScimPatchUserHandler
+func (a *Api) ScimPatchUserHandler(c *gin.Context) {
+	account := c.GetString("ScimAccount")
+
+	user, ok := a.scimAccountUser(c, account, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	patch := &ScimPatchOp{}
+	err := c.ShouldBindJSON(patch)
+	if err != nil {
+		scimError(c, 400, "invalidSyntax", err.Error())
+		return
+	}
+
+	for _, op := range patch.Operations {
+		opName := strings.ToLower(op.Op)
+		if opName != "add" && opName != "replace" && opName != "remove" {
+			scimError(c, 400, "invalidValue", "Unsupported operation "+op.Op+".")
+			return
+		}
+
+		// no path, the value is a partial user
+		if op.Path == "" {
+			su := &ScimUser{}
+			err = json.Unmarshal(op.Value, su)
+			if err != nil {
+				scimError(c, 400, "invalidValue", err.Error())
+				return
+			}
+
+			applyScimUser(user, su)
+			continue
+		}
+
+		switch strings.ToLower(op.Path) {
+		case "active":
+			if opName == "remove" {
+				user.Active = false
+				continue
+			}
+			active, ok := scimBool(op.Value)
+			if !ok {
+				scimError(c, 400, "invalidValue", "active must be a boolean.")
+				return
+			}
+			user.Active = active
+		case "displayname":
+			user.DisplayName = scimString(op.Value, opName)
+		case "name.formatted":
+			user.Name = scimString(op.Value, opName)
+		case "emails", `emails[type eq "work"].value`, "emails.value":
+			if opName == "remove" {
+				user.Email = ""
+				continue
+			}
+
+			emails := make([]ScimEmail, 0)
+			if json.Unmarshal(op.Value, &emails) == nil && len(emails) > 0 {
+				user.Email = emails[0].Value
+				continue
+			}
+			user.Email = scimString(op.Value, opName)
+		default:
+			scimError(c, 400, "invalidPath", "Unsupported path "+op.Path+".")
+			return
+		}
+	}
+
+	if !a.scimPutUser(c, user, false) {
+		return
+	}
+
+	scimSend(c, 200, scimUser(user, account))
+}
+
+// ScimDeleteUserHandler deprovisions a user by deactivating it,
+// users of other accounts are only removed from the account
+func (a *Api) ScimDeleteUserHandler(c *gin.Context) {
+	account := c.GetString("ScimAccount")
+
+	user, ok := a.scimAccountUser(c, account, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if scimSharedUser(user, account) {
+		scimRemoveAccount(user, account)
+	} else {
+		user.Active = false
+	}
+
+	if !a.scimPutUser(c, user, false) {
+		return
+	}
+
+	c.Status(204)
+}
+
+// ScimListGroupsHandler
+func (a *Api) ScimListGroupsHandler(c *gin.Context) {
+	account := c.GetString("ScimAccount")
+
+	res := ScimListResponse{
+		Schemas:    []string{scimSchemaList},
+		StartIndex: 1,
+		Resources:  make([]interface{}, 0),
+	}
+
+	// only displayName eq filters are meaningful for two groups
+	filter := c.Query("filter")
+	for _, id := range []string{ScimGroupMembers, ScimGroupAdmins} {
+		if filter != "" && !strings.Contains(filter, `"`+id+`"`) {
+			continue
+		}
+
+		group, err := a.scimGroup(account, id)
+		if err != nil {
+			scimError(c, 500, "", "Error communicating with database.")
+			return
+		}
+
+		res.Resources = append(res.Resources, group)
+	}
+
+	res.TotalResults = len(res.Resources)
+	res.ItemsPerPage = len(res.Resources)
+
+	scimSend(c, 200, res)
+}
+
+// ScimGetGroupHandler
+func (a *Api) ScimGetGroupHandler(c *gin.Context) {
+	account := c.GetString("ScimAccount")
+
+	id := c.Param("id")
+	if id != ScimGroupMembers && id != ScimGroupAdmins {
+		scimError(c, 404, "", "Group "+id+" not found.")
+		return
+	}
+
+	group, err := a.scimGroup(account, id)
+	if err != nil {
+		scimError(c, 500, "", "Error communicating with database.")
+		return
+	}
+
+	scimSend(c, 200, group)
+}
+
+// ScimPatchGroupHandler adds or removes group members
+func (a *Api) ScimPatchGroupHandler(c *gin.Context) {
+	account := c.GetString("ScimAccount")
+
+	id := c.Param("id")
+	if id != ScimGroupMembers && id != ScimGroupAdmins {
+		scimError(c, 404, "", "Group "+id+" not found.")
+		return
+	}
+
+	patch := &ScimPatchOp{}
+	err := c.ShouldBindJSON(patch)
+	if err != nil {
+		scimError(c, 400, "invalidSyntax", err.Error())
+		return
+	}
+
+	for _, op := range patch.Operations {
+		opName := strings.ToLower(op.Op)
+		path := strings.ToLower(op.Path)
+
+		members := make([]ScimMember, 0)
+		if len(op.Value) > 0 {
+			_ = json.Unmarshal(op.Value, &members)
+		}
+
+		// remove with a filtered path: members[value eq "id"]
+		if m := scimMemberPath.FindStringSubmatch(op.Path); m != nil {
+			members = append(members, ScimMember{Value: m[1]})
+			path = "members"
+		}
+
+		if path != "members" {
+			scimError(c, 400, "invalidPath", "Unsupported path "+op.Path+".")
+			return
+		}
+
+		for _, m := range members {
+			var ok bool
+			switch opName {
+			case "add", "replace":
+				ok = a.scimSetMembership(c, account, id, m.Value, true)
+			case "remove":
+				ok = a.scimSetMembership(c, account, id, m.Value, false)
+			default:
+				scimError(c, 400, "invalidValue", "Unsupported operation "+op.Op+".")
+				return
+			}
+
+			if !ok {
+				return
+			}
+		}
+	}
+
+	c.Status(204)
+}
+
+// scimSetMembership adds or removes a user of the account from
+// an account group. Users enter the account through user
+// provisioning, never through groups.
+func (a *Api) scimSetMembership(c *gin.Context, account string, group string, userId string, member bool) bool {
+	user, ok := a.scimAccountUser(c, account, userId)
+	if !ok {
+		return false
+	}
+
+	switch {
+	case member && group == ScimGroupAdmins:
+		user.AdminAccounts = appendUnique(user.AdminAccounts, account)
+	case member:
+		return true
+	case group == ScimGroupAdmins:
+		user.AdminAccounts = removeString(user.AdminAccounts, account)
+		for i := range user.Memberships {
+			if user.Memberships[i].Account == account {
+				user.Memberships[i].Admin = false
+			}
+		}
+	default:
+		scimRemoveAccount(user, account)
+	}
+
+	return a.scimPutUser(c, user, false)
+}
+
+// scimSharedUser returns true if the user belongs to accounts
+// other than account
+func scimSharedUser(user *User, account string) bool {
+	for _, acc := range user.Accounts {
+		if acc != account {
+			return true
+		}
+	}
+
+	for _, acc := range user.AdminAccounts {
+		if acc != account {
+			return true
+		}
+	}
+
+	for _, m := range user.Memberships {
+		if m.Account != account {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scimRemoveAccount removes the account from the user's accounts,
+// admin accounts and memberships
+func scimRemoveAccount(user *User, account string) {
+	user.Accounts = removeString(user.Accounts, account)
+	user.AdminAccounts = removeString(user.AdminAccounts, account)
+
+	memberships := make([]Membership, 0, len(user.Memberships))
+	for _, m := range user.Memberships {
+		if m.Account != account {
+			memberships = append(memberships, m)
+		}
+	}
+	user.Memberships = memberships
+}
+
+// scimGroup builds an account group from its users
+func (a *Api) scimGroup(account string, id string) (*ScimGroup, error) {
+	field := "accounts"
+	display := "Members"
+	if id == ScimGroupAdmins {
+		field = "admin_accounts"
+		display = "Admins"
+	}
+
+	query := es.Obj{
+		"_source": []string{"id", "display_name"},
+		"query": es.Obj{
+			"term": es.Obj{field: account},
+		},
+	}
+
+	group := &ScimGroup{
+		Schemas:     []string{scimSchemaGroup},
+		Id:          id,
+		DisplayName: display,
+		Members:     make([]ScimMember, 0),
+		Meta:        &ScimMeta{ResourceType: "Group"},
+	}
+
+	page := &SearchQuery{Size: SearchSizeMax}
+
+	for {
+		obj, err := page.page(query, nil, UserSearchFields.Tiebreak)
+		if err != nil {
+			return nil, err
+		}
+
+		code, results, errorResponse, err := a.SearchUsers(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if code == 404 {
+			return group, nil
+		}
+
+		if code != 200 {
+			if errorResponse != nil {
+				a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+			}
+			return nil, fmt.Errorf("got code %d finding group members", code)
+		}
+
+		for _, hit := range results.Hits.Hits {
+			group.Members = append(group.Members, ScimMember{
+				Value:   hit.Source.Id,
+				Display: hit.Source.DisplayName,
+			})
+		}
+
+		if results.NextCursor == "" {
+			return group, nil
+		}
+
+		page.Cursor = results.NextCursor
+	}
+}
+
+// scimAccountUser returns a user associated with the account or
+// responds not found. Sysops are not managed through SCIM.
+func (a *Api) scimAccountUser(c *gin.Context, account string, id string) (*User, bool) {
+	code, userResult, err := a.GetUser(id)
+	if err != nil {
+		scimError(c, 500, "", "Error communicating with database.")
+		return nil, false
+	}
+
+	if code != 200 || userResult.Source.Sysop || !stringInSlice(account, userResult.Source.Accounts) {
+		scimError(c, 404, "", "User "+id+" not found.")
+		return nil, false
+	}
+
+	return &userResult.Source, true
+}
+
+// scimPutUser stores a user, new users are upserted so
+// their password is hashed.
+func (a *Api) scimPutUser(c *gin.Context, user *User, create bool) bool {
+	var (
+		code          int
+		errorResponse *es.ErrorResponse
+		err           error
+	)
+
+	if create {
+		code, _, errorResponse, err = a.UpsertUser(user)
+	} else {
+		// cut off existing sessions for deactivated users
+		if !user.Active {
+			err = a.RevokeUserTokens(user.Id)
+			if err != nil {
+				scimError(c, 500, "", err.Error())
+				return false
+			}
+		}
+		code, _, errorResponse, err = a.putUser(user)
+	}
+
+	if err != nil || code < 200 || code >= 300 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		if err != nil {
+			a.Logger.Error("ScimUpsertError", zap.Error(err))
+		}
+		scimError(c, 500, "", "There was a problem storing the user.")
+		return false
+	}
+
+	return true
+}
+
+// scimUser converts a User to its SCIM representation
+func scimUser(user *User, account string) ScimUser {
+	active := user.Active
+	su := ScimUser{
+		Schemas:     []string{scimSchemaUser},
+		Id:          user.Id,
+		UserName:    user.Id,
+		DisplayName: user.DisplayName,
+		Active:      &active,
+		Groups:      []ScimMember{{Value: ScimGroupMembers, Display: "Members"}},
+		Meta:        &ScimMeta{ResourceType: "User"},
+	}
+
+	if user.Name != "" {
+		su.Name = &ScimName{Formatted: user.Name}
+	}
+
+	if user.Email != "" {
+		su.Emails = []ScimEmail{{Value: user.Email, Primary: true}}
+	}
+
+	if stringInSlice(account, user.AdminAccounts) {
+		su.Groups = append(su.Groups, ScimMember{Value: ScimGroupAdmins, Display: "Admins"})
+	}
+
+	return su
+}
+
+// applyScimUser copies SCIM attributes onto a User
+func applyScimUser(user *User, su *ScimUser) {
+	if su.DisplayName != "" {
+		user.DisplayName = su.DisplayName
+	}
+
+	if su.Name != nil && su.Name.Formatted != "" {
+		user.Name = su.Name.Formatted
+	}
+
+	if su.Active != nil {
+		user.Active = *su.Active
+	}
+
+	for i, email := range su.Emails {
+		if email.Primary || i == 0 {
+			user.Email = email.Value
+		}
+	}
+}
+
+var (
+	scimFilterExpr = regexp.MustCompile(`(?i)^\s*([a-z.]+)\s+(eq|co|sw|pr)\s*(?:"((?:[^"\\]|\\.)*)"|(true|false))?\s*$`)
+	scimMemberPath = regexp.MustCompile(`(?i)^members\[value eq "([^"]+)"\]$`)
+	scimAndSplit   = regexp.MustCompile(`(?i)\s+and\s+`)
+)
+
+// scimFilter translates a SCIM filter into Elasticsearch filter
+// clauses. Supports eq, co, sw and pr joined with and.
+func scimFilter(filter string) ([]es.Obj, bool) {
+	must := make([]es.Obj, 0)
+	if strings.TrimSpace(filter) == "" {
+		return must, true
+	}
+
+	for _, expr := range scimAndSplit.Split(filter, -1) {
+		m := scimFilterExpr.FindStringSubmatch(expr)
+		if m == nil {
+			return nil, false
+		}
+
+		attr, op, value := strings.ToLower(m[1]), strings.ToLower(m[2]), m[3]
+		if m[4] != "" {
+			value = strings.ToLower(m[4])
+		}
+
+		field := ""
+		switch attr {
+		case "username", "id":
+			if op == "eq" {
+				must = append(must, es.Obj{"ids": es.Obj{"values": []string{value}}})
+				continue
+			}
+			field = "id"
+		case "displayname":
+			field = "display_name"
+		case "emails", "emails.value":
+			field = "email"
+		case "name.formatted":
+			field = "name"
+		case "active":
+			field = "active"
+		default:
+			return nil, false
+		}
+
+		switch op {
+		case "eq":
+			if field == "active" {
+				must = append(must, es.Obj{"term": es.Obj{field: value == "true"}})
+				continue
+			}
+			if field == "email" {
+				must = append(must, es.Obj{"term": es.Obj{field: value}})
+				continue
+			}
+			must = append(must, es.Obj{"match_phrase": es.Obj{field: value}})
+		case "co":
+			must = append(must, es.Obj{"wildcard": es.Obj{field: "*" + value + "*"}})
+		case "sw":
+			must = append(must, es.Obj{"prefix": es.Obj{field: value}})
+		case "pr":
+			must = append(must, es.Obj{"exists": es.Obj{"field": field}})
+		}
+	}
+
+	return must, true
+}
+
+// scimPage returns the 1 based startIndex and count
+func scimPage(c *gin.Context) (int, int) {
+	startIndex, err := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", "100"))
+	if err != nil || count < 0 {
+		count = 100
+	}
+
+	if count > ScimMaxCount {
+		count = ScimMaxCount
+	}
+
+	return startIndex, count
+}
+
+// scimBool accepts a boolean or a string boolean as sent
+// by some identity providers
+func scimBool(raw json.RawMessage) (bool, bool) {
+	var b bool
+	if json.Unmarshal(raw, &b) == nil {
+		return b, true
+	}
+
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		b, err := strconv.ParseBool(s)
+		return b, err == nil
+	}
+
+	return false, false
+}
+
+// scimString returns a string value or empty for remove
+func scimString(raw json.RawMessage, op string) string {
+	if op == "remove" {
+		return ""
+	}
+
+	var s string
+	_ = json.Unmarshal(raw, &s)
+
+	return s
+}
+
+// scimSend responds with a SCIM content type
+func scimSend(c *gin.Context, code int, obj interface{}) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(code, obj)
+}
+
+// scimError aborts with a SCIM error response
+func scimError(c *gin.Context, code int, scimType string, detail string) {
+	c.Header("Content-Type", scimContentType)
+	c.AbortWithStatusJSON(code, ScimError{
+		Schemas:  []string{scimSchemaError},
+		Status:   strconv.Itoa(code),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+// appendUnique appends s to list if not present
+func appendUnique(list []string, s string) []string {
+	if stringInSlice(s, list) {
+		return list
+	}
+
+	return append(list, s)
+}
+
+// removeString returns list without s
+func removeString(list []string, s string) []string {
+	out := make([]string, 0)
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
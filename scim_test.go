@@ -0,0 +1,95 @@
+package provision
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestScimGroupSort(t *testing.T) {
+	searches := 0
+	a, srv := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		searches++
+
+		body := struct {
+			Sort []map[string]interface{} `json:"sort"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Decode: %s", err.Error())
+		}
+
+		// the user id is a text field and can not be sorted
+		want := []map[string]interface{}{{"_id": map[string]interface{}{"order": "asc"}}}
+		if !reflect.DeepEqual(body.Sort, want) {
+			t.Errorf("sort = %v, want %v", body.Sort, want)
+		}
+
+		w.Write([]byte(`{"hits":{"hits":[{"_source":{"id":"u1","display_name":"User"},"sort":["u1"]}]}}`))
+	})
+	defer srv.Close()
+
+	group, err := a.scimGroup("acme", ScimGroupAdmins)
+	if err != nil {
+		t.Fatalf("scimGroup: %s", err.Error())
+	}
+
+	if searches != 1 || len(group.Members) != 1 || group.Members[0].Value != "u1" {
+		t.Errorf("got %d searches and members %+v", searches, group.Members)
+	}
+}
+
+func TestScimCreateUserConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tt := []struct {
+		name string
+		user string
+		code int
+	}{
+		{"user of the account", "u1", 409},
+		{"user of another account", "u2", 404},
+		{"sysop", "root", 404},
+		{"new user", "u3", 201},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newMemEs()
+			db.put(IdxUser+"/u1", User{Id: "u1", Active: true, Accounts: []string{"acme"}})
+			db.put(IdxUser+"/u2", User{Id: "u2", Active: true, Accounts: []string{"other"}})
+			db.put(IdxUser+"/root", User{Id: "root", Active: true, Sysop: true})
+
+			a, srv := newTestApi(db.ServeHTTP)
+			defer srv.Close()
+
+			router := gin.New()
+			router.POST("/scim/v2/Users", func(c *gin.Context) {
+				c.Set("ScimAccount", "acme")
+				a.ScimCreateUserHandler(c)
+			})
+
+			body := `{"userName": "` + tc.user + `", "displayName": "Changed"}`
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/scim/v2/Users", strings.NewReader(body)))
+			if w.Code != tc.code {
+				t.Fatalf("got %d, want %d: %s", w.Code, tc.code, w.Body.String())
+			}
+
+			// existing users are never overwritten
+			stored := User{}
+			db.get(IdxUser+"/"+tc.user, &stored)
+			if tc.code != 201 && stored.DisplayName == "Changed" {
+				t.Errorf("user %s was overwritten", tc.user)
+			}
+			if tc.code == 201 && !stringInSlice("acme", stored.Accounts) {
+				t.Errorf("accounts %v, want acme", stored.Accounts)
+			}
+		})
+	}
+}
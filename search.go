@@ -1,8 +1,6 @@
 package provision
 
 import (
-	"fmt"
-
 	"github.com/gin-gonic/gin"
 	"github.com/txn2/ack"
 	"github.com/txn2/es/v2"
@@ -17,6 +15,9 @@ type AssetSearchResults struct {
 		MaxScore float64       `json:"max_score"`
 		Hits     []AssetResult `json:"hits"`
 	} `json:"hits"`
+
+	// position after the last hit, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // AssetSearchResultsAck
@@ -33,6 +34,9 @@ type AccountSearchResults struct {
 		MaxScore float64         `json:"max_score"`
 		Hits     []AccountResult `json:"hits"`
 	} `json:"hits"`
+
+	// position after the last hit, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // AccountSearchResultsAck
@@ -49,6 +53,9 @@ type UserSearchResults struct {
 		MaxScore float64      `json:"max_score"`
 		Hits     []UserResult `json:"hits"`
 	} `json:"hits"`
+
+	// position after the last hit, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // UserSearchResultsAck
@@ -61,26 +68,46 @@ type UserSearchResultsAck struct {
 func (a *Api) SearchAssets(searchObj *es.Obj) (int, AssetSearchResults, *es.ErrorResponse, error) {
 	asResults := &AssetSearchResults{}
 
-	code, errorResponse, err := a.Elastic.PostObjUnmarshal(fmt.Sprintf("%s/_search", a.IdxPrefix+IdxAsset), searchObj, asResults)
+	code, nextCursor, errorResponse, err := a.searchPage(IdxAsset, searchObj, asResults)
 	if err != nil {
 		a.Logger.Error("EsError", zap.Error(err))
 		return code, *asResults, errorResponse, err
 	}
 
+	asResults.NextCursor = nextCursor
+
 	return code, *asResults, nil, nil
 }
 
-// SearchAssetsHandler
+// SearchAssetsHandler searches assets with a posted SearchQuery.
+// With Config.RawSearch sysops may post Elasticsearch DSL
+// with dsl=true.
 func (a *Api) SearchAssetsHandler(c *gin.Context) {
 	ak := ack.Gin(c)
 
-	obj := &es.Obj{}
-	err := ak.UnmarshalPostAbort(obj)
-	if err != nil {
-		a.Logger.Error("Search failure.", zap.Error(err))
+	obj, ok := a.searchObj(c, ak, AssetSearchFields)
+	if !ok {
 		return
 	}
 
+	a.searchAssetsSend(ak, obj)
+}
+
+// SearchAdmAssetsHandler searches the assets of :parentAccount and
+// its descendant accounts with a posted SearchQuery.
+func (a *Api) SearchAdmAssetsHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	obj, ok := a.admSearchObj(c, ak, AssetSearchFields)
+	if !ok {
+		return
+	}
+
+	a.searchAssetsSend(ak, obj)
+}
+
+// searchAssetsSend
+func (a *Api) searchAssetsSend(ak ack.GinAck, obj *es.Obj) {
 	code, esResult, errorResponse, err := a.SearchAssets(obj)
 	if err != nil {
 		a.Logger.Error("EsError", zap.Error(err))
@@ -109,11 +136,13 @@ func (a *Api) SearchAssetsHandler(c *gin.Context) {
 func (a *Api) SearchAccounts(searchObj *es.Obj) (int, AccountSearchResults, *es.ErrorResponse, error) {
 	asResults := &AccountSearchResults{}
 
-	code, errorResponse, err := a.Elastic.PostObjUnmarshal(fmt.Sprintf("%s/_search", a.IdxPrefix+IdxAccount), searchObj, asResults)
+	code, nextCursor, errorResponse, err := a.searchPage(IdxAccount, searchObj, asResults)
 	if err != nil {
 		return code, *asResults, errorResponse, err
 	}
 
+	asResults.NextCursor = nextCursor
+
 	// Redact Keys
 	for i := range asResults.Hits.Hits {
 		for ii := range asResults.Hits.Hits[i].Source.AccessKeys {
@@ -124,14 +153,14 @@ func (a *Api) SearchAccounts(searchObj *es.Obj) (int, AccountSearchResults, *es.
 	return code, *asResults, nil, nil
 }
 
-// SearchAccountsHandler
+// SearchAccountsHandler searches accounts with a posted SearchQuery.
+// With Config.RawSearch sysops may post Elasticsearch DSL
+// with dsl=true.
 func (a *Api) SearchAccountsHandler(c *gin.Context) {
 	ak := ack.Gin(c)
 
-	obj := &es.Obj{}
-	err := ak.UnmarshalPostAbort(obj)
-	if err != nil {
-		a.Logger.Error("Search failure.", zap.Error(err))
+	obj, ok := a.searchObj(c, ak, AccountSearchFields)
+	if !ok {
 		return
 	}
 
@@ -163,31 +192,51 @@ func (a *Api) SearchAccountsHandler(c *gin.Context) {
 func (a *Api) SearchUsers(searchObj *es.Obj) (int, UserSearchResults, *es.ErrorResponse, error) {
 	usResults := &UserSearchResults{}
 
-	code, errorResponse, err := a.Elastic.PostObjUnmarshal(fmt.Sprintf("%s/_search", a.IdxPrefix+IdxUser), searchObj, usResults)
+	code, nextCursor, errorResponse, err := a.searchPage(IdxUser, searchObj, usResults)
 	if err != nil {
 		return code, *usResults, errorResponse, err
 	}
 
+	usResults.NextCursor = nextCursor
+
 	// Redact Passwords
 	for i := range usResults.Hits.Hits {
 		usResults.Hits.Hits[i].Source.Password = RedactMsg
+		usResults.Hits.Hits[i].Source.RedactApiTokens()
 	}
 
 	return code, *usResults, nil, nil
 }
 
-// SearchUsersHandler
+// SearchUsersHandler searches users with a posted SearchQuery.
+// With Config.RawSearch sysops may post Elasticsearch DSL
+// with dsl=true.
 func (a *Api) SearchUsersHandler(c *gin.Context) {
 	ak := ack.Gin(c)
 
-	obj := &es.Obj{}
-	err := ak.UnmarshalPostAbort(obj)
-	if err != nil {
-		a.Logger.Error("EsError", zap.Error(err))
-		a.Logger.Error("Search failure.", zap.Error(err))
+	obj, ok := a.searchObj(c, ak, UserSearchFields)
+	if !ok {
+		return
+	}
+
+	a.searchUsersSend(ak, obj)
+}
+
+// SearchAdmUsersHandler searches the users of :parentAccount and
+// its descendant accounts with a posted SearchQuery.
+func (a *Api) SearchAdmUsersHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	obj, ok := a.admSearchObj(c, ak, UserSearchFields)
+	if !ok {
 		return
 	}
 
+	a.searchUsersSend(ak, obj)
+}
+
+// searchUsersSend
+func (a *Api) searchUsersSend(ak ack.GinAck, obj *es.Obj) {
 	code, esResult, errorResponse, err := a.SearchUsers(obj)
 	if err != nil {
 		a.Logger.Error("EsError", zap.Error(err))
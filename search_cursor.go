@@ -0,0 +1,210 @@
+package provision
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"github.com/txn2/es/v2"
+	"go.uber.org/zap"
+)
+
+// Page selects a page of a list route with the size and cursor
+// query parameters
+type Page struct {
+	Size   int    `json:"size,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// encodeCursor makes an opaque cursor of the sort values of the
+// last hit of a page
+func encodeCursor(sort []interface{}) string {
+	js, err := json.Marshal(sort)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// decodeCursor returns the sort values for search_after
+func decodeCursor(cursor string) ([]interface{}, error) {
+	js, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	sort := make([]interface{}, 0)
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+
+	err = dec.Decode(&sort)
+	if err != nil {
+		return nil, err
+	}
+
+	return sort, nil
+}
+
+// cursorResults unmarshals search results into results and keeps
+// the number of hits and the sort values of the last hit
+type cursorResults struct {
+	results interface{}
+	hits    int
+	last    []interface{}
+}
+
+// UnmarshalJSON
+func (cr *cursorResults) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, cr.results)
+	if err != nil {
+		return err
+	}
+
+	sorts := struct {
+		Hits struct {
+			Hits []struct {
+				Sort []interface{} `json:"sort"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}{}
+
+	// sort values are kept as json.Number for exact longs
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	err = dec.Decode(&sorts)
+	if err != nil {
+		return err
+	}
+
+	cr.hits = len(sorts.Hits.Hits)
+	if cr.hits > 0 {
+		cr.last = sorts.Hits.Hits[cr.hits-1].Sort
+	}
+
+	return nil
+}
+
+// searchPage searches idx unmarshaling into results, returning
+// the cursor of the next page when the page is full and sorted
+func (a *Api) searchPage(idx string, searchObj *es.Obj, results interface{}) (int, string, *es.ErrorResponse, error) {
+	cr := &cursorResults{results: results}
+
+	code, errorResponse, err := a.Elastic.PostObjUnmarshal(fmt.Sprintf("%s/_search", a.IdxPrefix+idx), searchObj, cr)
+	if err != nil || code != 200 {
+		return code, "", errorResponse, err
+	}
+
+	if len(cr.last) == 0 || cr.hits < objSize(searchObj) {
+		return code, "", nil, nil
+	}
+
+	return code, encodeCursor(cr.last), nil, nil
+}
+
+// objSize is the size of a search, the Elasticsearch default
+// when not set
+func objSize(searchObj *es.Obj) int {
+	if searchObj == nil {
+		return 10
+	}
+
+	switch size := (*searchObj)["size"].(type) {
+	case int:
+		return size
+	case float64:
+		return int(size)
+	case json.Number:
+		n, _ := size.Int64()
+		return int(n)
+	}
+
+	return 10
+}
+
+// listPage reads the size and cursor query parameters of a list
+// route. Aborts the request and returns false on failure.
+func (a *Api) listPage(c *gin.Context, ak ack.GinAck) (*SearchQuery, bool) {
+	page := Page{Cursor: c.Query("cursor")}
+
+	if size := c.Query("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			ak.SetPayloadType("ValidationError")
+			ak.SetPayload("size must be a number")
+			ak.GinErrorAbort(400, "ValidationError", "Invalid page.")
+			return nil, false
+		}
+		page.Size = n
+	}
+
+	return &SearchQuery{Size: page.Size, Cursor: page.Cursor}, true
+}
+
+// listObj pages a list route query sorted by tiebreak, a unique
+// field. Aborts the request and returns false on failure.
+func (a *Api) listObj(c *gin.Context, ak ack.GinAck, query es.Obj, tiebreak string) (*es.Obj, bool) {
+	sq, ok := a.listPage(c, ak)
+	if !ok {
+		return nil, false
+	}
+
+	obj, err := sq.page(query, nil, tiebreak)
+	if err != nil {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload(err.Error())
+		ak.GinErrorAbort(400, "ValidationError", "Invalid page.")
+		return nil, false
+	}
+
+	return obj, true
+}
+
+// dslObj reads Elasticsearch DSL from the request, limited to
+// SearchSizeMax hits and continued after the cursor query
+// parameter. Aborts the request and returns false on failure.
+func (a *Api) dslObj(c *gin.Context, ak ack.GinAck) (*es.Obj, bool) {
+	obj := &es.Obj{}
+	err := ak.UnmarshalPostAbort(obj)
+	if err != nil {
+		a.Logger.Error("Search failure.", zap.Error(err))
+		return nil, false
+	}
+
+	err = dslPage(obj, c.Query("cursor"))
+	if err != nil {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload(err.Error())
+		ak.GinErrorAbort(400, "ValidationError", "Invalid search.")
+		return nil, false
+	}
+
+	return obj, true
+}
+
+// dslPage limits Elasticsearch DSL to SearchSizeMax hits and
+// continues after cursor if not empty
+func dslPage(obj *es.Obj, cursor string) error {
+	if size := objSize(obj); size < 0 || size > SearchSizeMax {
+		return fmt.Errorf("size must be between 0 and %d", SearchSizeMax)
+	}
+
+	if cursor == "" {
+		return nil
+	}
+
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return errors.New("invalid cursor")
+	}
+
+	(*obj)["search_after"] = after
+
+	return nil
+}
@@ -0,0 +1,401 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"github.com/txn2/es/v2"
+	"github.com/txn2/token"
+	"go.uber.org/zap"
+)
+
+// SearchSizeDefault is the page size of a SearchQuery without one
+const SearchSizeDefault = 20
+
+// SearchSizeMax is the largest page size of a SearchQuery
+const SearchSizeMax = 100
+
+// SearchWindowMax limits from + size, the Elasticsearch default
+// max_result_window
+const SearchWindowMax = 10000
+
+// SearchTextMax is the longest text query
+const SearchTextMax = 256
+
+// SearchTiebreakDefault is the field sorted last by searches and
+// lists, making pages stable for cursors
+const SearchTiebreakDefault = "id"
+
+// SearchQuery is a search of accounts, users, assets, roles or
+// groups translated
+// to Elasticsearch. Text matches the text fields of the index,
+// each filter matches any of its values and all filters must
+// match.
+type SearchQuery struct {
+	Text    string              `json:"text,omitempty"`
+	Ids     []string            `json:"ids,omitempty"`
+	Filters map[string][]string `json:"filters,omitempty"`
+	Sort    []SearchSort        `json:"sort,omitempty"`
+	From    int                 `json:"from,omitempty"`
+	Size    int                 `json:"size,omitempty"`
+
+	// next_cursor of the previous page, in place of from
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// SearchSort orders results by a field, ascending unless Desc
+type SearchSort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// SearchFields are the fields of an index available to a
+// SearchQuery
+type SearchFields struct {
+	// matched by SearchQuery.Text
+	Text []string
+
+	// keyword, boolean and numeric fields for filters
+	Filter []string
+
+	// filter fields in nested documents and their path
+	Nested map[string]string
+
+	Sort []string
+
+	// never returned by a SearchQuery
+	Exclude []string
+
+	// fields holding the accounts of a document, any of which
+	// places it in the scope of a tenant search
+	Accounts []string
+
+	// unique keyword field sorted last for a stable order,
+	// defaults to SearchTiebreakDefault
+	Tiebreak string
+}
+
+// AccountSearchFields
+var AccountSearchFields = SearchFields{
+	Text:    []string{"display_name", "description"},
+	Filter:  []string{"id", "parent", "active", "org_id", "modules"},
+	Sort:    []string{"id", "parent", "active", "org_id"},
+	Exclude: []string{"access_keys.key"},
+}
+
+// UserSearchFields
+var UserSearchFields = SearchFields{
+	Text: []string{"id", "display_name", "description"},
+	Filter: []string{"email", "identities", "active", "sysop", "sections_all", "deny_sections",
+		"accounts", "admin_accounts", "roles.account", "roles.roles",
+		"memberships.account", "memberships.sections", "memberships.roles", "memberships.admin"},
+	Sort:     []string{"email", "active", "sysop", "epoch"},
+	Exclude:  []string{"password", "api_tokens.hash"},
+	Accounts: []string{"accounts", "admin_accounts"},
+
+	// the user id is a text field
+	Tiebreak: "_id",
+}
+
+// AssetSearchFields
+var AssetSearchFields = SearchFields{
+	Text:   []string{"display_name", "description"},
+	Filter: []string{"id", "account_id", "active", "asset_class"},
+	Nested: map[string]string{
+		"routes.account_id": "routes",
+		"routes.model_id":   "routes",
+		"routes.type":       "routes",
+	},
+	Sort:     []string{"id", "account_id", "asset_class", "active"},
+	Accounts: []string{"account_id", "routes.account_id"},
+}
+
+// RoleSearchFields
+var RoleSearchFields = SearchFields{
+	Text:     []string{"display_name", "description"},
+	Filter:   []string{"id", "account", "sections", "sections_all"},
+	Sort:     []string{"id", "account", "sections_all"},
+	Accounts: []string{"account"},
+
+	// role ids are unique within an account
+	Tiebreak: "_id",
+}
+
+// GroupSearchFields
+var GroupSearchFields = SearchFields{
+	Text: []string{"display_name", "description"},
+	Filter: []string{"id", "account", "members",
+		"grants.account", "grants.sections", "grants.roles", "grants.admin"},
+	Sort:     []string{"id", "account"},
+	Accounts: []string{"account"},
+
+	// group ids are unique within an account
+	Tiebreak: "_id",
+}
+
+// Obj translates the query to Elasticsearch, returning an error
+// for fields not in fields and out of range pages. Documents
+// must match every scope filter.
+func (sq *SearchQuery) Obj(fields SearchFields, scope ...es.Obj) (*es.Obj, error) {
+	if len(sq.Text) > SearchTextMax {
+		return nil, fmt.Errorf("text is limited to %d characters", SearchTextMax)
+	}
+
+	must := make([]es.Obj, 0)
+	if sq.Text != "" {
+		must = append(must, es.Obj{
+			"simple_query_string": es.Obj{
+				"query":            sq.Text,
+				"fields":           fields.Text,
+				"default_operator": "and",
+			},
+		})
+	}
+
+	filter := append(make([]es.Obj, 0), scope...)
+	if len(sq.Ids) > 0 {
+		filter = append(filter, es.Obj{"ids": es.Obj{"values": sq.Ids}})
+	}
+
+	for field, values := range sq.Filters {
+		if len(values) < 1 {
+			return nil, errors.New("filter " + field + " has no values")
+		}
+
+		terms := es.Obj{"terms": es.Obj{field: values}}
+
+		if path, ok := fields.Nested[field]; ok {
+			filter = append(filter, es.Obj{"nested": es.Obj{"path": path, "query": terms}})
+			continue
+		}
+
+		if !stringInSlice(field, fields.Filter) {
+			return nil, errors.New("unable to filter on " + field + ", use one of " + strings.Join(fields.filters(), ", "))
+		}
+
+		filter = append(filter, terms)
+	}
+
+	sort := make([]es.Obj, 0, len(sq.Sort))
+	for _, s := range sq.Sort {
+		if !stringInSlice(s.Field, fields.Sort) {
+			return nil, errors.New("unable to sort on " + s.Field + ", use one of " + strings.Join(fields.Sort, ", "))
+		}
+
+		order := "asc"
+		if s.Desc {
+			order = "desc"
+		}
+
+		sort = append(sort, es.Obj{s.Field: es.Obj{"order": order}})
+	}
+
+	// relevance first for text without a sort
+	if len(sort) == 0 && sq.Text != "" {
+		sort = append(sort, es.Obj{"_score": es.Obj{"order": "desc"}})
+	}
+
+	obj := es.Obj{
+		"query": es.Obj{
+			"bool": es.Obj{
+				"must":   must,
+				"filter": filter,
+			},
+		},
+	}
+
+	if len(fields.Exclude) > 0 {
+		obj["_source"] = es.Obj{"excludes": fields.Exclude}
+	}
+
+	return sq.page(obj, sort, fields.Tiebreak)
+}
+
+// page sets the size, sort and position of obj, sorting last on
+// tiebreak. A cursor continues after the last hit of the page
+// that returned it.
+func (sq *SearchQuery) page(obj es.Obj, sort []es.Obj, tiebreak string) (*es.Obj, error) {
+	size := sq.Size
+	if size == 0 {
+		size = SearchSizeDefault
+	}
+
+	if size < 0 || size > SearchSizeMax {
+		return nil, fmt.Errorf("size must be between 1 and %d", SearchSizeMax)
+	}
+
+	if sq.From < 0 || sq.From+size > SearchWindowMax {
+		return nil, fmt.Errorf("from + size must be less than %d, use cursor", SearchWindowMax)
+	}
+
+	if tiebreak == "" {
+		tiebreak = SearchTiebreakDefault
+	}
+
+	if !stringInSlice(tiebreak, sq.sortFields()) {
+		sort = append(sort, es.Obj{tiebreak: es.Obj{"order": "asc"}})
+	}
+
+	obj["size"] = size
+	obj["sort"] = sort
+
+	if sq.Cursor == "" {
+		obj["from"] = sq.From
+		return &obj, nil
+	}
+
+	if sq.From != 0 {
+		return nil, errors.New("from can not be used with cursor")
+	}
+
+	after, err := decodeCursor(sq.Cursor)
+	if err != nil || len(after) != len(sort) {
+		return nil, errors.New("invalid cursor, cursors are only valid for the same sort")
+	}
+
+	delete(obj, "from")
+	obj["search_after"] = after
+
+	return &obj, nil
+}
+
+// sortFields
+func (sq *SearchQuery) sortFields() []string {
+	fields := make([]string, 0, len(sq.Sort))
+	for _, s := range sq.Sort {
+		fields = append(fields, s.Field)
+	}
+
+	return fields
+}
+
+// filters lists the filter fields
+func (sf SearchFields) filters() []string {
+	filters := append([]string{}, sf.Filter...)
+	for field := range sf.Nested {
+		filters = append(filters, field)
+	}
+
+	return filters
+}
+
+// accountScope matches documents with any of the accounts in
+// any of the Accounts fields
+func (sf SearchFields) accountScope(accounts []string) es.Obj {
+	should := make([]es.Obj, 0, len(sf.Accounts))
+	for _, field := range sf.Accounts {
+		terms := es.Obj{"terms": es.Obj{field: accounts}}
+
+		if path, ok := sf.Nested[field]; ok {
+			terms = es.Obj{"nested": es.Obj{"path": path, "query": terms}}
+		}
+
+		should = append(should, terms)
+	}
+
+	return es.Obj{
+		"bool": es.Obj{
+			"should":               should,
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+// searchObj reads a SearchQuery from the request or, with
+// dsl=true, Elasticsearch DSL posted by a sysop when
+// Config.RawSearch is enabled. Aborts the request and returns
+// false on failure.
+func (a *Api) searchObj(c *gin.Context, ak ack.GinAck, fields SearchFields) (*es.Obj, bool) {
+	if c.Query("dsl") == "true" {
+		if !a.rawSearchUser(c, ak) {
+			return nil, false
+		}
+
+		return a.dslObj(c, ak)
+	}
+
+	sq := &SearchQuery{}
+	err := ak.UnmarshalPostAbort(sq)
+	if err != nil {
+		a.Logger.Error("Search failure.", zap.Error(err))
+		return nil, false
+	}
+
+	obj, err := sq.Obj(fields)
+	if err != nil {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload(err.Error())
+		ak.GinErrorAbort(400, "ValidationError", "Invalid search.")
+		return nil, false
+	}
+
+	return obj, true
+}
+
+// admSearchObj reads a SearchQuery from the request scoped to
+// :parentAccount and its descendants. Aborts the request and
+// returns false on failure.
+func (a *Api) admSearchObj(c *gin.Context, ak ack.GinAck, fields SearchFields) (*es.Obj, bool) {
+	sq := &SearchQuery{}
+	err := ak.UnmarshalPostAbort(sq)
+	if err != nil {
+		a.Logger.Error("Search failure.", zap.Error(err))
+		return nil, false
+	}
+
+	parentAccountId := c.Param("parentAccount")
+	descendants, err := a.AccountDescendants(parentAccountId)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return nil, false
+	}
+
+	accounts := append([]string{parentAccountId}, descendants...)
+
+	obj, err := sq.Obj(fields, fields.accountScope(accounts))
+	if err != nil {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload(err.Error())
+		ak.GinErrorAbort(400, "ValidationError", "Invalid search.")
+		return nil, false
+	}
+
+	return obj, true
+}
+
+// rawSearchUser returns true if raw search is enabled and the
+// token user is a sysop, aborting the request otherwise
+func (a *Api) rawSearchUser(c *gin.Context, ak ack.GinAck) bool {
+	if !a.RawSearch {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload("Elasticsearch DSL search is disabled, post a SearchQuery.")
+		ak.GinErrorAbort(400, "ValidationError", "Raw search disabled.")
+		return false
+	}
+
+	var tok *token.Tok
+	if tokI, ok := c.Get("Tok"); ok {
+		tok = tokI.(*token.Tok)
+	}
+
+	user, _, tf := a.tokenUser(apiTokenFromRequest(c), tok)
+	if tf != nil {
+		a.tokenFailureAbort(c, tf)
+		return false
+	}
+
+	if !user.Sysop {
+		ak.SetPayloadType("ErrorMessage")
+		ak.SetPayload("Elasticsearch DSL search requires a sysop.")
+		ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		return false
+	}
+
+	return true
+}
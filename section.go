@@ -0,0 +1,46 @@
+package provision
+
+import (
+	"path"
+)
+
+// SectionSeparator separates levels of hierarchical section
+// names, e.g. data:read
+const SectionSeparator = ":"
+
+// sectionMatch returns true if sec matches one of the patterns.
+// Patterns are section names or globs where * matches any run
+// of characters including the separator, so data:* matches
+// data:read and data:read:raw. Malformed patterns never match.
+func sectionMatch(sec string, patterns []string) bool {
+	for _, p := range patterns {
+		if p == sec {
+			return true
+		}
+
+		if ok, err := path.Match(p, sec); err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sectionDenied returns true if the section is denied to the user
+// globally or by a membership or group in one of the accounts.
+// Denies override every grant except sysop.
+func (u *User) sectionDenied(sec string, accounts []string) bool {
+	if sectionMatch(sec, u.DenySections) {
+		return true
+	}
+
+	for _, acc := range accounts {
+		for _, m := range u.memberships(acc) {
+			if sectionMatch(sec, m.DenySections) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -18,20 +18,30 @@ const RedactMsg = "REDACTED"
 
 // User defines a user object
 type User struct {
-	Id            string   `json:"id" json:"id" mapstructure:"id"`
-	Description   string   `json:"description" yaml:"description" mapstructure:"description"`
-	DisplayName   string   `json:"display_name" yaml:"displayName" mapstructure:"display_name"`
-	Name          string   `json:"name" yaml:"name" mapstructure:"name"`
-	Email         string   `json:"email" yaml:"email" mapstructure:"email"`
-	EmailVerified bool     `json:"email_verified" yaml:"email_verified" mapstructure:"email_verified"`
-	Picture       string   `json:"picture" yaml:"picture" mapstructure:"picture"`
-	Active        bool     `json:"active" yaml:"active" mapstructure:"active"`
-	Sysop         bool     `json:"sysop" yaml:"sysop" mapstructure:"sysop"`
-	Password      string   `json:"password" yaml:"password" mapstructure:"password"`
-	Sections      []string `json:"sections" yaml:"sections" mapstructure:"sections"`
-	SectionsAll   bool     `json:"sections_all" yaml:"sectionsAll" mapstructure:"sections_all"`
-	Accounts      []string `json:"accounts" yaml:"accounts" mapstructure:"accounts"`
-	AdminAccounts []string `json:"admin_accounts" yaml:"adminAccounts" mapstructure:"admin_accounts"`
+	Id            string         `json:"id" json:"id" mapstructure:"id"`
+	Description   string         `json:"description" yaml:"description" mapstructure:"description"`
+	DisplayName   string         `json:"display_name" yaml:"displayName" mapstructure:"display_name"`
+	Name          string         `json:"name" yaml:"name" mapstructure:"name"`
+	Email         string         `json:"email" yaml:"email" mapstructure:"email"`
+	EmailVerified bool           `json:"email_verified" yaml:"email_verified" mapstructure:"email_verified"`
+	Picture       string         `json:"picture" yaml:"picture" mapstructure:"picture"`
+	Active        bool           `json:"active" yaml:"active" mapstructure:"active"`
+	Sysop         bool           `json:"sysop" yaml:"sysop" mapstructure:"sysop"`
+	Password      string         `json:"password" yaml:"password" mapstructure:"password"`
+	Sections      []string       `json:"sections" yaml:"sections" mapstructure:"sections"`
+	SectionsAll   bool           `json:"sections_all" yaml:"sectionsAll" mapstructure:"sections_all"`
+	DenySections  []string       `json:"deny_sections" yaml:"denySections" mapstructure:"deny_sections"`
+	Accounts      []string       `json:"accounts" yaml:"accounts" mapstructure:"accounts"`
+	AdminAccounts []string       `json:"admin_accounts" yaml:"adminAccounts" mapstructure:"admin_accounts"`
+	Epoch         int64          `json:"epoch" yaml:"epoch" mapstructure:"epoch"`
+	ApiTokens     []ApiToken     `json:"api_tokens" yaml:"apiTokens" mapstructure:"api_tokens"`
+	Identities    []string       `json:"identities" yaml:"identities" mapstructure:"identities"`
+	Roles         []AccountRoles `json:"roles" yaml:"roles" mapstructure:"roles"`
+	Memberships   []Membership   `json:"memberships" yaml:"memberships" mapstructure:"memberships"`
+
+	// resolved from Roles and groups, not stored
+	RoleGrants  []RoleGrant  `json:"role_grants,omitempty" yaml:"roleGrants" mapstructure:"role_grants"`
+	GroupGrants []Membership `json:"group_grants,omitempty" yaml:"groupGrants" mapstructure:"group_grants"`
 }
 
 // UserResult returned from Elastic
@@ -48,8 +58,10 @@ type UserResultAck struct {
 
 // UserTokenResult
 type UserTokenResult struct {
-	User  User   `json:"user"`
-	Token string `json:"token"`
+	User         User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Expires      int64  `json:"expires,omitempty"`
 }
 
 // UserTokenResultAck
@@ -62,6 +74,10 @@ type UserTokenResultAck struct {
 type Auth struct {
 	Id       string `json:"id"`
 	Password string `json:"password"`
+
+	// optional account to authenticate through, selects
+	// the account's configured authenticator
+	Account string `json:"account,omitempty"`
 }
 
 // UpsertUser inserts or updates a user record. Elasticsearch
@@ -69,14 +85,63 @@ type Auth struct {
 func (a *Api) UpsertUser(user *User) (int, es.Result, *es.ErrorResponse, error) {
 	a.Logger.Info("Upsert user record", zap.String("id", user.Id), zap.String("display_name", user.DisplayName))
 
+	// carry the epoch forward and advance it when the password
+	// changes or the user is deactivated, invalidating issued
+	// user reference tokens
+	code, existingUser, err := a.GetUser(user.Id)
+	if err != nil {
+		return 500, es.Result{}, nil, err
+	}
+
+	// api tokens are managed through the api token routes
+	user.ApiTokens = nil
+
+	if code == 200 {
+		user.ApiTokens = existingUser.Source.ApiTokens
+
+		if user.Epoch < existingUser.Source.Epoch {
+			user.Epoch = existingUser.Source.Epoch
+		}
+
+		pwChange := user.Password != "" && user.Password != RedactMsg
+		if pwChange || (existingUser.Source.Active && !user.Active) {
+			user.Epoch++
+		}
+	}
+
+	// membership accounts are user accounts
+	user.normalizeMemberships()
+
 	// attempt to encrypt the password if one was provided
 	// otherwise populate with existing
-	err := user.CheckEncryptPassword(a)
+	err = user.CheckEncryptPassword(a)
 	if err != nil {
 		return 500, es.Result{}, nil, err
 	}
 
-	return a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxUser, user.Id), user)
+	// cut off existing sessions for deactivated users
+	if !user.Active {
+		err = a.RevokeUserTokens(user.Id)
+		if err != nil {
+			return 500, es.Result{}, nil, err
+		}
+	}
+
+	return a.putUser(user)
+}
+
+// putUser stores a user record as is
+func (a *Api) putUser(user *User) (int, es.Result, *es.ErrorResponse, error) {
+	stored := *user
+	stored.RoleGrants = nil
+	stored.GroupGrants = nil
+
+	code, esResult, errorResponse, err := a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxUser, user.Id), stored)
+
+	// drop any cached copy so permission changes apply immediately
+	a.userCache.invalidate(user.Id)
+
+	return code, esResult, errorResponse, err
 }
 
 // UpsertUserHandler
@@ -136,6 +201,38 @@ func (a *Api) GetUser(id string) (int, *UserResult, error) {
 	return code, userResult, nil
 }
 
+// ResolveUser returns the current stored user through a short
+// lived cache. Returns nil if the user does not exist.
+func (a *Api) ResolveUser(id string) (*User, error) {
+	if user, ok := a.userCache.get(id); ok {
+		return &user, nil
+	}
+
+	code, userResult, err := a.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if code >= 400 && code < 500 {
+		return nil, nil
+	}
+
+	if code >= 500 {
+		return nil, errors.New("received 500 code from database")
+	}
+
+	userResult.Source.Password = RedactMsg
+
+	err = a.ResolveUserGrants(&userResult.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	a.userCache.set(userResult.Source)
+
+	return &userResult.Source, nil
+}
+
 // GetUserHandler gets a user by ID
 func (a *Api) GetUserHandler(c *gin.Context) {
 	ak := ack.Gin(c)
@@ -151,6 +248,7 @@ func (a *Api) GetUserHandler(c *gin.Context) {
 	}
 
 	userResult.Source.Password = RedactMsg
+	userResult.Source.RedactApiTokens()
 
 	if code >= 400 && code < 500 {
 		ak.SetPayload("User " + id + " not found.")
@@ -181,14 +279,54 @@ func (a *Api) AuthUser(auth Auth) (*UserResult, bool, error) {
 		return nil, false, errors.New("received 500 code from database")
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(userResult.Source.Password), []byte(auth.Password))
+	authenticator, err := a.UserAuthenticator(&userResult.Source, auth.Account)
 	if err != nil {
+		return nil, false, err
+	}
+
+	if authenticator == nil {
 		return userResult, false, nil
 	}
 
+	ok, err := a.authenticate(authenticator, &userResult.Source, auth.Password)
+	if err != nil || !ok {
+		return userResult, false, err
+	}
+
 	return userResult, true, nil
 }
 
+// authenticate the user with authenticator, storing any changes
+// it makes to the user (directory group mappings)
+func (a *Api) authenticate(authenticator Authenticator, user *User, password string) (bool, error) {
+	// authenticators modify memberships in place
+	before := *user
+	before.Memberships = append([]Membership(nil), user.Memberships...)
+
+	ok, err := authenticator.Authenticate(user, password)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if !authChanged(before, *user) {
+		return true, nil
+	}
+
+	code, _, errorResponse, err := a.putUser(user)
+	if err != nil {
+		return false, err
+	}
+
+	if code < 200 || code >= 300 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return false, errors.New("unable to store user after authentication")
+	}
+
+	return true, nil
+}
+
 // AuthUserHandler
 func (a *Api) AuthUserHandler(c *gin.Context) {
 	ak := ack.Gin(c)
@@ -214,9 +352,10 @@ func (a *Api) AuthUserHandler(c *gin.Context) {
 	}
 
 	foundUser.Source.Password = RedactMsg
+	foundUser.Source.RedactApiTokens()
 
 	if ok {
-		tkn, err := a.Config.Token.GetToken(foundUser.Source)
+		utr, err := a.IssueUserToken(foundUser.Source)
 		if err != nil {
 			a.Logger.Error("TokenFailResult", zap.Error(err))
 			ak.SetPayloadType("TokenFailResult")
@@ -225,15 +364,12 @@ func (a *Api) AuthUserHandler(c *gin.Context) {
 		}
 
 		if c.Query("raw") == "true" {
-			c.Data(200, "text/plain", []byte(tkn))
+			c.Data(200, "text/plain", []byte(utr.Token))
 			return
 		}
 
 		ak.SetPayloadType("UserTokenResult")
-		ak.GinSend(UserTokenResult{
-			User:  foundUser.Source,
-			Token: tkn,
-		})
+		ak.GinSend(utr)
 		return
 	}
 
@@ -303,6 +439,44 @@ func GetUserMapping(prefix string) es.IndexTemplate {
 					"display_name": es.Obj{
 						"type": "text",
 					},
+					"email": es.Obj{
+						"type": "keyword",
+					},
+					"identities": es.Obj{
+						"type": "keyword",
+					},
+					"roles": es.Obj{
+						"properties": es.Obj{
+							"account": es.Obj{
+								"type": "keyword",
+							},
+							"roles": es.Obj{
+								"type": "keyword",
+							},
+						},
+					},
+					"memberships": es.Obj{
+						"properties": es.Obj{
+							"account": es.Obj{
+								"type": "keyword",
+							},
+							"sections": es.Obj{
+								"type": "keyword",
+							},
+							"sections_all": es.Obj{
+								"type": "boolean",
+							},
+							"deny_sections": es.Obj{
+								"type": "keyword",
+							},
+							"roles": es.Obj{
+								"type": "keyword",
+							},
+							"admin": es.Obj{
+								"type": "boolean",
+							},
+						},
+					},
 					"active": es.Obj{
 						"type": "boolean",
 					},
@@ -315,12 +489,41 @@ func GetUserMapping(prefix string) es.IndexTemplate {
 					"sections_all": es.Obj{
 						"type": "boolean",
 					},
+					"deny_sections": es.Obj{
+						"type": "keyword",
+					},
 					"accounts": es.Obj{
 						"type": "keyword",
 					},
 					"admin_accounts": es.Obj{
 						"type": "keyword",
 					},
+					"epoch": es.Obj{
+						"type": "long",
+					},
+					"api_tokens": es.Obj{
+						"type": "nested",
+						"properties": es.Obj{
+							"name": es.Obj{
+								"type": "keyword",
+							},
+							"hash": es.Obj{
+								"type": "keyword",
+							},
+							"created": es.Obj{
+								"type": "long",
+							},
+							"expires": es.Obj{
+								"type": "long",
+							},
+							"sections": es.Obj{
+								"type": "keyword",
+							},
+							"accounts": es.Obj{
+								"type": "keyword",
+							},
+						},
+					},
 				},
 			},
 		},
@@ -1,25 +1,55 @@
 package provision
 
 import (
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mitchellh/mapstructure"
 	"github.com/txn2/ack"
 	"github.com/txn2/token"
+	"go.uber.org/zap"
 )
 
-// AccessCheck is used to configure an access check
+const AccessModeAll = "all"
+const AccessModeAny = "any"
+
+// AccessCheck is used to configure an access check. Mode all
+// (default) requires access in every account, any in at least
+// one of the accounts.
 type AccessCheck struct {
 	Sections []string `json:"sections"`
 	Accounts []string `json:"accounts"`
+	Mode     string   `json:"mode,omitempty"`
 }
 
 // AccessCheckResult
 type AccessCheckResult struct {
-	AccessChecked *AccessCheck `json:"access_checked"`
-	Status        bool         `json:"status"`
-	Message       string       `json:"message"`
+	AccessChecked *AccessCheck       `json:"access_checked"`
+	Status        bool               `json:"status"`
+	Message       string             `json:"message"`
+	Explanation   *PolicyExplanation `json:"explanation,omitempty"`
+}
+
+// BatchAccessCheckMax is the maximum number of checks in a batch
+const BatchAccessCheckMax = 100
+
+// BatchAccessCheckItem is an access check, or an admin
+// check when Admin is true
+type BatchAccessCheckItem struct {
+	AccessCheck
+	Admin bool `json:"admin"`
+}
+
+// BatchAccessCheck
+type BatchAccessCheck struct {
+	Checks []BatchAccessCheckItem `json:"checks"`
+}
+
+// BatchAccessCheckResult has a result for each check in order
+type BatchAccessCheckResult struct {
+	Results []AccessCheckResult `json:"results"`
 }
 
 // AccountAccessCheckHandler
@@ -62,64 +92,273 @@ func AccountAccessCheckHandler(checkAdmin bool) gin.HandlerFunc {
 // UserTokenHandler
 func UserTokenHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ak := ack.Gin(c)
-
-		tokI, ok := c.Get("Tok")
+		tok, ok := validTok(c)
 		if !ok {
-			ak.SetPayloadType("ErrorMessage")
-			ak.SetPayload("missing token")
-			ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
 			return
 		}
 
-		tok := tokI.(*token.Tok)
-
-		if !tok.Valid {
-			ak.SetPayloadType("ErrorMessage")
-			ak.SetPayload("invalid token")
-			ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		// user reference tokens must be resolved from storage
+		// see Api.UserTokenHandler
+		if isUserRefTok(tok) {
+			tokenAbort(c, "token requires user resolution")
 			return
 		}
 
-		// check for expiration
-		time.Local = time.UTC
-		exp := int64(tok.Claims["exp"].(float64))
-		if time.Now().Unix() > exp {
-			ak.SetPayloadType("ErrorMessage")
-			ak.SetPayload("expired token")
-			ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		user, ok := tokUser(c, tok)
+		if !ok {
 			return
 		}
 
-		user := &User{}
-		err := mapstructure.Decode(tok.Claims["data"].(map[string]interface{}), user)
-		if err != nil {
-			ak.SetPayloadType("ErrorMessage")
-			ak.SetPayload("there was a problem decoding the token")
-			ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		if !user.HasBasicAccess() {
+			tokenAbort(c, "user does not have basic access")
 			return
 		}
 
-		if !user.HasBasicAccess() {
-			ak.SetPayloadType("ErrorMessage")
-			ak.SetPayload("user does not have basic access")
-			ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		// set a user middleware
+		c.Set("User", user)
+	}
+}
+
+// UserTokenHandler validates the token like the package level
+// UserTokenHandler, rejects tokens found in the revocation list
+// and resolves user reference tokens from storage. Personal api
+// tokens are accepted in place of a JWT.
+func (a *Api) UserTokenHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tok *token.Tok
+		if tokI, ok := c.Get("Tok"); ok {
+			tok = tokI.(*token.Tok)
+		}
+
+		user, at, tf := a.tokenUser(apiTokenFromRequest(c), tok)
+		if tf != nil {
+			a.tokenFailureAbort(c, tf)
 			return
 		}
 
+		if at != nil {
+			c.Set("ApiToken", at.Name)
+		}
+
 		// set a user middleware
 		c.Set("User", user)
 	}
 }
 
+// tokenFailure is a failed token check, err is set when the
+// check could not be completed
+type tokenFailure struct {
+	status  int
+	code    string
+	message string
+	err     error
+}
+
+// errorMessage
+func (tf *tokenFailure) errorMessage() string {
+	if tf.err != nil {
+		return tf.err.Error()
+	}
+
+	return "UnauthorizedAccess"
+}
+
+// tokenFailureAbort aborts the request with a failed token check
+func (a *Api) tokenFailureAbort(c *gin.Context, tf *tokenFailure) {
+	if tf.err != nil {
+		a.Logger.Error("Token check failure.", zap.String("code", tf.code), zap.Error(tf.err))
+	}
+
+	ak := ack.Gin(c)
+	ak.SetPayloadType("ErrorMessage")
+	ak.SetPayload(tf.message)
+	ak.GinErrorAbort(tf.status, tf.code, tf.errorMessage())
+}
+
+// tokenDenied
+func tokenDenied(msg string) *tokenFailure {
+	return &tokenFailure{status: 401, code: "E401", message: msg}
+}
+
+// tokenUser returns the user of a personal api token or, when
+// apiToken is empty, of tok. The api token is returned when one
+// was used.
+func (a *Api) tokenUser(apiToken string, tok *token.Tok) (*User, *ApiToken, *tokenFailure) {
+	if apiToken != "" {
+		user, at, err := a.ApiTokenUser(apiToken)
+		if err != nil {
+			return nil, nil, &tokenFailure{500, "ApiTokenError", "unable to check api token", err}
+		}
+
+		if user == nil {
+			return nil, nil, tokenDenied("invalid api token")
+		}
+
+		if !user.HasBasicAccess() {
+			return nil, nil, tokenDenied("user does not have basic access")
+		}
+
+		return user, at, nil
+	}
+
+	if msg := checkTok(tok); msg != "" {
+		return nil, nil, tokenDenied(msg)
+	}
+
+	jti, _ := tok.Claims["jti"].(string)
+	revoked, err := a.IsTokenRevoked(jti)
+	if err != nil {
+		return nil, nil, &tokenFailure{500, "RevocationCheckError", "unable to check token revocation", err}
+	}
+
+	if revoked {
+		return nil, nil, tokenDenied("revoked token")
+	}
+
+	user, err := decodeTokUser(tok)
+	if err != nil {
+		return nil, nil, tokenDenied("there was a problem decoding the token")
+	}
+
+	if isUserRefTok(tok) {
+		current, err := a.ResolveUser(user.Id)
+		if err != nil {
+			return nil, nil, &tokenFailure{500, "UserResolutionError", "unable to resolve user", err}
+		}
+
+		if current == nil {
+			return nil, nil, tokenDenied("user not found")
+		}
+
+		// tokens issued before the user's current epoch
+		// are no longer valid
+		if user.Epoch < current.Epoch {
+			return nil, nil, tokenDenied("stale token")
+		}
+
+		user = current
+	}
+
+	if !user.HasBasicAccess() {
+		return nil, nil, tokenDenied("user does not have basic access")
+	}
+
+	return user, nil, nil
+}
+
+// validTok gets the token set by the token middleware and ensures
+// it is valid and not expired. Aborts the request and returns false
+// on failure.
+func validTok(c *gin.Context) (*token.Tok, bool) {
+	var tok *token.Tok
+	if tokI, ok := c.Get("Tok"); ok {
+		tok = tokI.(*token.Tok)
+	}
+
+	if msg := checkTok(tok); msg != "" {
+		tokenAbort(c, msg)
+		return nil, false
+	}
+
+	return tok, true
+}
+
+// checkTok returns the reason a token is not valid or expired,
+// empty for a valid token
+func checkTok(tok *token.Tok) string {
+	if tok == nil {
+		return "missing token"
+	}
+
+	if !tok.Valid {
+		return "invalid token"
+	}
+
+	// check for expiration
+	time.Local = time.UTC
+	exp, _ := tok.Claims["exp"].(float64)
+	if time.Now().Unix() > int64(exp) {
+		return "expired token"
+	}
+
+	return ""
+}
+
+// tokUser decodes the user from token data. Aborts the request
+// and returns false on failure.
+func tokUser(c *gin.Context, tok *token.Tok) (*User, bool) {
+	user, err := decodeTokUser(tok)
+	if err != nil {
+		tokenAbort(c, "there was a problem decoding the token")
+		return nil, false
+	}
+
+	return user, true
+}
+
+// decodeTokUser decodes the user from token data
+func decodeTokUser(tok *token.Tok) (*User, error) {
+	user := &User{}
+
+	data, ok := tok.Claims["data"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("token has no user data")
+	}
+
+	err := mapstructure.Decode(data, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// isUserRefTok returns true if the token carries only a user
+// reference (id and epoch) rather than the full user.
+func isUserRefTok(tok *token.Tok) bool {
+	ref, _ := tok.Claims["user_ref"].(bool)
+	return ref
+}
+
+// tokenAbort aborts with an unauthorized error message
+func tokenAbort(c *gin.Context, msg string) {
+	ak := ack.Gin(c)
+	ak.SetPayloadType("ErrorMessage")
+	ak.SetPayload(msg)
+	ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+}
+
 // UserHasAdminAccessHandler
 func UserHasAdminAccessHandler(c *gin.Context) {
 	c.Set("AdminCheck", true)
 	UserHasAccessHandler(c)
 }
 
-// UserHasAccessHandler
+// UserHasAccessHandler evaluates DefaultPolicy, use
+// Api.UserHasAccessHandler for the configured policy
 func UserHasAccessHandler(c *gin.Context) {
+	userHasAccess(c, defaultPolicyEvaluator, func(user *User, ac *AccessCheck) (PolicyInput, error) {
+		return PolicyInput{User: user, Request: ac}, nil
+	})
+}
+
+// UserHasAdminAccessHandler evaluates admin access with
+// Api.PolicyInput. Must be preceded by a UserTokenHandler.
+func (a *Api) UserHasAdminAccessHandler(c *gin.Context) {
+	c.Set("AdminCheck", true)
+	a.UserHasAccessHandler(c)
+}
+
+// UserHasAccessHandler evaluates access with Api.PolicyInput,
+// making account attributes available to the policy. Must be
+// preceded by a UserTokenHandler.
+func (a *Api) UserHasAccessHandler(c *gin.Context) {
+	userHasAccess(c, a.PolicyEvaluator(), a.PolicyInput)
+}
+
+// userHasAccess evaluates pe, with explain=true the result
+// includes a trace of the rules evaluated
+func userHasAccess(c *gin.Context, pe *PolicyEvaluator, policyInput func(user *User, ac *AccessCheck) (PolicyInput, error)) {
 	ak := ack.Gin(c)
 	ak.SetPayloadType("AccessCheckResult")
 	acr := AccessCheckResult{
@@ -152,7 +391,42 @@ func UserHasAccessHandler(c *gin.Context) {
 
 	_, checkAdmin := c.Get("AdminCheck")
 
-	if (!checkAdmin && user.HasAccess(ac)) || (checkAdmin && user.HasAdminAccess(ac)) {
+	check := PolicyCheckAccess
+	if checkAdmin {
+		check = PolicyCheckAdmin
+
+		// you can not be an admin of nothing
+		if len(ac.Accounts) < 1 {
+			acr.Message = "Admin check requires at least one account."
+			ak.SetPayload(acr)
+			ak.GinErrorAbort(400, "ValidationError", "No accounts specified.")
+			return
+		}
+	}
+
+	in, err := policyInput(user, ac)
+	if err != nil {
+		acr.Message = "Failed to lookup accounts."
+		ak.SetPayload(acr)
+		ak.GinErrorAbort(500, "AccountLookupError", err.Error())
+		return
+	}
+
+	var decision PolicyDecision
+	if c.Query("explain") == "true" {
+		decision, acr.Explanation, err = pe.Explain(check, in)
+	} else {
+		decision, err = pe.Evaluate(check, in)
+	}
+
+	if err != nil {
+		acr.Message = "Failed to evaluate access policy."
+		ak.SetPayload(acr)
+		ak.GinErrorAbort(500, "PolicyError", err.Error())
+		return
+	}
+
+	if decision.Allowed {
 		acr.Status = true
 		acr.Message = "Has access."
 		ak.SetPayloadType("AccessCheckResult")
@@ -171,93 +445,146 @@ func UserHasAccessHandler(c *gin.Context) {
 	ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
 }
 
-// BasicAccess returns true is user is active and not locked
-func (u *User) HasBasicAccess() bool {
-	return u.Active
-}
+// UserHasAccessBatchHandler evaluates a list of access and
+// admin checks for the token user in one request, accepting
+// explain=true. Must be preceded by a UserTokenHandler.
+func (a *Api) UserHasAccessBatchHandler(c *gin.Context) {
+	ak := ack.Gin(c)
 
-// BasicAccess returns true is user is active and not locked
-func (u *User) HasAccess(ac *AccessCheck) bool {
+	userI, ok := c.Get("User")
+	if !ok {
+		ak.SetPayload("No user object in token.")
+		ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		return
+	}
 
-	// is the user active
-	//
-	if !u.HasBasicAccess() {
-		return false
+	user := userI.(*User)
+
+	batch := &BatchAccessCheck{}
+	err := ak.UnmarshalPostAbort(batch)
+	if err != nil {
+		a.Logger.Error("Batch access check failure.", zap.Error(err))
+		return
 	}
 
-	// if user HasBasicAccess and is a SysOp then they
-	// have access
-	if u.Sysop {
-		return true
+	if len(batch.Checks) > BatchAccessCheckMax {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload("A batch is limited to " + strconv.Itoa(BatchAccessCheckMax) + " checks.")
+		ak.GinErrorAbort(400, "ValidationError", "Too many checks.")
+		return
 	}
 
-	// Admin check - if the user is an admin in the account
-	if u.HasAdminAccess(ac) {
-		return true
+	// look up the accounts of every check once
+	all := &AccessCheck{Accounts: make([]string, 0)}
+	for _, check := range batch.Checks {
+		for _, acc := range check.Accounts {
+			all.Accounts = appendUnique(all.Accounts, acc)
+		}
 	}
 
-	// At this point the user is valid but not a Sysop or
-	// an admin of any of the accounts in the AccessCheck account
-	// array.
+	in, err := a.PolicyInput(user, all)
+	if err != nil {
+		a.Logger.Error("Account lookup failure.", zap.Error(err))
+		ak.SetPayloadType("AccountLookupError")
+		ak.SetPayload("Unable to lookup accounts.")
+		ak.GinErrorAbort(500, "AccountLookupError", err.Error())
+		return
+	}
 
-	// Check for basic account access for ALL accounts listed in
-	// the AccessCheck account array.
-	for _, acc := range ac.Accounts {
-		if !stringInSlice(acc, u.Accounts) {
-			return false
+	pe := a.PolicyEvaluator()
+	explain := c.Query("explain") == "true"
+
+	results := make([]AccessCheckResult, 0, len(batch.Checks))
+	for i := range batch.Checks {
+		check := &batch.Checks[i]
+
+		policyCheck := PolicyCheckAccess
+		if check.Admin {
+			policyCheck = PolicyCheckAdmin
+		}
+
+		in.Request = &check.AccessCheck
+		acr := AccessCheckResult{AccessChecked: &check.AccessCheck}
+
+		// you can not be an admin of nothing
+		if check.Admin && len(check.Accounts) < 1 {
+			acr.Message = "Admin check requires at least one account."
+			results = append(results, acr)
+			continue
+		}
+
+		var decision PolicyDecision
+		if explain {
+			decision, acr.Explanation, err = pe.Explain(policyCheck, in)
+		} else {
+			decision, err = pe.Evaluate(policyCheck, in)
 		}
-	}
 
-	// Sections check
-	// does the user have access to all sections?
-	if !u.SectionsAll {
-		// does config contain SECTIONS we need to check? and...
-		// return false if ac.Sections has a section not in u.Sections
-		for _, sec := range ac.Sections {
-			if !stringInSlice(sec, u.Sections) {
-				return false
-			}
+		switch {
+		case err != nil:
+			acr.Message = "Failed to evaluate access policy."
+		case decision.Allowed:
+			acr.Status = true
+			acr.Message = "Has access."
+		case check.Admin:
+			acr.Message = "User does not have admin access."
+		default:
+			acr.Message = "User does not have basic access."
 		}
 
+		results = append(results, acr)
 	}
 
-	return true
+	ak.SetPayloadType("BatchAccessCheckResult")
+	ak.GinSend(BatchAccessCheckResult{Results: results})
 }
 
-// HasAdminAccess
-func (u *User) HasAdminAccess(ac *AccessCheck) bool {
-	// is the user active
-	//
-	if !u.HasBasicAccess() {
-		return false
+// BasicAccess returns true is user is active and not locked
+func (u *User) HasBasicAccess() bool {
+	return u.Active
+}
+
+// HasAccess evaluates the access check of DefaultPolicy, use
+// Api.HasAccess for the configured policy
+func (u *User) HasAccess(ac *AccessCheck) bool {
+	decision, err := defaultPolicyEvaluator.Evaluate(PolicyCheckAccess, PolicyInput{User: u, Request: ac})
+
+	return err == nil && decision.Allowed
+}
+
+// hasSection returns true if the section is in the user's
+// Sections, granted by a role assigned in all accounts or
+// granted by a membership or role in every requested account.
+func (u *User) hasSection(sec string, accounts []string) bool {
+	if sectionMatch(sec, u.Sections) {
+		return true
 	}
 
-	// if user HasBasicAccess and is a SysOp then they
-	// have access
-	if u.Sysop {
+	if u.roleGrants("", sec) {
 		return true
 	}
 
-	// At this point we are a valid user but not a Sysop
-	// so we need to ensure at least one account was specified.
-	// You can not be an admin of nothing.
-	if len(ac.Accounts) > 1 {
+	if len(accounts) < 1 {
 		return false
 	}
 
-	// Admin check - if the user is not an admin in ALL
-	// of the accounts in the AccessCheck account array then
-	// deny them
-	for _, acc := range ac.Accounts {
-		if !stringInSlice(acc, u.AdminAccounts) {
+	for _, acc := range accounts {
+		if !u.accountGrants(acc, sec) {
 			return false
 		}
 	}
 
-	// we must be an admin in all of the accounts provided
 	return true
 }
 
+// HasAdminAccess evaluates the admin check of DefaultPolicy, use
+// Api.HasAdminAccess for the configured policy
+func (u *User) HasAdminAccess(ac *AccessCheck) bool {
+	decision, err := defaultPolicyEvaluator.Evaluate(PolicyCheckAdmin, PolicyInput{User: u, Request: ac})
+
+	return err == nil && decision.Allowed
+}
+
 // stringInSlice util
 func stringInSlice(a string, list []string) bool {
 	for _, b := range list {
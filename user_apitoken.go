@@ -0,0 +1,384 @@
+package provision
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"go.uber.org/zap"
+)
+
+// ApiTokenPrefix identifies a personal api token in
+// an Authorization header.
+const ApiTokenPrefix = "prv_"
+
+// ApiToken is a named personal api token stored hashed on
+// a user. Sections and Accounts optionally restrict the
+// token to a subset of the user's own.
+type ApiToken struct {
+	Name     string   `json:"name" yaml:"name" mapstructure:"name"`
+	Hash     string   `json:"hash" yaml:"hash" mapstructure:"hash"`
+	Created  int64    `json:"created" yaml:"created" mapstructure:"created"`
+	Expires  int64    `json:"expires" yaml:"expires" mapstructure:"expires"`
+	Sections []string `json:"sections" yaml:"sections" mapstructure:"sections"`
+	Accounts []string `json:"accounts" yaml:"accounts" mapstructure:"accounts"`
+}
+
+// ApiTokenResult is returned once on creation and is the
+// only time the raw token is available.
+type ApiTokenResult struct {
+	ApiToken ApiToken `json:"api_token"`
+	Token    string   `json:"token"`
+}
+
+// RedactApiTokens
+func (u *User) RedactApiTokens() {
+	for i := range u.ApiTokens {
+		u.ApiTokens[i].Hash = RedactMsg
+	}
+}
+
+// ApiTokenUser returns the user for a raw api token scoped
+// to the token's sections and accounts. Returns nil if the
+// token is unknown or expired.
+func (a *Api) ApiTokenUser(raw string) (*User, *ApiToken, error) {
+	userId, ok := parseApiToken(raw)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	user, err := a.ResolveUser(userId)
+	if err != nil || user == nil {
+		return nil, nil, err
+	}
+
+	hash := hashToken(raw)
+	for _, at := range user.ApiTokens {
+		if subtle.ConstantTimeCompare([]byte(at.Hash), []byte(hash)) != 1 {
+			continue
+		}
+
+		if time.Now().Unix() > at.Expires {
+			return nil, nil, nil
+		}
+
+		return user.scopedTo(at), &at, nil
+	}
+
+	return nil, nil, nil
+}
+
+// scopedTo returns a copy of the user restricted to the
+// sections and accounts of an api token. Scoped tokens never
+// carry sysop, and tokens scoped to sections never carry admin
+// since admin grants every section of an account.
+func (u *User) scopedTo(at ApiToken) *User {
+	scoped := *u
+	scoped.ApiTokens = nil
+
+	if len(at.Sections) > 0 {
+		scoped.Sections = scopeSections(at.Sections, u.Sysop || u.SectionsAll, u.Sections)
+		scoped.SectionsAll = false
+		scoped.Sysop = false
+
+		// per account grants are narrowed the same way, group
+		// grants are folded into the scoped memberships and admin
+		// becomes membership with the token's sections
+		scoped.Memberships = make([]Membership, 0, len(u.Memberships)+len(u.GroupGrants)+len(u.AdminAccounts))
+		scoped.GroupGrants = nil
+		for _, m := range u.allMemberships() {
+			m.Sections = scopeSections(at.Sections, m.SectionsAll || m.Admin, m.Sections)
+			m.SectionsAll = false
+			m.Admin = false
+			m.Roles = nil
+			scoped.Memberships = append(scoped.Memberships, m)
+		}
+
+		scoped.Accounts = append(make([]string, 0, len(u.Accounts)), u.Accounts...)
+		for _, acc := range u.AdminAccounts {
+			scoped.Accounts = appendUnique(scoped.Accounts, acc)
+			scoped.Memberships = append(scoped.Memberships, Membership{
+				Account:  acc,
+				Sections: scopeSections(at.Sections, true, nil),
+			})
+		}
+		scoped.AdminAccounts = nil
+
+		scoped.RoleGrants = make([]RoleGrant, 0, len(u.RoleGrants))
+		for _, rg := range u.RoleGrants {
+			rg.Sections = scopeSections(at.Sections, rg.SectionsAll, rg.Sections)
+			rg.SectionsAll = false
+			scoped.RoleGrants = append(scoped.RoleGrants, rg)
+		}
+	}
+
+	if len(at.Accounts) > 0 {
+		accounts := make([]string, 0)
+		adminAccounts := make([]string, 0)
+		for _, acc := range at.Accounts {
+			if u.Sysop || stringInSlice(acc, scoped.Accounts) {
+				accounts = append(accounts, acc)
+			}
+			if (u.Sysop && len(at.Sections) == 0) || stringInSlice(acc, scoped.AdminAccounts) {
+				adminAccounts = append(adminAccounts, acc)
+			}
+		}
+
+		scoped.Accounts = accounts
+		scoped.AdminAccounts = adminAccounts
+		scoped.Sysop = false
+
+		memberships := make([]Membership, 0)
+		for _, m := range scoped.allMemberships() {
+			if stringInSlice(m.Account, at.Accounts) {
+				memberships = append(memberships, m)
+			}
+		}
+		scoped.Memberships = memberships
+		scoped.GroupGrants = nil
+	}
+
+	return &scoped
+}
+
+// scopeSections returns the requested sections that are
+// granted by all or found in granted.
+func scopeSections(requested []string, all bool, granted []string) []string {
+	sections := make([]string, 0)
+	for _, sec := range requested {
+		if all || sectionMatch(sec, granted) {
+			sections = append(sections, sec)
+		}
+	}
+
+	return sections
+}
+
+// ListApiTokensHandler lists the api tokens of the token user.
+// Must be preceded by Api.UserTokenHandler.
+func (a *Api) ListApiTokensHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	user, ok := a.apiTokenManager(c, ak)
+	if !ok {
+		return
+	}
+
+	user.RedactApiTokens()
+
+	tokens := user.ApiTokens
+	if tokens == nil {
+		tokens = []ApiToken{}
+	}
+
+	ak.SetPayloadType("ApiTokens")
+	ak.GinSend(tokens)
+}
+
+// CreateApiTokenHandler creates a named api token for the token
+// user. Must be preceded by Api.UserTokenHandler.
+func (a *Api) CreateApiTokenHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	at := &ApiToken{}
+	err := ak.UnmarshalPostAbort(at)
+	if err != nil {
+		a.Logger.Error("ApiToken failure.", zap.Error(err))
+		return
+	}
+
+	user, ok := a.apiTokenManager(c, ak)
+	if !ok {
+		return
+	}
+
+	if at.Name == "" {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload("Api token requires a name.")
+		ak.GinErrorAbort(400, "ValidationError", "Missing api token name.")
+		return
+	}
+
+	for _, existing := range user.ApiTokens {
+		if existing.Name == at.Name {
+			ak.SetPayloadType("ValidationError")
+			ak.SetPayload("Api token " + at.Name + " already exists.")
+			ak.GinErrorAbort(400, "ValidationError", "Duplicate api token name.")
+			return
+		}
+	}
+
+	now := time.Now().Unix()
+	if at.Expires <= now {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload("Api token expiration must be in the future.")
+		ak.GinErrorAbort(400, "ValidationError", "Invalid api token expiration.")
+		return
+	}
+
+	// tokens may only narrow the user's own access
+	for _, sec := range at.Sections {
+		if !user.Sysop && !user.SectionsAll && !user.hasSection(sec, at.Accounts) {
+			ak.SetPayloadType("ValidationError")
+			ak.SetPayload("User does not have section " + sec + ".")
+			ak.GinErrorAbort(400, "ValidationError", "Api token section not granted to user.")
+			return
+		}
+	}
+
+	for _, acc := range at.Accounts {
+		if !user.Sysop && !stringInSlice(acc, user.Accounts) {
+			ak.SetPayloadType("ValidationError")
+			ak.SetPayload("User does not have account " + acc + ".")
+			ak.GinErrorAbort(400, "ValidationError", "Api token account not granted to user.")
+			return
+		}
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		ak.GinErrorAbort(500, "ApiTokenError", err.Error())
+		return
+	}
+
+	raw := ApiTokenPrefix + hex.EncodeToString([]byte(user.Id)) + "_" + secret
+
+	at.Hash = hashToken(raw)
+	at.Created = now
+	user.ApiTokens = append(user.ApiTokens, *at)
+
+	if !a.putApiTokenUser(user, ak) {
+		return
+	}
+
+	at.Hash = RedactMsg
+
+	ak.SetPayloadType("ApiTokenResult")
+	ak.GinSend(ApiTokenResult{
+		ApiToken: *at,
+		Token:    raw,
+	})
+}
+
+// RevokeApiTokenHandler removes the api token :name from the
+// token user. Must be preceded by Api.UserTokenHandler.
+func (a *Api) RevokeApiTokenHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	user, ok := a.apiTokenManager(c, ak)
+	if !ok {
+		return
+	}
+
+	name := c.Param("name")
+	tokens := make([]ApiToken, 0)
+	for _, at := range user.ApiTokens {
+		if at.Name != name {
+			tokens = append(tokens, at)
+		}
+	}
+
+	if len(tokens) == len(user.ApiTokens) {
+		ak.SetPayload("Api token " + name + " not found.")
+		ak.GinErrorAbort(404, "ApiTokenNotFound", "Api token not found")
+		return
+	}
+
+	user.ApiTokens = tokens
+
+	if !a.putApiTokenUser(user, ak) {
+		return
+	}
+
+	ak.SetPayloadType("RevokeResult")
+	ak.GinSend(true)
+}
+
+// apiTokenManager returns the stored user for the token user.
+// Api tokens are not permitted to manage api tokens.
+func (a *Api) apiTokenManager(c *gin.Context, ak ack.GinAck) (*User, bool) {
+	userI, ok := c.Get("User")
+	if !ok {
+		ak.SetPayload("Unable to get user from token.")
+		ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		return nil, false
+	}
+
+	if _, isApiToken := c.Get("ApiToken"); isApiToken {
+		ak.SetPayload("Api tokens can not manage api tokens.")
+		ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		return nil, false
+	}
+
+	code, userResult, err := a.GetUser(userI.(*User).Id)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("UserLookupError")
+		ak.SetPayload("Unable to lookup user.")
+		ak.GinErrorAbort(500, "UserLookupError", err.Error())
+		return nil, false
+	}
+
+	if code != 200 {
+		ak.SetPayload("User not found.")
+		ak.GinErrorAbort(404, "UserNotFound", "User not found")
+		return nil, false
+	}
+
+	return &userResult.Source, true
+}
+
+// putApiTokenUser stores the user after an api token change
+func (a *Api) putApiTokenUser(user *User, ak ack.GinAck) bool {
+	code, esResult, errorResponse, err := a.putUser(user)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		if errorResponse != nil {
+			ak.SetPayloadType("EsErrorResponse")
+			ak.SetPayload(errorResponse)
+		}
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return false
+	}
+
+	if code < 200 || code >= 300 {
+		a.Logger.Error("Es returned a non 200")
+		ak.SetPayloadType("EsError")
+		ak.SetPayload(esResult)
+		ak.GinErrorAbort(500, "EsError", "Es returned a non 200")
+		return false
+	}
+
+	return true
+}
+
+// apiTokenFromRequest returns a personal api token from the
+// Authorization header if one is present.
+func apiTokenFromRequest(c *gin.Context) string {
+	authHeader := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(authHeader) > 1 && authHeader[0] == "Bearer" && strings.HasPrefix(authHeader[1], ApiTokenPrefix) {
+		return authHeader[1]
+	}
+
+	return ""
+}
+
+// parseApiToken returns the user id encoded in a raw api token
+func parseApiToken(raw string) (string, bool) {
+	parts := strings.Split(strings.TrimPrefix(raw, ApiTokenPrefix), "_")
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	userId, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+
+	return string(userId), true
+}
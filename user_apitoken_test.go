@@ -0,0 +1,77 @@
+package provision
+
+import (
+	"testing"
+)
+
+func TestScopedToSectionsDropsAdmin(t *testing.T) {
+	user := &User{
+		Id:            "u1",
+		Active:        true,
+		Accounts:      []string{"acme"},
+		AdminAccounts: []string{"acme", "beta"},
+		Memberships: []Membership{
+			{Account: "gamma", Admin: true},
+			{Account: "delta", Sections: []string{"billing", "reports"}},
+		},
+	}
+
+	scoped := user.scopedTo(ApiToken{Name: "reports", Sections: []string{"reports"}})
+
+	tt := []struct {
+		name    string
+		account string
+		section string
+		access  bool
+	}{
+		{"admin account in scope", "acme", "reports", true},
+		{"admin account out of scope", "acme", "billing", false},
+		{"admin only account in scope", "beta", "reports", true},
+		{"admin only account out of scope", "beta", "billing", false},
+		{"admin membership in scope", "gamma", "reports", true},
+		{"admin membership out of scope", "gamma", "billing", false},
+		{"membership in scope", "delta", "reports", true},
+		{"membership out of scope", "delta", "billing", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ac := &AccessCheck{Accounts: []string{tc.account}, Sections: []string{tc.section}}
+			if got := scoped.HasAccess(ac); got != tc.access {
+				t.Errorf("HasAccess(%s, %s) = %v, want %v", tc.account, tc.section, got, tc.access)
+			}
+		})
+	}
+
+	for _, acc := range []string{"acme", "beta", "gamma"} {
+		if scoped.HasAdminAccess(&AccessCheck{Accounts: []string{acc}}) {
+			t.Errorf("HasAdminAccess(%s) = true for a token scoped to sections", acc)
+		}
+	}
+
+	// the user itself is unchanged
+	if !user.HasAccess(&AccessCheck{Accounts: []string{"acme"}, Sections: []string{"billing"}}) {
+		t.Error("user lost admin access in acme")
+	}
+	if len(user.Accounts) != 1 || len(user.AdminAccounts) != 2 || !user.Memberships[0].Admin {
+		t.Errorf("user modified by scopedTo: %+v", user)
+	}
+}
+
+func TestScopedToAccountsKeepsAdmin(t *testing.T) {
+	user := &User{
+		Id:            "u1",
+		Active:        true,
+		Accounts:      []string{"acme", "beta"},
+		AdminAccounts: []string{"acme", "beta"},
+	}
+
+	scoped := user.scopedTo(ApiToken{Name: "acme", Accounts: []string{"acme"}})
+
+	if !scoped.HasAdminAccess(&AccessCheck{Accounts: []string{"acme"}}) {
+		t.Error("HasAdminAccess(acme) = false, want true")
+	}
+	if scoped.HasAccess(&AccessCheck{Accounts: []string{"beta"}, Sections: []string{"billing"}}) {
+		t.Error("HasAccess(beta) = true for a token scoped to acme")
+	}
+}
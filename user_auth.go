@@ -0,0 +1,279 @@
+package provision
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const AuthTypeBcrypt = "bcrypt"
+const AuthTypeLdap = "ldap"
+
+// Authenticator validates a password for a user. Implementations
+// may update the user from an external source (e.g. directory
+// group membership).
+type Authenticator interface {
+	Authenticate(user *User, password string) (bool, error)
+}
+
+// AuthCfg configures how users of an account authenticate
+type AuthCfg struct {
+	// bcrypt (default) or ldap
+	Type string   `json:"type" yaml:"type"`
+	Ldap *LdapCfg `json:"ldap,omitempty" yaml:"ldap"`
+}
+
+// LdapCfg configures an LDAP bind authenticator
+type LdapCfg struct {
+	// ldap:// or ldaps:// url of the directory
+	Url                string `json:"url" yaml:"url"`
+	StartTls           bool   `json:"start_tls" yaml:"startTls"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify" yaml:"insecureSkipVerify"`
+
+	// seconds, defaults to 10
+	Timeout int `json:"timeout" yaml:"timeout"`
+
+	// bind dn template, %s is replaced with the escaped user id
+	// e.g. uid=%s,ou=people,dc=example,dc=com
+	BindDn string `json:"bind_dn" yaml:"bindDn"`
+
+	// group lookup performed as the bound user, %s in the filter
+	// is replaced with the escaped bind dn
+	GroupBaseDn string `json:"group_base_dn" yaml:"groupBaseDn"`
+	GroupFilter string `json:"group_filter" yaml:"groupFilter"`
+	GroupAttr   string `json:"group_attr" yaml:"groupAttr"`
+
+	// when present directory groups determine the user's
+	// sections and admin status for the account
+	GroupMappings []LdapGroupMapping `json:"group_mappings" yaml:"groupMappings"`
+}
+
+// LdapGroupMapping grants sections and admin to members of a group
+type LdapGroupMapping struct {
+	Group       string   `json:"group" yaml:"group"`
+	Sections    []string `json:"sections" yaml:"sections"`
+	SectionsAll bool     `json:"sections_all" yaml:"sectionsAll"`
+	Admin       bool     `json:"admin" yaml:"admin"`
+}
+
+// BcryptAuthenticator checks the password hash stored on the user
+type BcryptAuthenticator struct{}
+
+// Authenticate
+func (ba BcryptAuthenticator) Authenticate(user *User, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if err != nil {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// LdapAuthenticator binds to a directory as the user
+type LdapAuthenticator struct {
+	Account string
+	Cfg     LdapCfg
+}
+
+// Authenticate
+func (la *LdapAuthenticator) Authenticate(user *User, password string) (bool, error) {
+	// an empty password is an anonymous bind in LDAP
+	if password == "" {
+		return false, nil
+	}
+
+	conn, err := ldap.DialURL(la.Cfg.Url)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	timeout := la.Cfg.Timeout
+	if timeout == 0 {
+		timeout = 10
+	}
+	conn.SetTimeout(time.Duration(timeout) * time.Second)
+
+	if la.Cfg.StartTls {
+		err = conn.StartTLS(&tls.Config{InsecureSkipVerify: la.Cfg.InsecureSkipVerify})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	bindDn := fmt.Sprintf(la.Cfg.BindDn, escapeDn(user.Id))
+
+	err = conn.Bind(bindDn, password)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if len(la.Cfg.GroupMappings) < 1 {
+		return true, nil
+	}
+
+	groups, err := la.groups(conn, bindDn)
+	if err != nil {
+		return false, err
+	}
+
+	la.applyGroups(user, groups)
+
+	return true, nil
+}
+
+// groups returns the group names of the bound user
+func (la *LdapAuthenticator) groups(conn *ldap.Conn, bindDn string) ([]string, error) {
+	filter := la.Cfg.GroupFilter
+	if filter == "" {
+		filter = "(member=%s)"
+	}
+
+	attr := la.Cfg.GroupAttr
+	if attr == "" {
+		attr = "cn"
+	}
+
+	req := ldap.NewSearchRequest(
+		la.Cfg.GroupBaseDn,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(filter, ldap.EscapeFilter(bindDn)),
+		[]string{attr},
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]string, 0)
+	for _, entry := range res.Entries {
+		groups = append(groups, entry.GetAttributeValues(attr)...)
+	}
+
+	return groups, nil
+}
+
+// applyGroups sets the sections and admin status of the user's
+// membership in the account from the group mappings, grants of
+// other accounts are untouched.
+func (la *LdapAuthenticator) applyGroups(user *User, groups []string) {
+	sections := make([]string, 0)
+	sectionsAll := false
+	admin := false
+
+	for _, gm := range la.Cfg.GroupMappings {
+		if !stringInSlice(gm.Group, groups) {
+			continue
+		}
+
+		for _, sec := range gm.Sections {
+			if !stringInSlice(sec, sections) {
+				sections = append(sections, sec)
+			}
+		}
+
+		sectionsAll = sectionsAll || gm.SectionsAll
+		admin = admin || gm.Admin
+	}
+
+	// the directory decides admin of the account
+	user.AdminAccounts = removeString(user.AdminAccounts, la.Account)
+
+	for i := range user.Memberships {
+		if user.Memberships[i].Account == la.Account {
+			user.Memberships[i].Sections = sections
+			user.Memberships[i].SectionsAll = sectionsAll
+			user.Memberships[i].Admin = admin
+			return
+		}
+	}
+
+	user.Memberships = append(user.Memberships, Membership{
+		Account:     la.Account,
+		Sections:    sections,
+		SectionsAll: sectionsAll,
+		Admin:       admin,
+	})
+}
+
+// UserAuthenticator returns the authenticator configured for
+// the account used to log in. If account is empty the first of the
+// user's accounts with an authenticator configured is used,
+// falling back to bcrypt. Returns nil if the user is not
+// associated with the requested account.
+func (a *Api) UserAuthenticator(user *User, account string) (Authenticator, error) {
+	accounts := user.Accounts
+	if account != "" {
+		if !stringInSlice(account, user.Accounts) {
+			return nil, nil
+		}
+		accounts = []string{account}
+	}
+
+	for _, acc := range accounts {
+		code, accountResult, err := a.GetAccountRaw(acc)
+		if code == 404 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		authCfg := accountResult.Source.Auth
+		if authCfg == nil || authCfg.Type == "" || authCfg.Type == AuthTypeBcrypt {
+			continue
+		}
+
+		if authCfg.Type == AuthTypeLdap {
+			if authCfg.Ldap == nil {
+				return nil, errors.New("account " + acc + " ldap authentication is not configured")
+			}
+
+			return &LdapAuthenticator{Account: acc, Cfg: *authCfg.Ldap}, nil
+		}
+
+		return nil, errors.New("account " + acc + " has unknown authentication type " + authCfg.Type)
+	}
+
+	return BcryptAuthenticator{}, nil
+}
+
+// authChanged reports whether authentication updated the user
+func authChanged(before User, after User) bool {
+	return !reflect.DeepEqual(before.Sections, after.Sections) ||
+		before.SectionsAll != after.SectionsAll ||
+		!reflect.DeepEqual(before.AdminAccounts, after.AdminAccounts) ||
+		!reflect.DeepEqual(before.Memberships, after.Memberships)
+}
+
+// escapeDn escapes a value for use in a distinguished name
+func escapeDn(v string) string {
+	var sb strings.Builder
+	for i, r := range v {
+		switch {
+		case strings.ContainsRune(",+\"\\<>;=", r):
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case r == 0:
+			sb.WriteString("\\00")
+		case (i == 0 && (r == ' ' || r == '#')) || (i == len(v)-1 && r == ' '):
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,93 @@
+package provision
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// groupsAuthenticator applies fixed directory groups
+type groupsAuthenticator struct {
+	la     *LdapAuthenticator
+	groups []string
+}
+
+// Authenticate
+func (ga groupsAuthenticator) Authenticate(user *User, password string) (bool, error) {
+	ga.la.applyGroups(user, ga.groups)
+	return true, nil
+}
+
+func TestAuthenticateStoresGroupMappings(t *testing.T) {
+	stored := make([]User, 0)
+	a, srv := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || !strings.HasSuffix(r.URL.Path, "/user/_doc/u1") {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(500)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		user := User{}
+		if err := json.Unmarshal(body, &user); err != nil {
+			t.Errorf("Unmarshal: %s", err.Error())
+		}
+		stored = append(stored, user)
+
+		w.Write([]byte(`{"result":"updated"}`))
+	})
+	defer srv.Close()
+
+	ga := groupsAuthenticator{la: &LdapAuthenticator{Account: "acme", Cfg: LdapCfg{
+		GroupMappings: []LdapGroupMapping{
+			{Group: "analysts", Sections: []string{"api"}},
+			{Group: "operators", Admin: true},
+		},
+	}}}
+
+	user := &User{
+		Id:          "u1",
+		Accounts:    []string{"acme"},
+		Memberships: []Membership{{Account: "acme", Sections: []string{"billing"}}},
+	}
+
+	tt := []struct {
+		groups []string
+		stored bool
+		want   Membership
+	}{
+		// only the existing membership changes
+		{[]string{"analysts"}, true, Membership{Account: "acme", Sections: []string{"api"}}},
+		{[]string{"analysts"}, false, Membership{Account: "acme", Sections: []string{"api"}}},
+		{[]string{"analysts", "operators"}, true, Membership{Account: "acme", Sections: []string{"api"}, Admin: true}},
+		{[]string{}, true, Membership{Account: "acme", Sections: []string{}}},
+	}
+
+	for i, tc := range tt {
+		stored = stored[:0]
+		ga.groups = tc.groups
+
+		ok, err := a.authenticate(ga, user, "")
+		if err != nil || !ok {
+			t.Fatalf("%d: authenticate = %v, %v", i, ok, err)
+		}
+
+		if !tc.stored {
+			if len(stored) != 0 {
+				t.Errorf("%d: unchanged user stored", i)
+			}
+			continue
+		}
+
+		if len(stored) != 1 {
+			t.Fatalf("%d: user stored %d times, want 1", i, len(stored))
+		}
+
+		if len(stored[0].Memberships) != 1 || !reflect.DeepEqual(stored[0].Memberships[0], tc.want) {
+			t.Errorf("%d: stored memberships %+v, want %+v", i, stored[0].Memberships, tc.want)
+		}
+	}
+}
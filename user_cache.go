@@ -0,0 +1,116 @@
+package provision
+
+import (
+	"sync"
+	"time"
+)
+
+// UserCacheTTLDefault in seconds
+const UserCacheTTLDefault = 30
+
+// UserCacheSizeMax is the most users cached, an arbitrary user
+// is dropped to make room
+const UserCacheSizeMax = 10000
+
+// userCacheEntry
+type userCacheEntry struct {
+	user    User
+	expires time.Time
+}
+
+// userCache is a short lived in-memory cache of users resolved
+// from storage for token validation.
+type userCache struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]userCacheEntry
+
+	// expired entries are removed once a ttl
+	swept time.Time
+}
+
+// newUserCache
+func newUserCache(ttlSeconds int) *userCache {
+	return &userCache{
+		ttl:     time.Duration(ttlSeconds) * time.Second,
+		entries: make(map[string]userCacheEntry),
+	}
+}
+
+// get returns a copy of a cached user if present and not expired
+func (uc *userCache) get(id string) (User, bool) {
+	if uc == nil {
+		return User{}, false
+	}
+
+	uc.mu.RLock()
+	entry, ok := uc.entries[id]
+	uc.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		return User{}, false
+	}
+
+	return entry.user, true
+}
+
+// set
+func (uc *userCache) set(user User) {
+	if uc == nil || uc.ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if now.Sub(uc.swept) >= uc.ttl {
+		uc.sweep(now)
+	}
+
+	if _, ok := uc.entries[user.Id]; !ok && len(uc.entries) >= UserCacheSizeMax {
+		for id := range uc.entries {
+			delete(uc.entries, id)
+			break
+		}
+	}
+
+	uc.entries[user.Id] = userCacheEntry{
+		user:    user,
+		expires: now.Add(uc.ttl),
+	}
+}
+
+// sweep removes expired entries, the lock must be held
+func (uc *userCache) sweep(now time.Time) {
+	for id, entry := range uc.entries {
+		if now.After(entry.expires) {
+			delete(uc.entries, id)
+		}
+	}
+
+	uc.swept = now
+}
+
+// invalidate removes a user from the cache
+func (uc *userCache) invalidate(id string) {
+	if uc == nil {
+		return
+	}
+
+	uc.mu.Lock()
+	delete(uc.entries, id)
+	uc.mu.Unlock()
+}
+
+// reset removes all users from the cache
+func (uc *userCache) reset() {
+	if uc == nil {
+		return
+	}
+
+	uc.mu.Lock()
+	uc.entries = make(map[string]userCacheEntry)
+	uc.mu.Unlock()
+}
@@ -0,0 +1,56 @@
+package provision
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestUserCacheRemovesExpired(t *testing.T) {
+	uc := newUserCache(30)
+
+	uc.set(User{Id: "u1"})
+	uc.set(User{Id: "u2"})
+
+	// age the entries past the ttl
+	uc.mu.Lock()
+	for id, entry := range uc.entries {
+		entry.expires = time.Now().Add(-time.Second)
+		uc.entries[id] = entry
+	}
+	uc.swept = time.Now().Add(-uc.ttl)
+	uc.mu.Unlock()
+
+	uc.set(User{Id: "u3"})
+
+	if len(uc.entries) != 1 {
+		t.Errorf("got %d entries, want 1", len(uc.entries))
+	}
+
+	if _, ok := uc.get("u3"); !ok {
+		t.Error("u3 not cached")
+	}
+}
+
+func TestUserCacheSizeMax(t *testing.T) {
+	uc := newUserCache(30)
+
+	for i := 0; i < UserCacheSizeMax+10; i++ {
+		uc.set(User{Id: "u" + strconv.Itoa(i)})
+	}
+
+	if len(uc.entries) != UserCacheSizeMax {
+		t.Errorf("got %d entries, want %d", len(uc.entries), UserCacheSizeMax)
+	}
+
+	// the latest user is always cached
+	if _, ok := uc.get("u" + strconv.Itoa(UserCacheSizeMax+9)); !ok {
+		t.Error("latest user not cached")
+	}
+
+	// replacing a cached user drops nothing
+	uc.set(User{Id: "u" + strconv.Itoa(UserCacheSizeMax+9)})
+	if len(uc.entries) != UserCacheSizeMax {
+		t.Errorf("got %d entries after replace, want %d", len(uc.entries), UserCacheSizeMax)
+	}
+}
@@ -0,0 +1,519 @@
+package provision
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"github.com/txn2/es/v2"
+	"go.uber.org/zap"
+)
+
+const IdxInvite = "invite"
+
+// InviteExpDefault in hours
+const InviteExpDefault = 72
+
+// Invite is a single use invitation for an email to join an
+// account. Only the sha256 hash of the invite token is stored
+// and used as the document id.
+type Invite struct {
+	Id        string   `json:"id" yaml:"id"`
+	Email     string   `json:"email" yaml:"email"`
+	Account   string   `json:"account" yaml:"account"`
+	Sections  []string `json:"sections" yaml:"sections"`
+	Admin     bool     `json:"admin" yaml:"admin"`
+	InvitedBy string   `json:"invited_by" yaml:"invitedBy"`
+	Created   int64    `json:"created" yaml:"created"`
+	Expires   int64    `json:"expires" yaml:"expires"`
+	Accepted  bool     `json:"accepted" yaml:"accepted"`
+	UserId    string   `json:"user_id" yaml:"userId"`
+}
+
+// InviteResult returned from Elastic
+type InviteResult struct {
+	es.Result
+	Source Invite `json:"_source"`
+}
+
+// InviteSearchResults
+type InviteSearchResults struct {
+	es.SearchResults
+	Hits struct {
+		Total    int            `json:"total"`
+		MaxScore float64        `json:"max_score"`
+		Hits     []InviteResult `json:"hits"`
+	} `json:"hits"`
+
+	// position after the last hit, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// InviteTokenResult is returned once on creation and is the
+// only time the raw invite token is available.
+type InviteTokenResult struct {
+	Invite Invite `json:"invite"`
+	Token  string `json:"token"`
+}
+
+// InviteAccept is posted by the invitee. New users choose an
+// id (defaults to the invited email) and password, existing
+// users with the invited email confirm their password.
+type InviteAccept struct {
+	Token       string `json:"token"`
+	Id          string `json:"id"`
+	Password    string `json:"password"`
+	DisplayName string `json:"display_name"`
+}
+
+// CreateAdmInviteHandler invites an email to :parentAccount or
+// one of its children.
+func (a *Api) CreateAdmInviteHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	invite := &Invite{}
+	err := ak.UnmarshalPostAbort(invite)
+	if err != nil {
+		a.Logger.Error("Invite failure.", zap.Error(err))
+		return
+	}
+
+	if invite.Email == "" || invite.Account == "" {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload("Invite requires an email and an account.")
+		ak.GinErrorAbort(400, "ValidationError", "Missing invite email or account.")
+		return
+	}
+
+	parentAccountId := c.Param("parentAccount")
+
+	ok, err := a.IsAdmAccount(parentAccountId, invite.Account)
+	if err != nil {
+		ak.SetPayloadType("AccountLookupError")
+		ak.SetPayload("Unable to lookup account.")
+		ak.GinErrorAbort(500, "AccountLookupError", err.Error())
+		return
+	}
+
+	if !ok {
+		ak.SetPayloadType("ValidationError")
+		ak.SetPayload("Account is not the requester or a child of the requester.")
+		ak.GinErrorAbort(400, "ValidationError", "Invite account does not belong to parent.")
+		return
+	}
+
+	raw, err := randomToken(32)
+	if err != nil {
+		ak.GinErrorAbort(500, "InviteError", err.Error())
+		return
+	}
+
+	now := time.Now().Unix()
+
+	invite.Id = hashToken(raw)
+	invite.Created = now
+	invite.Expires = now + InviteExpDefault*3600
+	invite.Accepted = false
+	invite.UserId = ""
+
+	if userI, ok := c.Get("User"); ok {
+		invite.InvitedBy = userI.(*User).Id
+	}
+
+	err = a.UpsertInvite(invite)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	ak.SetPayloadType("InviteTokenResult")
+	ak.GinSend(InviteTokenResult{
+		Invite: *invite,
+		Token:  raw,
+	})
+}
+
+// GetAdmInvitesHandler lists pending invites for :parentAccount
+// and its children.
+func (a *Api) GetAdmInvitesHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	parentAccountId := c.Param("parentAccount")
+
+	accounts, err := a.AdmAccounts(parentAccountId)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	obj, ok := a.listObj(c, ak, es.Obj{
+		"query": es.Obj{
+			"bool": es.Obj{
+				"filter": []es.Obj{
+					{"terms": es.Obj{"account": accounts}},
+					{"term": es.Obj{"accepted": false}},
+					{"range": es.Obj{"expires": es.Obj{"gt": time.Now().Unix()}}},
+				},
+			},
+		},
+	}, SearchTiebreakDefault)
+	if !ok {
+		return
+	}
+
+	invResults := &InviteSearchResults{}
+
+	code, nextCursor, errorResponse, err := a.searchPage(IdxInvite, obj, invResults)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		if errorResponse != nil {
+			ak.SetPayloadType("EsErrorResponse")
+			ak.SetPayload(errorResponse)
+		}
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	if code >= 400 && code < 500 {
+		ak.SetPayload(errorResponse)
+		ak.GinErrorAbort(code, "SearchError", "There was a problem searching")
+		return
+	}
+
+	invResults.NextCursor = nextCursor
+
+	ak.SetPayloadType("InviteSearchResults")
+	ak.GinSend(invResults)
+}
+
+// RevokeAdmInviteHandler removes a pending invite of :parentAccount
+// or one of its children.
+func (a *Api) RevokeAdmInviteHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	parentAccountId := c.Param("parentAccount")
+	inviteId := c.Param("invite")
+
+	code, inviteResult, err := a.GetInvite(inviteId)
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	if code != 200 {
+		ak.SetPayload("Invite " + inviteId + " not found.")
+		ak.GinErrorAbort(404, "InviteNotFound", "Invite not found")
+		return
+	}
+
+	ok, err := a.IsAdmAccount(parentAccountId, inviteResult.Source.Account)
+	if err != nil || !ok {
+		ak.SetPayload("Invite " + inviteId + " not found.")
+		ak.GinErrorAbort(404, "InviteNotFound", "Invite not found")
+		return
+	}
+
+	_, err = a.esDelete(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxInvite, inviteId))
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	ak.SetPayloadType("RevokeResult")
+	ak.GinSend(true)
+}
+
+// AcceptInviteHandler accepts an invite, creating a new user with
+// the invitee's own password or linking an existing user.
+func (a *Api) AcceptInviteHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	accept := &InviteAccept{}
+	err := ak.UnmarshalPostAbort(accept)
+	if err != nil {
+		a.Logger.Error("Invite accept failure.", zap.Error(err))
+		return
+	}
+
+	code, inviteResult, err := a.GetInvite(hashToken(accept.Token))
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	invite := &inviteResult.Source
+
+	if code != 200 || invite.Accepted || time.Now().Unix() > invite.Expires {
+		ak.SetPayload("Invalid or expired invite.")
+		ak.GinErrorAbort(401, "InviteInvalid", "Invalid or expired invite.")
+		return
+	}
+
+	existing, err := a.userByTerm("email", invite.Email)
+	if err != nil {
+		ak.SetPayloadType("UserLookupError")
+		ak.SetPayload("Unable to lookup user.")
+		ak.GinErrorAbort(500, "UserLookupError", err.Error())
+		return
+	}
+
+	var user *User
+
+	if existing != nil {
+		// linking an existing user requires their password
+		_, ok, err := a.AuthUser(Auth{Id: existing.Id, Password: accept.Password})
+		if err != nil {
+			ak.GinErrorAbort(500, "AuthError", err.Error())
+			return
+		}
+
+		if !ok {
+			ak.GinErrorAbort(401, "AuthFailure", "Invalid credentials for existing user.")
+			return
+		}
+
+		// re-read, authentication may have updated the user
+		_, userResult, err := a.GetUser(existing.Id)
+		if err != nil {
+			ak.GinErrorAbort(500, "UserLookupError", err.Error())
+			return
+		}
+
+		user = &userResult.Source
+	}
+
+	if user == nil {
+		id := accept.Id
+		if id == "" {
+			id = invite.Email
+		}
+
+		code, _, err := a.GetUser(id)
+		if err != nil {
+			ak.GinErrorAbort(500, "UserLookupError", err.Error())
+			return
+		}
+
+		if code == 200 {
+			ak.SetPayloadType("ValidationError")
+			ak.SetPayload("User id " + id + " is taken.")
+			ak.GinErrorAbort(400, "ValidationError", "User id is taken.")
+			return
+		}
+
+		if len(accept.Password) < 10 {
+			ak.SetPayloadType("ValidationError")
+			ak.SetPayload("Password must be over ten characters.")
+			ak.GinErrorAbort(400, "ValidationError", "Password too short.")
+			return
+		}
+
+		user = &User{
+			Id:            id,
+			DisplayName:   accept.DisplayName,
+			Email:         invite.Email,
+			EmailVerified: true,
+			Active:        true,
+			Password:      accept.Password,
+		}
+	}
+
+	// invited sections are granted in the invited account only
+	user.Accounts = appendUnique(user.Accounts, invite.Account)
+	if len(invite.Sections) > 0 {
+		user.Memberships = append(user.Memberships, Membership{
+			Account:  invite.Account,
+			Sections: invite.Sections,
+		})
+		user.normalizeMemberships()
+	}
+
+	if invite.Admin {
+		user.AdminAccounts = appendUnique(user.AdminAccounts, invite.Account)
+	}
+
+	// claim the invite before granting access, only one
+	// concurrent accept succeeds
+	claimed, err := a.claimInvite(invite.Id, user.Id)
+	if err != nil {
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	if !claimed {
+		ak.SetPayload("Invalid or expired invite.")
+		ak.GinErrorAbort(401, "InviteInvalid", "Invalid or expired invite.")
+		return
+	}
+
+	invite.Accepted = true
+	invite.UserId = user.Id
+
+	err = a.UpsertInvite(invite)
+	if err != nil {
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	if existing != nil {
+		code, _, _, err = a.putUser(user)
+	} else {
+		code, _, _, err = a.UpsertUser(user)
+	}
+
+	if err != nil || code < 200 || code >= 300 {
+		if err != nil {
+			a.Logger.Error("EsError", zap.Error(err))
+		}
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", "There was a problem storing the user.")
+		return
+	}
+
+	user.Password = RedactMsg
+	user.RedactApiTokens()
+
+	ak.SetPayloadType("User")
+	ak.GinSend(user)
+}
+
+// UpsertInvite stores an invite
+func (a *Api) UpsertInvite(invite *Invite) error {
+	code, _, errorResponse, err := a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxInvite, invite.Id), invite)
+	if err != nil {
+		return err
+	}
+
+	if code < 200 || code >= 300 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return fmt.Errorf("got code %d storing invite", code)
+	}
+
+	return nil
+}
+
+// inviteClaimId is the document id of the claim of an invite
+func inviteClaimId(id string) string {
+	return id + ":claim"
+}
+
+// claimInvite creates the claim of an invite, returning false if
+// the invite was already claimed. Creation is atomic, unlike the
+// accepted flag of the invite.
+func (a *Api) claimInvite(id string, userId string) (bool, error) {
+	claim := &Invite{
+		Id:       inviteClaimId(id),
+		Created:  time.Now().Unix(),
+		Accepted: true,
+		UserId:   userId,
+	}
+
+	code, _, errorResponse, err := a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s/_create", a.IdxPrefix+IdxInvite, claim.Id), claim)
+	if err != nil {
+		return false, err
+	}
+
+	if code == 409 {
+		return false, nil
+	}
+
+	if code < 200 || code >= 300 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return false, fmt.Errorf("got code %d claiming invite", code)
+	}
+
+	return true, nil
+}
+
+// GetInvite
+func (a *Api) GetInvite(id string) (int, *InviteResult, error) {
+	inviteResult := &InviteResult{}
+
+	code, ret, err := a.Elastic.Get(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxInvite, id))
+	if err != nil {
+		return code, inviteResult, err
+	}
+
+	err = json.Unmarshal(ret, inviteResult)
+	if err != nil {
+		return code, inviteResult, err
+	}
+
+	return code, inviteResult, nil
+}
+
+// GetInviteMapping
+func GetInviteMapping(prefix string) es.IndexTemplate {
+	template := es.Obj{
+		"index_patterns": []string{prefix + IdxInvite},
+		"settings": es.Obj{
+			"number_of_shards": 2,
+		},
+		"mappings": es.Obj{
+			"_doc": es.Obj{
+				"_source": es.Obj{
+					"enabled": true,
+				},
+				"properties": es.Obj{
+					"id": es.Obj{
+						"type": "keyword",
+					},
+					"email": es.Obj{
+						"type": "keyword",
+					},
+					"account": es.Obj{
+						"type": "keyword",
+					},
+					"sections": es.Obj{
+						"type": "keyword",
+					},
+					"admin": es.Obj{
+						"type": "boolean",
+					},
+					"invited_by": es.Obj{
+						"type": "keyword",
+					},
+					"created": es.Obj{
+						"type": "long",
+					},
+					"expires": es.Obj{
+						"type": "long",
+					},
+					"accepted": es.Obj{
+						"type": "boolean",
+					},
+					"user_id": es.Obj{
+						"type": "keyword",
+					},
+				},
+			},
+		},
+	}
+
+	return es.IndexTemplate{
+		Name:     prefix + IdxInvite,
+		Template: template,
+	}
+}
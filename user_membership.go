@@ -0,0 +1,138 @@
+package provision
+
+// Membership grants a user sections, roles and admin in a
+// single account. Sections granted by a membership apply
+// only in that account, unlike the user's global Sections.
+// DenySections overrides any grant in the account.
+type Membership struct {
+	Account      string   `json:"account" yaml:"account" mapstructure:"account"`
+	Sections     []string `json:"sections" yaml:"sections" mapstructure:"sections"`
+	SectionsAll  bool     `json:"sections_all" yaml:"sectionsAll" mapstructure:"sections_all"`
+	DenySections []string `json:"deny_sections" yaml:"denySections" mapstructure:"deny_sections"`
+	Roles        []string `json:"roles" yaml:"roles" mapstructure:"roles"`
+	Admin        bool     `json:"admin" yaml:"admin" mapstructure:"admin"`
+}
+
+// allMemberships returns the user's memberships and group grants
+func (u *User) allMemberships() []Membership {
+	memberships := make([]Membership, 0, len(u.Memberships)+len(u.GroupGrants))
+	memberships = append(memberships, u.Memberships...)
+
+	return append(memberships, u.GroupGrants...)
+}
+
+// memberships returns the user's memberships and group grants
+// in account
+func (u *User) memberships(account string) []Membership {
+	memberships := make([]Membership, 0)
+	for _, m := range u.allMemberships() {
+		if m.Account == account {
+			memberships = append(memberships, m)
+		}
+	}
+
+	return memberships
+}
+
+// isAccountMember returns true if the user belongs to the account
+// through Accounts, AdminAccounts or a membership. Group grants
+// are not considered.
+func (u *User) isAccountMember(account string) bool {
+	if stringInSlice(account, u.Accounts) || stringInSlice(account, u.AdminAccounts) {
+		return true
+	}
+
+	for _, m := range u.Memberships {
+		if m.Account == account {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isAccountAdmin returns true if the user is an admin of the
+// account through AdminAccounts, a membership or a group.
+func (u *User) isAccountAdmin(account string) bool {
+	if stringInSlice(account, u.AdminAccounts) {
+		return true
+	}
+
+	for _, m := range u.memberships(account) {
+		if m.Admin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// accountGrants returns true if the user's memberships, groups
+// or roles in account grant the section.
+func (u *User) accountGrants(account string, sec string) bool {
+	for _, m := range u.memberships(account) {
+		if m.SectionsAll || sectionMatch(sec, m.Sections) {
+			return true
+		}
+	}
+
+	return u.roleGrants(account, sec)
+}
+
+// roleAssignments returns the user's role assignments including
+// roles assigned through memberships and groups.
+func (u *User) roleAssignments() []AccountRoles {
+	assignments := make([]AccountRoles, 0, len(u.Roles))
+	assignments = append(assignments, u.Roles...)
+
+	for _, m := range u.allMemberships() {
+		if len(m.Roles) > 0 {
+			assignments = append(assignments, AccountRoles{Account: m.Account, Roles: m.Roles})
+		}
+	}
+
+	return assignments
+}
+
+// normalizeMemberships merges duplicate memberships and ensures
+// every membership account is in the user's Accounts.
+func (u *User) normalizeMemberships() {
+	if len(u.Memberships) < 1 {
+		return
+	}
+
+	memberships := make([]Membership, 0, len(u.Memberships))
+	idx := make(map[string]int)
+
+	for _, m := range u.Memberships {
+		if m.Account == "" {
+			continue
+		}
+
+		i, ok := idx[m.Account]
+		if !ok {
+			idx[m.Account] = len(memberships)
+			memberships = append(memberships, m)
+			continue
+		}
+
+		merged := &memberships[i]
+		merged.SectionsAll = merged.SectionsAll || m.SectionsAll
+		merged.Admin = merged.Admin || m.Admin
+		for _, sec := range m.Sections {
+			merged.Sections = appendUnique(merged.Sections, sec)
+		}
+		for _, sec := range m.DenySections {
+			merged.DenySections = appendUnique(merged.DenySections, sec)
+		}
+		for _, r := range m.Roles {
+			merged.Roles = appendUnique(merged.Roles, r)
+		}
+	}
+
+	for _, m := range memberships {
+		u.Accounts = appendUnique(u.Accounts, m.Account)
+	}
+
+	u.Memberships = memberships
+}
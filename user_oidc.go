@@ -0,0 +1,642 @@
+package provision
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt_lib "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"github.com/txn2/es/v2"
+	"go.uber.org/zap"
+)
+
+const IdxOidcState = "oidc_state"
+
+// OidcStateExp in seconds a login attempt remains valid
+const OidcStateExp = 600
+
+// OidcCfg configures an OpenID Connect relying party
+type OidcCfg struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+
+	// defaults to openid, email and profile
+	Scopes []string
+
+	// link an existing user with the same verified email on
+	// first login, sysops are never linked
+	LinkEmail bool
+
+	// create users on first login, associated with
+	// ProvisionAccount and granted ProvisionSections. An
+	// existing user is never replaced.
+	AutoProvision     bool
+	ProvisionAccount  string
+	ProvisionSections []string
+}
+
+// OidcState is a pending login stored server-side and keyed
+// by the state parameter sent to the provider.
+type OidcState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Expires  int64  `json:"expires"`
+}
+
+// OidcStateResult returned from Elastic
+type OidcStateResult struct {
+	es.Result
+	Source OidcState `json:"_source"`
+}
+
+// oidcDiscovery is the subset of provider metadata used
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// oidcJwks
+type oidcJwks struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// oidcTokenResponse
+type oidcTokenResponse struct {
+	IdToken string `json:"id_token"`
+	Error   string `json:"error"`
+}
+
+// oidcProvider caches provider discovery and signing keys
+type oidcProvider struct {
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+// OidcLoginHandler starts an authorization code flow with PKCE
+// by redirecting to the provider.
+func (a *Api) OidcLoginHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	if a.Config.Oidc == nil {
+		ak.GinErrorAbort(404, "OidcNotConfigured", "OpenID Connect is not configured.")
+		return
+	}
+
+	disc, err := a.oidcDiscover()
+	if err != nil {
+		a.Logger.Error("OidcDiscoveryError", zap.Error(err))
+		ak.GinErrorAbort(500, "OidcDiscoveryError", err.Error())
+		return
+	}
+
+	state, err := a.NewOidcState()
+	if err != nil {
+		a.Logger.Error("OidcStateError", zap.Error(err))
+		ak.GinErrorAbort(500, "OidcStateError", err.Error())
+		return
+	}
+
+	scopes := a.Config.Oidc.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", a.Config.Oidc.ClientId)
+	q.Set("redirect_uri", a.Config.Oidc.RedirectUrl)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", state.State)
+	q.Set("nonce", state.Nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+
+	c.Redirect(http.StatusFound, disc.AuthorizationEndpoint+"?"+q.Encode())
+}
+
+// OidcCallbackHandler completes the authorization code flow,
+// maps the external identity to a User and issues a token.
+func (a *Api) OidcCallbackHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	if a.Config.Oidc == nil {
+		ak.GinErrorAbort(404, "OidcNotConfigured", "OpenID Connect is not configured.")
+		return
+	}
+
+	if errParam := c.Query("error"); errParam != "" {
+		ak.SetPayload(c.Query("error_description"))
+		ak.GinErrorAbort(401, "AuthFailure", errParam)
+		return
+	}
+
+	state, err := a.TakeOidcState(c.Query("state"))
+	if err != nil {
+		a.Logger.Error("OidcStateError", zap.Error(err))
+		ak.GinErrorAbort(500, "OidcStateError", err.Error())
+		return
+	}
+
+	if state == nil {
+		ak.GinErrorAbort(401, "AuthFailure", "Unknown or expired login state.")
+		return
+	}
+
+	claims, err := a.OidcExchange(c.Query("code"), state)
+	if err != nil {
+		a.Logger.Warn("OidcExchangeError", zap.Error(err))
+		ak.GinErrorAbort(401, "AuthFailure", err.Error())
+		return
+	}
+
+	user, err := a.OidcUser(claims)
+	if err != nil {
+		a.Logger.Error("OidcUserError", zap.Error(err))
+		ak.GinErrorAbort(500, "OidcUserError", err.Error())
+		return
+	}
+
+	if user == nil {
+		ak.GinErrorAbort(401, "AuthFailure", "User account not found.")
+		return
+	}
+
+	if !user.HasBasicAccess() {
+		ak.GinErrorAbort(401, "AuthFailure", "User account is not active.")
+		return
+	}
+
+	user.Password = RedactMsg
+	user.RedactApiTokens()
+
+	utr, err := a.IssueUserToken(*user)
+	if err != nil {
+		a.Logger.Error("TokenFailResult", zap.Error(err))
+		ak.SetPayloadType("TokenFailResult")
+		ak.GinErrorAbort(401, "AuthFailure", "Filed to generate token.")
+		return
+	}
+
+	ak.SetPayloadType("UserTokenResult")
+	ak.GinSend(utr)
+}
+
+// NewOidcState creates and stores a pending login
+func (a *Api) NewOidcState() (*OidcState, error) {
+	state, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &OidcState{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		Expires:  time.Now().Unix() + OidcStateExp,
+	}
+
+	code, _, errorResponse, err := a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxOidcState, state), st)
+	if err != nil {
+		return nil, err
+	}
+
+	if code < 200 || code >= 300 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return nil, fmt.Errorf("got code %d storing login state", code)
+	}
+
+	return st, nil
+}
+
+// TakeOidcState returns and removes a pending login. Returns
+// nil if the state is unknown, expired or already taken.
+func (a *Api) TakeOidcState(state string) (*OidcState, error) {
+	if state == "" {
+		return nil, nil
+	}
+
+	pth := fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxOidcState, url.PathEscape(state))
+
+	code, ret, err := a.Elastic.Get(pth)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != 200 {
+		return nil, nil
+	}
+
+	osResult := &OidcStateResult{}
+	err = json.Unmarshal(ret, osResult)
+	if err != nil {
+		return nil, err
+	}
+
+	// state is single use, only one delete of the version
+	// read succeeds when callbacks race
+	code, err = a.esDelete(fmt.Sprintf("%s?version=%d", pth, osResult.Version))
+	if err != nil {
+		return nil, err
+	}
+
+	if code != 200 {
+		return nil, nil
+	}
+
+	if time.Now().Unix() > osResult.Source.Expires {
+		return nil, nil
+	}
+
+	return &osResult.Source, nil
+}
+
+// OidcExchange trades an authorization code for tokens and
+// returns the verified id token claims.
+func (a *Api) OidcExchange(code string, state *OidcState) (jwt_lib.MapClaims, error) {
+	disc, err := a.oidcDiscover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", a.Config.Oidc.RedirectUrl)
+	form.Set("client_id", a.Config.Oidc.ClientId)
+	form.Set("code_verifier", state.Verifier)
+	if a.Config.Oidc.ClientSecret != "" {
+		form.Set("client_secret", a.Config.Oidc.ClientSecret)
+	}
+
+	resp, err := a.oidcHttp().PostForm(disc.TokenEndpoint, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	tr := &oidcTokenResponse{}
+	err = json.NewDecoder(resp.Body).Decode(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 || tr.IdToken == "" {
+		return nil, fmt.Errorf("token exchange failed with code %d: %s", resp.StatusCode, tr.Error)
+	}
+
+	tkn, err := jwt_lib.Parse(tr.IdToken, func(t *jwt_lib.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt_lib.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		return a.oidcKey(disc, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := tkn.Claims.(jwt_lib.MapClaims)
+	if !ok || !tkn.Valid {
+		return nil, errors.New("invalid id token")
+	}
+
+	if !claims.VerifyIssuer(disc.Issuer, true) {
+		return nil, errors.New("id token issuer mismatch")
+	}
+
+	if !oidcAudience(claims, a.Config.Oidc.ClientId) {
+		return nil, errors.New("id token audience mismatch")
+	}
+
+	if nonce, _ := claims["nonce"].(string); nonce != state.Nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+// OidcUser finds the User linked to an external identity,
+// linking by verified email or auto-provisioning when configured.
+// Returns nil if no user could be mapped.
+func (a *Api) OidcUser(claims jwt_lib.MapClaims) (*User, error) {
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, errors.New("id token missing subject")
+	}
+
+	identity := a.Config.Oidc.Issuer + "|" + sub
+
+	user, err := a.userByTerm("identities", identity)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	name, _ := claims["name"].(string)
+	picture, _ := claims["picture"].(string)
+
+	// link an existing user with the same verified email
+	if a.Config.Oidc.LinkEmail && email != "" && emailVerified {
+		user, err = a.userByTerm("email", email)
+		if err != nil {
+			return nil, err
+		}
+
+		if user != nil && user.Sysop {
+			a.Logger.Warn("Refusing to link an OIDC identity to a sysop", zap.String("id", user.Id))
+			return nil, nil
+		}
+	}
+
+	if user != nil {
+		user.Identities = append(user.Identities, identity)
+
+		code, _, errorResponse, err := a.putUser(user)
+		if err != nil {
+			return nil, err
+		}
+
+		if code < 200 || code >= 300 {
+			if errorResponse != nil {
+				a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+			}
+			return nil, fmt.Errorf("got code %d storing user", code)
+		}
+
+		return user, nil
+	}
+
+	if !a.Config.Oidc.AutoProvision {
+		return nil, nil
+	}
+
+	id := email
+	if id == "" || !emailVerified {
+		id = identity
+	}
+
+	// never replace an existing user
+	code, _, err := a.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if code == 200 {
+		a.Logger.Warn("Refusing to provision an OIDC user over an existing user", zap.String("id", id))
+		return nil, nil
+	}
+
+	// a random password so the user can only log in
+	// through the provider until one is set
+	pw, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	user = &User{
+		Id:            id,
+		DisplayName:   name,
+		Name:          name,
+		Email:         email,
+		EmailVerified: emailVerified,
+		Picture:       picture,
+		Active:        true,
+		Password:      pw,
+		Sections:      a.Config.Oidc.ProvisionSections,
+		Identities:    []string{identity},
+	}
+
+	if a.Config.Oidc.ProvisionAccount != "" {
+		user.Accounts = []string{a.Config.Oidc.ProvisionAccount}
+	}
+
+	code, _, errorResponse, err := a.UpsertUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if code < 200 || code >= 300 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return nil, fmt.Errorf("got code %d storing user", code)
+	}
+
+	return user, nil
+}
+
+// userByTerm returns the first user with a keyword field
+// matching value or nil if none exists.
+func (a *Api) userByTerm(field string, value string) (*User, error) {
+	query := &es.Obj{
+		"size": 1,
+		"query": es.Obj{
+			"term": es.Obj{
+				field: value,
+			},
+		},
+	}
+
+	code, usResults, errorResponse, err := a.SearchUsers(query)
+	if err != nil {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return nil, err
+	}
+
+	if code != 200 || len(usResults.Hits.Hits) < 1 {
+		return nil, nil
+	}
+
+	// SearchUsers redacts, re-read the full record
+	_, userResult, err := a.GetUser(usResults.Hits.Hits[0].Source.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userResult.Source, nil
+}
+
+// oidcHttp
+func (a *Api) oidcHttp() *http.Client {
+	if a.HttpClient != nil && a.HttpClient.Http != nil {
+		return a.HttpClient.Http
+	}
+
+	return http.DefaultClient
+}
+
+// oidcDiscover returns cached provider metadata
+func (a *Api) oidcDiscover() (*oidcDiscovery, error) {
+	a.oidc.mu.Lock()
+	defer a.oidc.mu.Unlock()
+
+	if a.oidc.discovery != nil {
+		return a.oidc.discovery, nil
+	}
+
+	resp, err := a.oidcHttp().Get(strings.TrimSuffix(a.Config.Oidc.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("discovery returned code %d", resp.StatusCode)
+	}
+
+	disc := &oidcDiscovery{}
+	err = json.NewDecoder(resp.Body).Decode(disc)
+	if err != nil {
+		return nil, err
+	}
+
+	a.oidc.discovery = disc
+
+	return disc, nil
+}
+
+// oidcKey returns a provider signing key, refreshing the key
+// set when an unknown kid is encountered.
+func (a *Api) oidcKey(disc *oidcDiscovery, kid string) (*rsa.PublicKey, error) {
+	a.oidc.mu.Lock()
+	defer a.oidc.mu.Unlock()
+
+	if key, ok := a.oidc.keys[kid]; ok {
+		return key, nil
+	}
+
+	resp, err := a.oidcHttp().Get(disc.JwksUri)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	jwks := &oidcJwks{}
+	err = json.NewDecoder(resp.Body).Decode(jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	a.oidc.keys = keys
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %s", kid)
+	}
+
+	return key, nil
+}
+
+// oidcAudience checks a string or list aud claim
+func oidcAudience(claims jwt_lib.MapClaims, clientId string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == clientId
+	case []interface{}:
+		for _, v := range aud {
+			if s, _ := v.(string); s == clientId {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// GetOidcStateMapping
+func GetOidcStateMapping(prefix string) es.IndexTemplate {
+	template := es.Obj{
+		"index_patterns": []string{prefix + IdxOidcState},
+		"settings": es.Obj{
+			"number_of_shards": 1,
+		},
+		"mappings": es.Obj{
+			"_doc": es.Obj{
+				"_source": es.Obj{
+					"enabled": true,
+				},
+				"properties": es.Obj{
+					"state": es.Obj{
+						"type": "keyword",
+					},
+					"nonce": es.Obj{
+						"type": "keyword",
+					},
+					"verifier": es.Obj{
+						"type": "keyword",
+					},
+					"expires": es.Obj{
+						"type": "long",
+					},
+				},
+			},
+		},
+	}
+
+	return es.IndexTemplate{
+		Name:     prefix + IdxOidcState,
+		Template: template,
+	}
+}
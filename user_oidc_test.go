@@ -0,0 +1,315 @@
+package provision
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	jwt_lib "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/token"
+)
+
+// oidcStub is an OpenID Connect provider issuing id tokens for
+// codes registered by the test
+type oidcStub struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]oidcStubCode
+}
+
+// oidcStubCode is an authorization code with the PKCE challenge
+// and claims of the id token it is exchanged for
+type oidcStubCode struct {
+	challenge string
+	claims    jwt_lib.MapClaims
+	key       *rsa.PrivateKey
+}
+
+func newOidcStub(t *testing.T) *oidcStub {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %s", err.Error())
+	}
+
+	p := &oidcStub{key: key, codes: make(map[string]oidcStubCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                p.URL,
+			AuthorizationEndpoint: p.URL + "/authorize",
+			TokenEndpoint:         p.URL + "/token",
+			JwksUri:               p.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":[{"kid":"k1","kty":"RSA","n":"` +
+			base64.RawURLEncoding.EncodeToString(key.N.Bytes()) + `","e":"` +
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()) + `"}]}`))
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		code, ok := p.codes[r.PostFormValue("code")]
+		delete(p.codes, r.PostFormValue("code"))
+		p.mu.Unlock()
+
+		verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != code.challenge {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		tkn := jwt_lib.NewWithClaims(jwt_lib.SigningMethodRS256, code.claims)
+		tkn.Header["kid"] = "k1"
+
+		idToken, err := tkn.SignedString(code.key)
+		if err != nil {
+			t.Errorf("SignedString: %s", err.Error())
+		}
+
+		json.NewEncoder(w).Encode(oidcTokenResponse{IdToken: idToken})
+	})
+
+	p.Server = httptest.NewServer(mux)
+
+	return p
+}
+
+// authorize registers a code for a login redirect, returning the
+// code and the state of the login
+func (p *oidcStub) authorize(t *testing.T, location string, claims jwt_lib.MapClaims, key *rsa.PrivateKey) (string, string) {
+	u, err := url.Parse(location)
+	if err != nil {
+		t.Fatalf("Parse: %s", err.Error())
+	}
+
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "provision" || q.Get("response_type") != "code" {
+		t.Errorf("unexpected authorization request %s", location)
+	}
+
+	full := jwt_lib.MapClaims{
+		"iss":   p.URL,
+		"aud":   "provision",
+		"sub":   "sub1",
+		"nonce": q.Get("nonce"),
+		"exp":   time.Now().Unix() + 60,
+	}
+	for k, v := range claims {
+		full[k] = v
+	}
+
+	if key == nil {
+		key = p.key
+	}
+
+	code := "code-" + q.Get("state")
+
+	p.mu.Lock()
+	p.codes[code] = oidcStubCode{challenge: q.Get("code_challenge"), claims: full, key: key}
+	p.mu.Unlock()
+
+	return code, q.Get("state")
+}
+
+func TestOidcCallback(t *testing.T) {
+	provider := newOidcStub(t)
+	defer provider.Close()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %s", err.Error())
+	}
+
+	identity := provider.URL + "|sub1"
+
+	tt := []struct {
+		name   string
+		users  []User
+		link   bool
+		claims jwt_lib.MapClaims
+		key    *rsa.PrivateKey
+		state  func(state string) string
+		pkce   string
+		code   int
+		user   string
+	}{
+		{name: "valid login", code: 200, user: "u1",
+			users: []User{{Id: "u1", Active: true, Identities: []string{identity}}}},
+		{name: "unknown state", code: 401,
+			users: []User{{Id: "u1", Active: true, Identities: []string{identity}}},
+			state: func(string) string { return "unknown" }},
+		{name: "pkce mismatch", code: 401, pkce: "other",
+			users: []User{{Id: "u1", Active: true, Identities: []string{identity}}}},
+		{name: "nonce mismatch", code: 401,
+			users:  []User{{Id: "u1", Active: true, Identities: []string{identity}}},
+			claims: jwt_lib.MapClaims{"nonce": "other"}},
+		{name: "wrong audience", code: 401,
+			users:  []User{{Id: "u1", Active: true, Identities: []string{identity}}},
+			claims: jwt_lib.MapClaims{"aud": "other"}},
+		{name: "wrong issuer", code: 401,
+			users:  []User{{Id: "u1", Active: true, Identities: []string{identity}}},
+			claims: jwt_lib.MapClaims{"iss": "https://other.example.com"}},
+		{name: "expired id token", code: 401,
+			users:  []User{{Id: "u1", Active: true, Identities: []string{identity}}},
+			claims: jwt_lib.MapClaims{"exp": time.Now().Unix() - 60}},
+		{name: "wrong signing key", code: 401, key: otherKey,
+			users: []User{{Id: "u1", Active: true, Identities: []string{identity}}}},
+		{name: "unknown identity", code: 401,
+			users: []User{{Id: "u1", Active: true}}},
+		{name: "link verified email", code: 200, user: "jane", link: true,
+			users:  []User{{Id: "jane", Active: true, Email: "jane@example.com"}},
+			claims: jwt_lib.MapClaims{"email": "jane@example.com", "email_verified": true}},
+		{name: "link unverified email", code: 401, link: true,
+			users:  []User{{Id: "jane", Active: true, Email: "jane@example.com"}},
+			claims: jwt_lib.MapClaims{"email": "jane@example.com", "email_verified": false}},
+		{name: "link disabled", code: 401,
+			users:  []User{{Id: "jane", Active: true, Email: "jane@example.com"}},
+			claims: jwt_lib.MapClaims{"email": "jane@example.com", "email_verified": true}},
+		{name: "link sysop", code: 401, link: true,
+			users:  []User{{Id: "root", Active: true, Sysop: true, Email: "root@example.com"}},
+			claims: jwt_lib.MapClaims{"email": "root@example.com", "email_verified": true}},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newMemEs()
+			for _, u := range tc.users {
+				db.put(IdxUser+"/"+u.Id, u)
+			}
+
+			a, srv := newTestApi(db.ServeHTTP)
+			defer srv.Close()
+
+			a.Config.Token = &token.Jwt{Cfg: token.JwtCfg{EncKey: []byte("test"), Exp: 10}}
+			a.Config.Oidc = &OidcCfg{
+				Issuer:      provider.URL,
+				ClientId:    "provision",
+				RedirectUrl: "http://localhost/oidc/callback",
+				LinkEmail:   tc.link,
+			}
+
+			router := gin.New()
+			router.GET("/oidc/login", a.OidcLoginHandler)
+			router.GET("/oidc/callback", a.OidcCallbackHandler)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oidc/login", nil))
+			if w.Code != http.StatusFound {
+				t.Fatalf("login got %d: %s", w.Code, w.Body.String())
+			}
+
+			code, state := provider.authorize(t, w.Header().Get("Location"), tc.claims, tc.key)
+			if tc.state != nil {
+				state = tc.state(state)
+			}
+			if tc.pkce != "" {
+				provider.mu.Lock()
+				c := provider.codes[code]
+				c.challenge = tc.pkce
+				provider.codes[code] = c
+				provider.mu.Unlock()
+			}
+
+			callback := "/oidc/callback?" + url.Values{"code": {code}, "state": {state}}.Encode()
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, callback, nil))
+			if w.Code != tc.code {
+				t.Fatalf("callback got %d, want %d: %s", w.Code, tc.code, w.Body.String())
+			}
+
+			if tc.code != 200 {
+				return
+			}
+
+			res := struct {
+				Payload UserTokenResult `json:"payload"`
+			}{}
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("Unmarshal: %s", err.Error())
+			}
+			if res.Payload.User.Id != tc.user || res.Payload.Token == "" {
+				t.Errorf("got token for %s, want %s", res.Payload.User.Id, tc.user)
+			}
+
+			// the identity is linked to the user
+			stored := User{}
+			db.get(IdxUser+"/"+tc.user, &stored)
+			if !stringInSlice(identity, stored.Identities) {
+				t.Errorf("identities %v, want %s", stored.Identities, identity)
+			}
+
+			// the state is single use
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, callback, nil))
+			if w.Code != 401 {
+				t.Errorf("second callback got %d, want 401", w.Code)
+			}
+		})
+	}
+}
+
+func TestTakeOidcState(t *testing.T) {
+	db := newMemEs()
+	a, srv := newTestApi(db.ServeHTTP)
+	defer srv.Close()
+
+	db.put(IdxOidcState+"/expired", OidcState{State: "expired", Expires: time.Now().Unix() - 1})
+
+	st, err := a.TakeOidcState("expired")
+	if err != nil || st != nil {
+		t.Errorf("expired state = %v, %v", st, err)
+	}
+
+	state, err := a.NewOidcState()
+	if err != nil {
+		t.Fatalf("NewOidcState: %s", err.Error())
+	}
+
+	// concurrent callbacks redeem a state once
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	taken := 0
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			st, err := a.TakeOidcState(state.State)
+			if err != nil {
+				t.Errorf("TakeOidcState: %s", err.Error())
+				return
+			}
+
+			if st != nil {
+				mu.Lock()
+				taken++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if taken != 1 {
+		t.Errorf("state taken %d times, want 1", taken)
+	}
+}
@@ -0,0 +1,624 @@
+package provision
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	jwt_lib "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"github.com/txn2/es/v2"
+	"github.com/txn2/token"
+	"go.uber.org/zap"
+)
+
+const IdxRefreshToken = "refresh_token"
+const IdxRevokedToken = "revoked_token"
+
+// RevokedTokenPurgeInterval in seconds between removals of
+// expired entries from the revocation list
+const RevokedTokenPurgeInterval = 60
+
+// RefreshTokenExpDefault in minutes (7 days)
+const RefreshTokenExpDefault = 10080
+
+// RefreshToken is the server-side record of an issued
+// refresh token. The token itself is never stored, only
+// its sha256 hash which is also used as the document id.
+type RefreshToken struct {
+	Id      string `json:"id" yaml:"id"`
+	UserId  string `json:"user_id" yaml:"userId"`
+	Jti     string `json:"jti" yaml:"jti"`
+	Created int64  `json:"created" yaml:"created"`
+	Expires int64  `json:"expires" yaml:"expires"`
+	Revoked bool   `json:"revoked" yaml:"revoked"`
+}
+
+// RefreshTokenResult returned from Elastic
+type RefreshTokenResult struct {
+	es.Result
+	Source RefreshToken `json:"_source"`
+}
+
+// RefreshTokenSearchResults
+type RefreshTokenSearchResults struct {
+	es.SearchResults
+	Hits struct {
+		Total    int                  `json:"total"`
+		MaxScore float64              `json:"max_score"`
+		Hits     []RefreshTokenResult `json:"hits"`
+	} `json:"hits"`
+}
+
+// RevokedToken is an entry in the revocation list, keyed
+// by the jti of the revoked access token.
+type RevokedToken struct {
+	Jti     string `json:"jti" yaml:"jti"`
+	UserId  string `json:"user_id" yaml:"userId"`
+	Revoked int64  `json:"revoked" yaml:"revoked"`
+	Expires int64  `json:"expires" yaml:"expires"`
+}
+
+// UserRef is the token data used in place of the full
+// user when Config.TokenUserRef is enabled.
+type UserRef struct {
+	Id    string `json:"id" mapstructure:"id"`
+	Epoch int64  `json:"epoch" mapstructure:"epoch"`
+}
+
+// TokenRefresh is posted to exchange or revoke a refresh token
+type TokenRefresh struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// IssueUserToken creates a signed access token carrying the user
+// and a unique jti along with a paired refresh token stored
+// server-side.
+func (a *Api) IssueUserToken(user User) (*UserTokenResult, error) {
+	if a.Config.Token == nil {
+		return nil, errors.New("no token configuration")
+	}
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	// api tokens never travel in a token
+	user.ApiTokens = nil
+
+	// groups and roles travel resolved so token holders can
+	// check access
+	err = a.ResolveUserGrants(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	time.Local = time.UTC
+	now := time.Now().Unix()
+	exp := now + (int64(a.Config.Token.Cfg.Exp) * 60)
+
+	claims := jwt_lib.MapClaims{
+		"data": user,
+		"exp":  exp,
+		"jti":  jti,
+	}
+
+	// only reference the user, permissions are resolved
+	// from storage on validation
+	if a.Config.TokenUserRef {
+		claims["data"] = UserRef{Id: user.Id, Epoch: user.Epoch}
+		claims["user_ref"] = true
+	}
+
+	tkn := jwt_lib.New(jwt_lib.GetSigningMethod("HS256"))
+	tkn.Claims = claims
+
+	tokenString, err := tkn.SignedString(a.Config.Token.Cfg.EncKey)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := &RefreshToken{
+		Id:      hashToken(refresh),
+		UserId:  user.Id,
+		Jti:     jti,
+		Created: now,
+		Expires: now + (int64(a.Config.RefreshTokenExp) * 60),
+	}
+
+	err = a.UpsertRefreshToken(rt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserTokenResult{
+		User:         user,
+		Token:        tokenString,
+		RefreshToken: refresh,
+		Expires:      exp,
+	}, nil
+}
+
+// UpsertRefreshToken stores a refresh token record
+func (a *Api) UpsertRefreshToken(rt *RefreshToken) error {
+	code, _, errorResponse, err := a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxRefreshToken, rt.Id), rt)
+	if err != nil {
+		return err
+	}
+
+	if code < 200 || code >= 300 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return fmt.Errorf("got code %d storing refresh token", code)
+	}
+
+	return nil
+}
+
+// GetRefreshToken looks up a refresh token record by the
+// raw refresh token.
+func (a *Api) GetRefreshToken(refresh string) (int, *RefreshTokenResult, error) {
+	rtResult := &RefreshTokenResult{}
+
+	code, ret, err := a.Elastic.Get(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxRefreshToken, hashToken(refresh)))
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		return code, rtResult, err
+	}
+
+	err = json.Unmarshal(ret, rtResult)
+	if err != nil {
+		return code, rtResult, err
+	}
+
+	return code, rtResult, nil
+}
+
+// RevokeToken adds a jti to the revocation list
+func (a *Api) RevokeToken(jti string, userId string, expires int64) error {
+	if jti == "" {
+		return nil
+	}
+
+	rvk := &RevokedToken{
+		Jti:     jti,
+		UserId:  userId,
+		Revoked: time.Now().Unix(),
+		Expires: expires,
+	}
+
+	code, _, errorResponse, err := a.Elastic.PutObj(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxRevokedToken, jti), rvk)
+	if err != nil {
+		return err
+	}
+
+	if code < 200 || code >= 300 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return fmt.Errorf("got code %d revoking token", code)
+	}
+
+	// the revocation list only needs entries until the tokens
+	// expire, a failure here leaves them for the next revocation
+	err = a.purgeRevokedTokens()
+	if err != nil {
+		a.Logger.Warn("Unable to remove expired revocations.", zap.Error(err))
+	}
+
+	return nil
+}
+
+// purgeRevokedTokens removes revocation list entries of expired
+// tokens, at most once every RevokedTokenPurgeInterval seconds
+func (a *Api) purgeRevokedTokens() error {
+	now := time.Now().Unix()
+
+	last := atomic.LoadInt64(&a.revokedPurged)
+	if now-last < RevokedTokenPurgeInterval || !atomic.CompareAndSwapInt64(&a.revokedPurged, last, now) {
+		return nil
+	}
+
+	query := es.Obj{
+		"query": es.Obj{
+			"range": es.Obj{"expires": es.Obj{"lt": now}},
+		},
+	}
+
+	code, _, errorResponse, err := a.Elastic.PostObj(fmt.Sprintf("%s/_delete_by_query?conflicts=proceed", a.IdxPrefix+IdxRevokedToken), query)
+	if err != nil {
+		return err
+	}
+
+	if code < 200 || code >= 300 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return fmt.Errorf("got code %d removing expired revocations", code)
+	}
+
+	return nil
+}
+
+// RevokeRefreshToken marks a refresh token as revoked along with
+// the access token it was paired with.
+func (a *Api) RevokeRefreshToken(rt *RefreshToken) error {
+	rt.Revoked = true
+	err := a.UpsertRefreshToken(rt)
+	if err != nil {
+		return err
+	}
+
+	exp := time.Now().Unix() + (int64(a.Config.Token.Cfg.Exp) * 60)
+
+	return a.RevokeToken(rt.Jti, rt.UserId, exp)
+}
+
+// RevokeUserTokens revokes every outstanding refresh token
+// for a user along with the access tokens they were paired with.
+func (a *Api) RevokeUserTokens(userId string) error {
+	err := a.revokeUserAccessTokens(userId)
+	if err != nil {
+		return err
+	}
+
+	query := es.Obj{
+		"query": es.Obj{
+			"bool": es.Obj{
+				"filter": []es.Obj{
+					{"term": es.Obj{"user_id": userId}},
+					{"term": es.Obj{"revoked": false}},
+				},
+			},
+		},
+		"script": es.Obj{
+			"source": "ctx._source.revoked = true",
+			"lang":   "painless",
+		},
+	}
+
+	code, _, errorResponse, err := a.Elastic.PostObj(fmt.Sprintf("%s/_update_by_query?conflicts=proceed", a.IdxPrefix+IdxRefreshToken), query)
+	if err != nil {
+		return err
+	}
+
+	// index may not exist yet if no tokens were ever issued
+	if code == 404 {
+		return nil
+	}
+
+	if code < 200 || code >= 300 {
+		if errorResponse != nil {
+			a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+		}
+		return fmt.Errorf("got code %d revoking user tokens", code)
+	}
+
+	return nil
+}
+
+// revokeUserAccessTokens adds the jti of every access token
+// paired with an outstanding refresh token of a user, that may
+// not have expired yet, to the revocation list.
+func (a *Api) revokeUserAccessTokens(userId string) error {
+	if a.Config.Token == nil {
+		return nil
+	}
+
+	tokenExp := int64(a.Config.Token.Cfg.Exp) * 60
+	now := time.Now().Unix()
+
+	query := es.Obj{
+		"_source": []string{"id", "user_id", "jti", "created"},
+		"query": es.Obj{
+			"bool": es.Obj{
+				"filter": []es.Obj{
+					{"term": es.Obj{"user_id": userId}},
+					{"term": es.Obj{"revoked": false}},
+					{"range": es.Obj{"created": es.Obj{"gte": now - tokenExp}}},
+				},
+			},
+		},
+	}
+
+	page := &SearchQuery{Size: SearchSizeMax}
+
+	for {
+		obj, err := page.page(query, nil, SearchTiebreakDefault)
+		if err != nil {
+			return err
+		}
+
+		results := &RefreshTokenSearchResults{}
+		code, nextCursor, errorResponse, err := a.searchPage(IdxRefreshToken, obj, results)
+		if err != nil {
+			return err
+		}
+
+		// index may not exist yet if no tokens were ever issued
+		if code == 404 {
+			return nil
+		}
+
+		if code != 200 {
+			if errorResponse != nil {
+				a.Logger.Error("EsErrorResponse", zap.String("es_error_response", errorResponse.Message))
+			}
+			return fmt.Errorf("got code %d finding user tokens", code)
+		}
+
+		for _, hit := range results.Hits.Hits {
+			rt := hit.Source
+			err = a.RevokeToken(rt.Jti, rt.UserId, rt.Created+tokenExp)
+			if err != nil {
+				return err
+			}
+		}
+
+		if nextCursor == "" {
+			return nil
+		}
+
+		page.Cursor = nextCursor
+	}
+}
+
+// IsTokenRevoked returns true if the jti is in the revocation list.
+// Entries remain until the token expires.
+func (a *Api) IsTokenRevoked(jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+
+	code, _, err := a.Elastic.Get(fmt.Sprintf("%s/_doc/%s", a.IdxPrefix+IdxRevokedToken, jti))
+	if err != nil {
+		return false, err
+	}
+
+	if code == 200 {
+		return true, nil
+	}
+
+	if code >= 500 {
+		return false, fmt.Errorf("got code %d checking revocation list", code)
+	}
+
+	return false, nil
+}
+
+// RefreshUserToken exchanges a valid refresh token for a new
+// access and refresh token pair. The user is re-read from storage
+// so deactivated users are unable to continue a session.
+func (a *Api) RefreshUserToken(refresh string) (*UserTokenResult, error) {
+	code, rtResult, err := a.GetRefreshToken(refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != 200 {
+		return nil, nil
+	}
+
+	rt := rtResult.Source
+	if rt.Revoked || time.Now().Unix() > rt.Expires {
+		return nil, nil
+	}
+
+	// refresh tokens are single use
+	err = a.RevokeRefreshToken(&rt)
+	if err != nil {
+		return nil, err
+	}
+
+	code, userResult, err := a.GetUser(rt.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if code != 200 || !userResult.Source.HasBasicAccess() {
+		return nil, nil
+	}
+
+	userResult.Source.Password = RedactMsg
+	userResult.Source.RedactApiTokens()
+
+	return a.IssueUserToken(userResult.Source)
+}
+
+// RefreshTokenHandler
+func (a *Api) RefreshTokenHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	tr := &TokenRefresh{}
+	err := ak.UnmarshalPostAbort(tr)
+	if err != nil {
+		a.Logger.Error("Refresh failure.", zap.Error(err))
+		return
+	}
+
+	utr, err := a.RefreshUserToken(tr.RefreshToken)
+	if err != nil {
+		a.Logger.Error("Refresh error", zap.Error(err))
+		ak.GinErrorAbort(500, "RefreshError", err.Error())
+		return
+	}
+
+	if utr == nil {
+		ak.SetPayloadType("ErrorMessage")
+		ak.SetPayload("invalid refresh token")
+		ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		return
+	}
+
+	ak.SetPayloadType("UserTokenResult")
+	ak.GinSend(utr)
+}
+
+// RevokeTokenHandler revokes the token used to make the request
+// and optionally a refresh token belonging to the same user.
+// Must be preceded by UserTokenHandler.
+func (a *Api) RevokeTokenHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	userI, ok := c.Get("User")
+	if !ok {
+		ak.SetPayload("Unable to get user from token.")
+		ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		return
+	}
+
+	user := userI.(*User)
+
+	tr := &TokenRefresh{}
+	rs, _ := c.GetRawData()
+	if len(rs) > 0 {
+		err := ak.UnmarshalAbort(rs, tr)
+		if err != nil {
+			a.Logger.Error("Revoke failure.", zap.Error(err))
+			return
+		}
+	}
+
+	if tr.RefreshToken != "" {
+		code, rtResult, err := a.GetRefreshToken(tr.RefreshToken)
+		if err != nil {
+			ak.GinErrorAbort(500, "RevokeError", err.Error())
+			return
+		}
+
+		if code == 200 {
+			if rtResult.Source.UserId != user.Id {
+				ak.SetPayload("Refresh token does not belong to user.")
+				ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+				return
+			}
+
+			err = a.RevokeRefreshToken(&rtResult.Source)
+			if err != nil {
+				ak.GinErrorAbort(500, "RevokeError", err.Error())
+				return
+			}
+		}
+	}
+
+	tok := c.MustGet("Tok").(*token.Tok)
+	jti, _ := tok.Claims["jti"].(string)
+	exp, _ := tok.Claims["exp"].(float64)
+
+	err := a.RevokeToken(jti, user.Id, int64(exp))
+	if err != nil {
+		ak.GinErrorAbort(500, "RevokeError", err.Error())
+		return
+	}
+
+	ak.SetPayloadType("RevokeResult")
+	ak.GinSend(true)
+}
+
+// randomToken returns a hex encoded random string of n bytes
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken returns the hex encoded sha256 of a token
+func hashToken(tkn string) string {
+	sum := sha256.Sum256([]byte(tkn))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetRefreshTokenMapping
+func GetRefreshTokenMapping(prefix string) es.IndexTemplate {
+	template := es.Obj{
+		"index_patterns": []string{prefix + IdxRefreshToken},
+		"settings": es.Obj{
+			"number_of_shards": 2,
+		},
+		"mappings": es.Obj{
+			"_doc": es.Obj{
+				"_source": es.Obj{
+					"enabled": true,
+				},
+				"properties": es.Obj{
+					"id": es.Obj{
+						"type": "keyword",
+					},
+					"user_id": es.Obj{
+						"type": "keyword",
+					},
+					"jti": es.Obj{
+						"type": "keyword",
+					},
+					"created": es.Obj{
+						"type": "long",
+					},
+					"expires": es.Obj{
+						"type": "long",
+					},
+					"revoked": es.Obj{
+						"type": "boolean",
+					},
+				},
+			},
+		},
+	}
+
+	return es.IndexTemplate{
+		Name:     prefix + IdxRefreshToken,
+		Template: template,
+	}
+}
+
+// GetRevokedTokenMapping
+func GetRevokedTokenMapping(prefix string) es.IndexTemplate {
+	template := es.Obj{
+		"index_patterns": []string{prefix + IdxRevokedToken},
+		"settings": es.Obj{
+			"number_of_shards": 2,
+		},
+		"mappings": es.Obj{
+			"_doc": es.Obj{
+				"_source": es.Obj{
+					"enabled": true,
+				},
+				"properties": es.Obj{
+					"jti": es.Obj{
+						"type": "keyword",
+					},
+					"user_id": es.Obj{
+						"type": "keyword",
+					},
+					"revoked": es.Obj{
+						"type": "long",
+					},
+					"expires": es.Obj{
+						"type": "long",
+					},
+				},
+			},
+		},
+	}
+
+	return es.IndexTemplate{
+		Name:     prefix + IdxRevokedToken,
+		Template: template,
+	}
+}
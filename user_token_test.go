@@ -0,0 +1,69 @@
+package provision
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRevokeTokenPurgesExpired(t *testing.T) {
+	revoked := 0
+	purges := make([]int64, 0)
+
+	a, srv := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/revoked_token/_doc/"):
+			revoked++
+			w.WriteHeader(201)
+			w.Write([]byte(`{"result":"created"}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/revoked_token/_delete_by_query"):
+			query := struct {
+				Query struct {
+					Range struct {
+						Expires struct {
+							Lt int64 `json:"lt"`
+						} `json:"expires"`
+					} `json:"range"`
+				} `json:"query"`
+			}{}
+			if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+				t.Errorf("Decode: %s", err.Error())
+			}
+			purges = append(purges, query.Query.Range.Expires.Lt)
+			w.Write([]byte(`{"deleted":1}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(400)
+		}
+	})
+	defer srv.Close()
+
+	now := time.Now().Unix()
+
+	for _, jti := range []string{"j1", "j2", "j3"} {
+		if err := a.RevokeToken(jti, "u1", now+600); err != nil {
+			t.Fatalf("RevokeToken: %s", err.Error())
+		}
+	}
+
+	// purged once per interval
+	if revoked != 3 || len(purges) != 1 {
+		t.Fatalf("got %d revocations and %d purges, want 3 and 1", revoked, len(purges))
+	}
+
+	if purges[0] < now || purges[0] > time.Now().Unix() {
+		t.Errorf("purged expires before %d, want now", purges[0])
+	}
+
+	a.revokedPurged -= RevokedTokenPurgeInterval
+
+	if err := a.RevokeToken("j4", "u1", now+600); err != nil {
+		t.Fatalf("RevokeToken: %s", err.Error())
+	}
+
+	if len(purges) != 2 {
+		t.Errorf("got %d purges after the interval, want 2", len(purges))
+	}
+}
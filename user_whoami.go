@@ -0,0 +1,191 @@
+package provision
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/txn2/ack"
+	"go.uber.org/zap"
+)
+
+// EffectiveAccount is what a user may do in an account
+type EffectiveAccount struct {
+	Id           string   `json:"id"`
+	DisplayName  string   `json:"display_name"`
+	Parent       string   `json:"parent,omitempty"`
+	Admin        bool     `json:"admin"`
+	SectionsAll  bool     `json:"sections_all"`
+	Sections     []string `json:"sections"`
+	DenySections []string `json:"deny_sections"`
+
+	// granted through admin of an ancestor account with
+	// InheritAdmin rather than an association with the account
+	Derived bool `json:"derived"`
+}
+
+// EffectivePermissions of a token user. Sections and
+// DenySections apply in every account.
+type EffectivePermissions struct {
+	User          User               `json:"user"`
+	ApiToken      string             `json:"api_token,omitempty"`
+	Sysop         bool               `json:"sysop"`
+	SectionsAll   bool               `json:"sections_all"`
+	Sections      []string           `json:"sections"`
+	DenySections  []string           `json:"deny_sections"`
+	AdminAccounts []string           `json:"admin_accounts"`
+	Accounts      []EffectiveAccount `json:"accounts"`
+}
+
+// WhoAmIHandler returns the effective permissions of the token
+// user. Must be preceded by Api.UserTokenHandler.
+func (a *Api) WhoAmIHandler(c *gin.Context) {
+	ak := ack.Gin(c)
+
+	userI, ok := c.Get("User")
+	if !ok {
+		ak.SetPayload("No user object in token.")
+		ak.GinErrorAbort(401, "E401", "UnauthorizedAccess")
+		return
+	}
+
+	ep, err := a.EffectivePermissions(userI.(*User))
+	if err != nil {
+		a.Logger.Error("EsError", zap.Error(err))
+		ak.SetPayloadType("EsError")
+		ak.SetPayload("Error communicating with database.")
+		ak.GinErrorAbort(500, "EsError", err.Error())
+		return
+	}
+
+	if apiToken, ok := c.Get("ApiToken"); ok {
+		ep.ApiToken = apiToken.(string)
+	}
+
+	ak.SetPayloadType("EffectivePermissions")
+	ak.GinSend(ep)
+}
+
+// EffectivePermissions resolves the accounts, sections and
+// admin rights of a user. Admin is decided by the policy of the
+// Api. Inactive and unknown accounts are omitted. With
+// InheritAdmin, descendants of accounts the user administers are
+// included as derived accounts.
+func (a *Api) EffectivePermissions(user *User) (*EffectivePermissions, error) {
+	// never modify the user, it may be shared with the cache
+	u := *user
+	u.Password = RedactMsg
+	u.ApiTokens = append([]ApiToken{}, user.ApiTokens...)
+	u.RedactApiTokens()
+
+	ep := &EffectivePermissions{
+		User:          u,
+		Sysop:         u.Sysop,
+		SectionsAll:   u.SectionsAll,
+		Sections:      append([]string{}, u.Sections...),
+		DenySections:  append([]string{}, u.DenySections...),
+		AdminAccounts: make([]string, 0),
+		Accounts:      make([]EffectiveAccount, 0),
+	}
+
+	// roles assigned in all accounts
+	for _, rg := range u.RoleGrants {
+		if rg.Account != "" {
+			continue
+		}
+		ep.SectionsAll = ep.SectionsAll || rg.SectionsAll
+		for _, sec := range rg.Sections {
+			ep.Sections = appendUnique(ep.Sections, sec)
+		}
+	}
+
+	accounts := append([]string{}, u.Accounts...)
+	for _, acc := range u.AdminAccounts {
+		accounts = appendUnique(accounts, acc)
+	}
+	for _, m := range u.allMemberships() {
+		accounts = appendUnique(accounts, m.Account)
+	}
+
+	// admin of each account is decided by the policy of the Api,
+	// with InheritAdmin descendants of administered accounts are
+	// listed when the policy allows admin
+	all := append([]string{}, accounts...)
+	for i := 0; i < len(all); i++ {
+		acc := all[i]
+
+		code, accountResult, err := a.GetAccount(acc)
+		if code == 404 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		associated := stringInSlice(acc, accounts)
+
+		decision, err := a.Decide(user, PolicyCheckAdmin, &AccessCheck{Accounts: []string{acc}})
+		if err != nil {
+			return nil, err
+		}
+
+		if decision.Allowed && associated && a.InheritAdmin {
+			descendants, err := a.AccountDescendants(acc)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, d := range descendants {
+				all = appendUnique(all, d)
+			}
+		}
+
+		if !accountResult.Source.Active || (!associated && !decision.Allowed) {
+			continue
+		}
+
+		ea := u.effectiveAccount(accountResult.Source.Id)
+		ea.DisplayName = accountResult.Source.DisplayName
+		ea.Parent = accountResult.Source.Parent
+		ea.Admin = decision.Allowed
+		ea.Derived = !associated
+
+		if ea.Admin {
+			ep.AdminAccounts = append(ep.AdminAccounts, ea.Id)
+		}
+
+		ep.Accounts = append(ep.Accounts, ea)
+	}
+
+	return ep, nil
+}
+
+// effectiveAccount returns the sections and admin the user
+// has in an account in addition to their global sections.
+func (u *User) effectiveAccount(account string) EffectiveAccount {
+	ea := EffectiveAccount{
+		Id:           account,
+		Admin:        u.isAccountAdmin(account),
+		Sections:     make([]string, 0),
+		DenySections: make([]string, 0),
+	}
+
+	for _, m := range u.memberships(account) {
+		ea.SectionsAll = ea.SectionsAll || m.SectionsAll
+		for _, sec := range m.Sections {
+			ea.Sections = appendUnique(ea.Sections, sec)
+		}
+		for _, sec := range m.DenySections {
+			ea.DenySections = appendUnique(ea.DenySections, sec)
+		}
+	}
+
+	for _, rg := range u.RoleGrants {
+		if rg.Account != account {
+			continue
+		}
+		ea.SectionsAll = ea.SectionsAll || rg.SectionsAll
+		for _, sec := range rg.Sections {
+			ea.Sections = appendUnique(ea.Sections, sec)
+		}
+	}
+
+	return ea
+}
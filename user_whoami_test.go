@@ -0,0 +1,82 @@
+package provision
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEffectivePermissionsAdmin(t *testing.T) {
+	db := newMemEs()
+	for _, acc := range []Account{
+		{Id: "adm", Active: true},
+		{Id: "child", Parent: "adm", Active: true},
+		{Id: "grand", Parent: "child", Active: true},
+		{Id: "off", Parent: "adm", Active: false},
+		{Id: "mem", Active: true},
+	} {
+		db.put(IdxAccount+"/"+acc.Id, acc)
+	}
+
+	a, srv := newTestApi(db.ServeHTTP)
+	defer srv.Close()
+
+	denyAdmin, err := NewPolicyEvaluator(Policy{Name: "deny_admin", Default: PolicyDeny, Rules: []PolicyRule{
+		{Name: "access", Check: PolicyCheckAccess, Effect: PolicyAllow, When: "true"},
+	}})
+	if err != nil {
+		t.Fatalf("NewPolicyEvaluator: %s", err.Error())
+	}
+
+	allowAll, err := NewPolicyEvaluator(Policy{Name: "allow_all", Default: PolicyAllow})
+	if err != nil {
+		t.Fatalf("NewPolicyEvaluator: %s", err.Error())
+	}
+
+	// admin of adm only through AdminAccounts
+	user := &User{Id: "u1", Active: true, Accounts: []string{"mem"}, AdminAccounts: []string{"adm"}}
+
+	tt := []struct {
+		name    string
+		policy  *PolicyEvaluator
+		inherit bool
+		admin   []string
+		derived []string
+	}{
+		{"default policy", nil, false, []string{"adm"}, []string{}},
+		{"default policy inherit admin", nil, true, []string{"adm", "child", "grand"}, []string{"child", "grand"}},
+		{"policy denies admin", denyAdmin, true, []string{}, []string{}},
+		{"policy allows admin", allowAll, false, []string{"mem", "adm"}, []string{}},
+		{"policy allows admin inherit admin", allowAll, true, []string{"mem", "adm", "child", "grand"}, []string{"child", "grand"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			a.policy = tc.policy
+			a.InheritAdmin = tc.inherit
+
+			ep, err := a.EffectivePermissions(user)
+			if err != nil {
+				t.Fatalf("EffectivePermissions: %s", err.Error())
+			}
+
+			derived := make([]string, 0)
+			for _, ea := range ep.Accounts {
+				if ea.Derived {
+					derived = append(derived, ea.Id)
+				}
+
+				// whoami agrees with the access checks
+				if got := a.HasAdminAccess(user, &AccessCheck{Accounts: []string{ea.Id}}); got != ea.Admin {
+					t.Errorf("%s admin %v, HasAdminAccess %v", ea.Id, ea.Admin, got)
+				}
+			}
+
+			if !reflect.DeepEqual(ep.AdminAccounts, tc.admin) {
+				t.Errorf("admin accounts %v, want %v", ep.AdminAccounts, tc.admin)
+			}
+			if !reflect.DeepEqual(derived, tc.derived) {
+				t.Errorf("derived accounts %v, want %v", derived, tc.derived)
+			}
+		})
+	}
+}